@@ -2,8 +2,13 @@ package client
 
 import (
 	"encoding/json"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -23,26 +28,90 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 4096
+
+	// Time allowed for the peer to answer a shutdown close frame before the connection is closed.
+	closeGracePeriod = 2 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Negotiate permessage-deflate; whether frames are actually compressed is
+	// decided per connection through ConnectOptions
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
 		// Allow all origins - adjust for production
 		return true
 	},
 }
 
+// Codec describes how outbound frames are encoded for a client
+type Codec struct {
+	Name   string
+	Binary bool // binary frames can't be newline-joined into a single batch
+}
+
+// CodecJSON is the default text JSON codec
+var CodecJSON = Codec{Name: "json", Binary: false}
+
+// CodecJSONBinary sends the same JSON messages as binary frames, one message
+// per frame, for clients that only read binary frames
+var CodecJSONBinary = Codec{Name: "json-binary", Binary: true}
+
+// codecs lists the codecs a client may negotiate via the "encoding" query parameter
+var codecs = map[string]Codec{
+	CodecJSON.Name:       CodecJSON,
+	CodecJSONBinary.Name: CodecJSONBinary,
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
-	ID            string
-	Conn          *websocket.Conn
-	Send          chan []byte
-	Hub           *Hub
-	Subscriptions map[string]*types.SubscriptionRequest
-	mu            sync.RWMutex
-	lastSeen      time.Time
+	ID               string
+	Conn             *websocket.Conn
+	Send             chan []byte
+	Hub              *Hub
+	Subscriptions    map[string]*types.SubscriptionRequest
+	subscriptionIDs  map[int64]string // server-assigned subscription id -> key
+	subscriptionKeys map[string]int64 // key -> server-assigned subscription id
+	nextSubID        int64
+	Codec            Codec
+	Namespace        string // prefixes outbound channels when set
+	KeyName          string // name of the API key the client authenticated with, empty without auth
+	SymbolFormat     string // symbol form the client reads and writes, empty for resolved names
+	compressionLevel int    // flate level for outbound frames, 0 when compression is off
+	mu               sync.RWMutex
+	lastSeen         time.Time
+	connectedAt      time.Time
+
+	// Send is closed only under sendMu, after done, so guarded senders never hit a closed channel
+	sendMu   sync.RWMutex
+	closed   bool
+	done     chan struct{}
+	doneOnce sync.Once
+
+	// Frames that don't fit in Send spill here instead of being dropped, nil when disabled
+	overflow *overflowQueue
+
+	// Initial snapshots waiting to be sent, see QueueSnapshot
+	snapshotMu      sync.Mutex
+	snapshots       []queuedSnapshot
+	sendingSnapshot bool
+	heldFrames      int // frames held behind the snapshots
+
+	// Applied to every outbound frame when set, e.g. to convert symbols
+	rewrite func([]byte) []byte
+
+	// Closed to make writePump flush and send a close frame with closeCode and closeReason
+	goingAway     chan struct{}
+	goingAwayOnce sync.Once
+	closeCode     int
+	closeReason   string
+	readDone      chan struct{} // closed when readPump exits
+	writeDone     chan struct{} // closed when writePump exits
+
+	// Payload bytes written to and read from the connection, before compression
+	bytesSent     int64
+	bytesReceived int64
 }
 
 // Hub maintains the set of active clients and broadcasts messages to the clients
@@ -62,6 +131,16 @@ type Hub struct {
 	// Message router for specific client messages
 	ClientMessage chan ClientMessage
 
+	// Called for an unregistering client before its Send channel is closed
+	onUnregister func(*Client)
+
+	// Called for a frame TrySend held behind an initial snapshot that then didn't fit in time
+	onHeldDrop func(*Client, []byte)
+
+	// Payload bytes sent to and received from every client, including departed ones
+	bytesSent     int64
+	bytesReceived int64
+
 	// Mutex for thread safety
 	mu sync.RWMutex
 }
@@ -69,17 +148,26 @@ type Hub struct {
 type ClientMessage struct {
 	Client  *Client
 	Message []byte
+	Binary  bool // received as a binary frame rather than text
 }
 
 // NewClient creates a new client instance
 func NewClient(conn *websocket.Conn, hub *Hub) *Client {
 	return &Client{
-		ID:            generateClientID(),
-		Conn:          conn,
-		Send:          make(chan []byte, 256),
-		Hub:           hub,
-		Subscriptions: make(map[string]*types.SubscriptionRequest),
-		lastSeen:      time.Now(),
+		ID:               generateClientID(),
+		Conn:             conn,
+		Send:             make(chan []byte, 256),
+		Hub:              hub,
+		Subscriptions:    make(map[string]*types.SubscriptionRequest),
+		subscriptionIDs:  make(map[int64]string),
+		subscriptionKeys: make(map[string]int64),
+		Codec:            CodecJSON,
+		lastSeen:         time.Now(),
+		connectedAt:      time.Now(),
+		done:             make(chan struct{}),
+		goingAway:        make(chan struct{}),
+		readDone:         make(chan struct{}),
+		writeDone:        make(chan struct{}),
 	}
 }
 
@@ -94,6 +182,35 @@ func NewHub() *Hub {
 	}
 }
 
+// SetOnUnregister sets a callback run for an unregistering client before its
+// Send channel is closed, so the client can be detached from message routing
+func (h *Hub) SetOnUnregister(onUnregister func(*Client)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onUnregister = onUnregister
+}
+
+// SetOnHeldDrop sets a callback run for a frame that TrySend accepted while
+// an initial snapshot was being sent, and that was dropped after it
+func (h *Hub) SetOnHeldDrop(onHeldDrop func(*Client, []byte)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onHeldDrop = onHeldDrop
+}
+
+// heldDropped reports a held frame that was dropped
+func (h *Hub) heldDropped(c *Client, frame []byte) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	onHeldDrop := h.onHeldDrop
+	h.mu.RUnlock()
+	if onHeldDrop != nil {
+		onHeldDrop(c, frame)
+	}
+}
+
 // Run starts the hub
 func (h *Hub) Run() {
 	for {
@@ -102,41 +219,114 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			h.Clients[client] = true
 			h.mu.Unlock()
-			logrus.WithField("client_id", client.ID).Info("Client registered")
+			logrus.WithFields(logrus.Fields{
+				"client_id": client.ID,
+				"key_name":  client.KeyName,
+			}).Info("Client registered")
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
+			_, ok := h.Clients[client]
+			delete(h.Clients, client)
+			onUnregister := h.onUnregister
+			h.mu.Unlock()
+
+			if ok {
+				if onUnregister != nil {
+					onUnregister(client)
+				}
+				client.close()
 				logrus.WithField("client_id", client.ID).Info("Client unregistered")
 			}
-			h.mu.Unlock()
 
 		case message := <-h.Broadcast:
-			h.mu.RLock()
+			var dropped []*Client
+			h.mu.Lock()
 			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
+				if !client.TrySend(message) {
 					delete(h.Clients, client)
+					dropped = append(dropped, client)
 				}
 			}
-			h.mu.RUnlock()
+			onUnregister := h.onUnregister
+			h.mu.Unlock()
+
+			// Dropped clients are never unregistered again, so release their
+			// subscriptions here
+			for _, client := range dropped {
+				if onUnregister != nil {
+					onUnregister(client)
+				}
+				client.close()
+			}
 		}
 	}
 }
 
+// ConnectOptions carries per-connection settings decided during the handshake
+type ConnectOptions struct {
+	Namespace string
+
+	// Compress outbound frames with permessage-deflate when the client supports it
+	Compression      bool
+	CompressionLevel int // flate level, see compress/flate
+
+	// Origins allowed to connect, exact or "*.domain" wildcards. Empty allows all.
+	AllowedOrigins []string
+
+	// Name of the API key presented during the handshake
+	KeyName string
+
+	// Symbol form requested by the client, with the rewrite converting outbound
+	// frames to it (nil when frames already use it)
+	SymbolFormat string
+	Rewrite      func([]byte) []byte
+
+	// Spill frames beyond the Send buffer to a file in OverflowDir, up to
+	// OverflowMaxBytes, instead of dropping them. Empty disables it.
+	OverflowDir      string
+	OverflowMaxBytes int64
+}
+
+// NamespaceSeparator separates a client namespace from the channel name
+const NamespaceSeparator = ":"
+
 // ServeWS handles websocket requests from clients
-func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, opts ConnectOptions) {
+	u := upgrader
+	if len(opts.AllowedOrigins) > 0 {
+		u.CheckOrigin = func(r *http.Request) bool {
+			return OriginAllowed(r.Header.Get("Origin"), opts.AllowedOrigins)
+		}
+	}
+
+	// A rejected origin gets a 403 from the upgrader
+	conn, err := u.Upgrade(w, r, nil)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to upgrade connection")
 		return
 	}
 
 	client := NewClient(conn, hub)
+	client.Codec = negotiateCodec(r)
+	client.Namespace = opts.Namespace
+	client.KeyName = opts.KeyName
+	client.SymbolFormat = opts.SymbolFormat
+	client.rewrite = opts.Rewrite
+	if opts.OverflowDir != "" {
+		overflow, err := newOverflowQueue(opts.OverflowDir, opts.OverflowMaxBytes)
+		if err != nil {
+			logrus.WithError(err).WithField("client_id", client.ID).Error("Failed to create overflow queue, frames beyond the buffer will be dropped")
+		} else {
+			client.overflow = overflow
+			go client.drainOverflow()
+		}
+	}
+	if opts.Compression {
+		client.compressionLevel = opts.CompressionLevel
+	}
+	// A no-op unless the client negotiated permessage-deflate
+	conn.EnableWriteCompression(opts.Compression)
 	client.Hub.Register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in new goroutines.
@@ -144,9 +334,54 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// OriginAllowed reports whether an Origin header matches the allowlist. Entries
+// match the full origin or its host exactly, or any subdomain with "*.domain".
+// Requests without an Origin header come from non-browser clients and are allowed.
+func OriginAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+			continue
+		}
+		if entry == strings.ToLower(origin) || entry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateCodec selects the codec requested through the "encoding" query parameter
+func negotiateCodec(r *http.Request) Codec {
+	name := r.URL.Query().Get("encoding")
+	if name == "" {
+		return CodecJSON
+	}
+	
+	codec, exists := codecs[name]
+	if !exists {
+		logrus.WithField("encoding", name).Warn("Unsupported encoding requested, using json")
+		return CodecJSON
+	}
+	return codec
+}
+
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
+		close(c.readDone)
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
@@ -160,7 +395,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		messageType, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logrus.WithError(err).Error("WebSocket error")
@@ -169,9 +404,11 @@ func (c *Client) readPump() {
 		}
 
 		c.lastSeen = time.Now()
+		c.countReceived(len(message))
 		c.Hub.ClientMessage <- ClientMessage{
 			Client:  c,
 			Message: message,
+			Binary:  messageType == websocket.BinaryMessage,
 		}
 	}
 }
@@ -182,8 +419,15 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		close(c.writeDone)
 	}()
 
+	if c.compressionLevel != 0 {
+		if err := c.Conn.SetCompressionLevel(c.compressionLevel); err != nil {
+			logrus.WithError(err).WithField("client_id", c.ID).Warn("Invalid compression level, using default")
+		}
+	}
+
 	for {
 		select {
 		case message, ok := <-c.Send:
@@ -193,22 +437,13 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			if err := c.writeFrames(message); err != nil {
 				return
 			}
-			w.Write(message)
 
-			// Add queued messages to the current websocket message.
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
-			}
+		case <-c.goingAway:
+			c.writeGoingAway()
+			return
 
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
@@ -219,18 +454,234 @@ func (c *Client) writePump() {
 	}
 }
 
-// AddSubscription adds a subscription for this client
-func (c *Client) AddSubscription(key string, sub *types.SubscriptionRequest) {
+// writeFrames writes a message together with any queued messages
+func (c *Client) writeFrames(message []byte) error {
+	if isCompressedFrame(message) {
+		return c.writeCompressed(message)
+	}
+	message = c.prepare(message)
+	if c.Codec.Binary {
+		return c.writeDiscrete(message)
+	}
+	return c.writeBatched(message)
+}
+
+// writeGoingAway flushes the queued messages, sends the close frame requested
+// with CloseWith and waits up to closeGracePeriod for the peer to close its side
+func (c *Client) writeGoingAway() {
+	c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	select {
+	case message, ok := <-c.Send:
+		if ok && c.writeFrames(message) != nil {
+			return
+		}
+	default:
+	}
+
+	closeFrame := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+	if err := c.Conn.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(writeWait)); err != nil {
+		return
+	}
+
+	select {
+	case <-c.readDone:
+	case <-time.After(closeGracePeriod):
+	}
+}
+
+// prepare applies the client's symbol rewrite and namespace to an outbound frame
+func (c *Client) prepare(message []byte) []byte {
+	if c.rewrite != nil {
+		message = c.rewrite(message)
+	}
+	return c.applyNamespace(message)
+}
+
+// applyNamespace prefixes the channel of an outbound frame with the client's namespace
+func (c *Client) applyNamespace(message []byte) []byte {
+	if c.Namespace == "" {
+		return message
+	}
+
+	var frame map[string]json.RawMessage
+	if err := json.Unmarshal(message, &frame); err != nil {
+		return message
+	}
+
+	var channel string
+	if raw, ok := frame["channel"]; !ok || json.Unmarshal(raw, &channel) != nil {
+		return message
+	}
+
+	namespaced, err := json.Marshal(c.Namespace + NamespaceSeparator + channel)
+	if err != nil {
+		return message
+	}
+	frame["channel"] = namespaced
+
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return message
+	}
+	return data
+}
+
+// StripNamespace removes the client's namespace prefix from an inbound channel name
+func (c *Client) StripNamespace(channel string) string {
+	if c.Namespace == "" {
+		return channel
+	}
+	return strings.TrimPrefix(channel, c.Namespace+NamespaceSeparator)
+}
+
+// writeBatched writes a message together with any queued messages as one
+// newline-separated text frame
+func (c *Client) writeBatched(message []byte) error {
+	w, err := c.Conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	w.Write(message)
+	size := len(message)
+
+	// Add queued messages to the current websocket message.
+	var compressed []byte
+	n := len(c.Send)
+	for i := 0; i < n; i++ {
+		next, ok := <-c.Send
+		if !ok {
+			break
+		}
+		// A compressed frame ends the batch and goes out on its own
+		if isCompressedFrame(next) {
+			compressed = next
+			break
+		}
+		next = c.prepare(next)
+		w.Write([]byte{'\n'})
+		w.Write(next)
+		size += 1 + len(next)
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	c.countSent(size)
+
+	if compressed != nil {
+		return c.writeCompressed(compressed)
+	}
+	return nil
+}
+
+// writeDiscrete writes a message and any queued messages as separate binary frames
+func (c *Client) writeDiscrete(message []byte) error {
+	if err := c.Conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+		return err
+	}
+	c.countSent(len(message))
+
+	n := len(c.Send)
+	for i := 0; i < n; i++ {
+		next, ok := <-c.Send
+		if !ok {
+			return nil
+		}
+		if isCompressedFrame(next) {
+			if err := c.writeCompressed(next); err != nil {
+				return err
+			}
+			continue
+		}
+		next = c.prepare(next)
+		c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.Conn.WriteMessage(websocket.BinaryMessage, next); err != nil {
+			return err
+		}
+		c.countSent(len(next))
+	}
+	return nil
+}
+
+// countSent adds written payload bytes to the client's and the hub's counters
+func (c *Client) countSent(n int) {
+	atomic.AddInt64(&c.bytesSent, int64(n))
+	if c.Hub != nil {
+		atomic.AddInt64(&c.Hub.bytesSent, int64(n))
+	}
+}
+
+// countReceived adds read payload bytes to the client's and the hub's counters
+func (c *Client) countReceived(n int) {
+	atomic.AddInt64(&c.bytesReceived, int64(n))
+	if c.Hub != nil {
+		atomic.AddInt64(&c.Hub.bytesReceived, int64(n))
+	}
+}
+
+// BytesSent returns the payload bytes written to the client so far
+func (c *Client) BytesSent() int64 {
+	return atomic.LoadInt64(&c.bytesSent)
+}
+
+// BytesReceived returns the payload bytes read from the client so far
+func (c *Client) BytesReceived() int64 {
+	return atomic.LoadInt64(&c.bytesReceived)
+}
+
+// AddSubscription adds a subscription for this client and returns its id,
+// keeping the existing id when the client was already subscribed
+func (c *Client) AddSubscription(key string, sub *types.SubscriptionRequest) int64 {
+	id, _, _ := c.TryAddSubscription(key, sub, 0)
+	return id
+}
+
+// TryAddSubscription adds a subscription unless the client already holds max
+// others (0 means unlimited). It returns the subscription id, the number of
+// subscriptions the client holds and whether the subscription was added.
+func (c *Client) TryAddSubscription(key string, sub *types.SubscriptionRequest, max int) (int64, int, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	if id, exists := c.subscriptionKeys[key]; exists {
+		c.Subscriptions[key] = sub
+		return id, len(c.Subscriptions), true
+	}
+	if max > 0 && len(c.Subscriptions) >= max {
+		return 0, len(c.Subscriptions), false
+	}
 	c.Subscriptions[key] = sub
+
+	c.nextSubID++
+	c.subscriptionIDs[c.nextSubID] = key
+	c.subscriptionKeys[key] = c.nextSubID
+	return c.nextSubID, len(c.Subscriptions), true
 }
 
-// RemoveSubscription removes a subscription for this client
-func (c *Client) RemoveSubscription(key string) {
+// RemoveSubscription removes a subscription for this client and returns the
+// id it had, 0 if the client wasn't subscribed
+func (c *Client) RemoveSubscription(key string) int64 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.Subscriptions, key)
+
+	id := c.subscriptionKeys[key]
+	delete(c.subscriptionKeys, key)
+	delete(c.subscriptionIDs, id)
+	return id
+}
+
+// GetSubscriptionByID returns the subscription a server-assigned id refers to
+func (c *Client) GetSubscriptionByID(id int64) (*types.SubscriptionRequest, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key, exists := c.subscriptionIDs[id]
+	if !exists {
+		return nil, false
+	}
+	sub, exists := c.Subscriptions[key]
+	return sub, exists
 }
 
 // GetSubscriptions returns a copy of client subscriptions
@@ -252,11 +703,152 @@ func (c *Client) SendMessage(message interface{}) error {
 		return err
 	}
 
+	if !c.TrySend(data) {
+		return websocket.ErrCloseSent
+	}
+	return nil
+}
+
+// TrySend queues a frame without blocking. Returns false if the client's
+// buffer is full or the client was closed. While an initial snapshot is being
+// sent the frame is held until after it, see QueueSnapshot.
+func (c *Client) TrySend(data []byte) bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+	if pending, ok := c.holdBehindSnapshot(data); pending {
+		return ok
+	}
+	if c.overflow != nil {
+		return c.spill(data)
+	}
+
 	select {
 	case c.Send <- data:
-		return nil
+		return true
 	default:
-		return websocket.ErrCloseSent
+		return false
+	}
+}
+
+// SendWithin queues a frame, waiting up to timeout for buffer space. Returns
+// false if the buffer stayed full or the client was closed.
+func (c *Client) SendWithin(data []byte, timeout time.Duration) bool {
+	return c.sendBefore(data, time.Now().Add(timeout))
+}
+
+// sendBefore queues a frame, waiting until deadline for buffer space
+func (c *Client) sendBefore(data []byte, deadline time.Time) bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+	if c.overflow != nil {
+		// Spilling keeps the frame in order without waiting
+		return c.spill(data)
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case c.Send <- data:
+		return true
+	case <-c.done:
+		return false
+	case <-timer.C:
+		return false
+	}
+}
+
+// close marks the client closed and closes its Send channel. Waiting senders
+// are released through done first so close never blocks on them.
+func (c *Client) close() {
+	c.doneOnce.Do(func() { close(c.done) })
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.Send)
+}
+
+// CloseWith closes the connection after the queued messages, with a close frame
+// carrying code and reason. Only the first call has an effect.
+func (c *Client) CloseWith(code int, reason string) {
+	c.goingAwayOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.goingAway)
+	})
+}
+
+// Closing reports whether CloseWith was called
+func (c *Client) Closing() bool {
+	select {
+	case <-c.goingAway:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConnectedAt returns when the client connected
+func (c *Client) ConnectedAt() time.Time {
+	return c.connectedAt
+}
+
+// QueueFill returns the share of the Send buffer in use, from 0 to 1. A client
+// spilling frames to its overflow queue counts as full.
+func (c *Client) QueueFill() float64 {
+	if q := c.overflow; q != nil {
+		q.mu.Lock()
+		spilling := q.spilling
+		q.mu.Unlock()
+		if spilling {
+			return 1
+		}
+	}
+
+	if cap(c.Send) == 0 {
+		return 0
+	}
+	return float64(len(c.Send)) / float64(cap(c.Send))
+}
+
+// Shutdown asks every connected client to go away with a close frame, waits for
+// their queued messages and close handshakes for a short grace period, then
+// closes the connections that are still open
+func (h *Hub) Shutdown() {
+	clients := h.GetClients()
+	if len(clients) == 0 {
+		return
+	}
+
+	logrus.WithField("clients", len(clients)).Info("Closing client connections")
+	for _, c := range clients {
+		c.CloseWith(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	deadline := time.After(closeGracePeriod + writeWait)
+	for _, c := range clients {
+		select {
+		case <-c.writeDone:
+		case <-deadline:
+			// Out of time, close whatever is left
+			for _, c := range clients {
+				c.Conn.Close()
+			}
+			return
+		}
 	}
 }
 
@@ -267,9 +859,31 @@ func (h *Hub) GetClientCount() int {
 	return len(h.Clients)
 }
 
+// BytesTotals returns the payload bytes sent to and received from all clients,
+// including clients that have since disconnected
+func (h *Hub) BytesTotals() (sent, received int64) {
+	return atomic.LoadInt64(&h.bytesSent), atomic.LoadInt64(&h.bytesReceived)
+}
+
+// GetClients returns a snapshot of the connected clients
+func (h *Hub) GetClients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.Clients))
+	for c := range h.Clients {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// clientSeq numbers client IDs so two clients created in the same second never collide
+var clientSeq uint64
+
 // generateClientID generates a unique client ID
 func generateClientID() string {
-	return time.Now().Format("20060102150405") + "-" + randomString(8)
+	seq := atomic.AddUint64(&clientSeq, 1)
+	return time.Now().Format("20060102150405") + "-" + strconv.FormatUint(seq, 10) + "-" + randomString(8)
 }
 
 // randomString generates a random string of specified length
@@ -277,7 +891,7 @@ func randomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
+		b[i] = charset[rand.Intn(len(charset))]
 	}
 	return string(b)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,243 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com", "trusted.io", " *.Example.org "}
+
+	cases := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://trusted.io", true},
+		{"http://trusted.io:8080", true},
+		{"https://evil-trusted.io", false},
+		{"https://api.example.org", true},
+		{"https://deep.api.example.org", true},
+		{"https://example.org.evil.com", false},
+		{"https://notexample.org", false},
+		{"null", false},
+		{"://bad", false},
+	}
+
+	for _, tc := range cases {
+		if got := OriginAllowed(tc.origin, allowed); got != tc.want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", tc.origin, got, tc.want)
+		}
+	}
+
+	if OriginAllowed("https://app.example.com", nil) {
+		t.Error("an origin was allowed by an empty allowlist")
+	}
+}
+
+// serveQueued connects to a client using codec whose Send buffer already holds
+// frames, starts its write pump and returns the peer's end of the connection
+func serveQueued(t *testing.T, codec Codec, frames []string) *websocket.Conn {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		c := NewClient(conn, NewHub())
+		c.Codec = negotiateCodec(r)
+		for _, frame := range frames {
+			c.Send <- []byte(frame)
+		}
+		go c.writePump()
+	}))
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?encoding=" + codec.Name
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func TestBinaryCodecWritesDiscreteFrames(t *testing.T) {
+	frames := []string{`{"channel":"a"}`, `{"channel":"b"}`, `{"channel":"c"}`}
+	conn := serveQueued(t, CodecJSONBinary, frames)
+	for _, want := range frames {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != websocket.BinaryMessage || string(message) != want {
+			t.Fatalf("got frame %q of type %d, want binary frame %q", message, messageType, want)
+		}
+	}
+}
+
+func TestJSONCodecBatchesQueuedFrames(t *testing.T) {
+	frames := []string{`{"channel":"a"}`, `{"channel":"b"}`, `{"channel":"c"}`}
+	conn := serveQueued(t, CodecJSON, frames)
+
+	// Frames queued before the write are joined into one newline-separated frame
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(frames, "\n"); messageType != websocket.TextMessage || string(message) != want {
+		t.Fatalf("got frame %q of type %d, want text frame %q", message, messageType, want)
+	}
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	cases := map[string]Codec{
+		"":            CodecJSON,
+		"json":        CodecJSON,
+		"json-binary": CodecJSONBinary,
+		"msgpack":     CodecJSON,
+	}
+	for encoding, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/ws?encoding="+encoding, nil)
+		if got := negotiateCodec(r); got != want {
+			t.Errorf("negotiateCodec(%q) = %s, want %s", encoding, got.Name, want.Name)
+		}
+	}
+}
+
+func TestNamespaceRoundTrip(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	c.Namespace = "app1"
+
+	var frame struct {
+		Channel string          `json:"channel"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(c.prepare([]byte(`{"channel":"trades","data":[{"coin":"BTC"}]}`)), &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Channel != "app1:trades" || string(frame.Data) != `[{"coin":"BTC"}]` {
+		t.Fatalf("outbound frame = %+v, want channel app1:trades with its data intact", frame)
+	}
+	if got := c.StripNamespace(frame.Channel); got != "trades" {
+		t.Fatalf("StripNamespace(%q) = %q, want trades", frame.Channel, got)
+	}
+
+	// Another tenant's prefix isn't stripped, and frames without a channel pass through
+	if got := c.StripNamespace("app2:trades"); got != "app2:trades" {
+		t.Fatalf("StripNamespace(app2:trades) = %q, want it unchanged", got)
+	}
+	if got := string(c.prepare([]byte(`{"method":"pong"}`))); got != `{"method":"pong"}` {
+		t.Fatalf("frame without a channel became %s", got)
+	}
+
+	plain := NewClient(nil, NewHub())
+	if got := string(plain.prepare([]byte(`{"channel":"trades"}`))); got != `{"channel":"trades"}` {
+		t.Fatalf("frame of a client without namespace became %s", got)
+	}
+}
+
+func TestByteCountersMatchFrameSizes(t *testing.T) {
+	frames := []string{`{"channel":"a"}`, `{"channel":"bb"}`, `{"channel":"ccc"}`}
+	inbound := []string{`{"method":"ping"}`, `{"method":"subscribe","subscription":{"type":"allMids"}}`}
+
+	hub := NewHub()
+	clients := make(chan *Client, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		c := NewClient(conn, hub)
+		for _, frame := range frames {
+			c.Send <- []byte(frame)
+		}
+		go c.writePump()
+		go c.readPump()
+		clients <- c
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := <-clients
+
+	// The queued frames arrive as one newline-joined frame
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range inbound {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		<-hub.ClientMessage
+	}
+
+	wantSent := int64(len(strings.Join(frames, "\n")))
+	wantReceived := int64(len(inbound[0]) + len(inbound[1]))
+
+	// The write pump counts once the frame is flushed, possibly after the peer read it
+	deadline := time.Now().Add(2 * time.Second)
+	for c.BytesSent() != wantSent && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.BytesSent() != wantSent || c.BytesReceived() != wantReceived {
+		t.Fatalf("client counted %d sent, %d received, want %d, %d", c.BytesSent(), c.BytesReceived(), wantSent, wantReceived)
+	}
+	if sent, received := hub.BytesTotals(); sent != wantSent || received != wantReceived {
+		t.Fatalf("hub counted %d sent, %d received, want %d, %d", sent, received, wantSent, wantReceived)
+	}
+}
+
+func TestQueueFill(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	if fill := c.QueueFill(); fill != 0 {
+		t.Fatalf("empty buffer fill = %v, want 0", fill)
+	}
+	for i := 0; i < cap(c.Send)/2; i++ {
+		c.Send <- []byte("frame")
+	}
+	if fill := c.QueueFill(); fill != 0.5 {
+		t.Fatalf("half full buffer fill = %v, want 0.5", fill)
+	}
+	fillSendBuffer(c)
+	if fill := c.QueueFill(); fill != 1 {
+		t.Fatalf("full buffer fill = %v, want 1", fill)
+	}
+
+	// A client spilling to its overflow queue is full whatever its buffer holds.
+	// Nothing drains the queue here, so the client keeps spilling.
+	q, err := newOverflowQueue(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spilling := NewClient(nil, NewHub())
+	spilling.overflow = q
+	fillSendBuffer(spilling)
+	if !spilling.TrySend([]byte("spilled")) {
+		t.Fatal("frame refused by the overflow queue")
+	}
+	for len(spilling.Send) > 0 {
+		<-spilling.Send
+	}
+	if fill := spilling.QueueFill(); fill != 1 {
+		t.Fatalf("spilling client fill = %v, want 1", fill)
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// compressedFrameMarker starts queued frames that are delivered gzip-compressed.
+// JSON frames never start with a NUL byte.
+const compressedFrameMarker = 0x00
+
+// compressedFrameHeader is the JSON line opening a compressed binary frame, the
+// gzip stream of the frame follows the newline
+type compressedFrameHeader struct {
+	Channel     string `json:"channel"`
+	Compression string `json:"compression"`
+}
+
+// CompressedFrame marks a JSON frame to be delivered as a single binary frame:
+// a header line such as {"channel":"l2Book","compression":"gzip"} followed by
+// the gzip-compressed frame. Symbol rewriting and namespaces still apply.
+func CompressedFrame(data []byte) []byte {
+	marked := make([]byte, 1+len(data))
+	marked[0] = compressedFrameMarker
+	copy(marked[1:], data)
+	return marked
+}
+
+// isCompressedFrame reports whether a queued frame was marked by CompressedFrame
+func isCompressedFrame(message []byte) bool {
+	return len(message) > 0 && message[0] == compressedFrameMarker
+}
+
+// writeCompressed writes a frame marked by CompressedFrame as a binary frame
+func (c *Client) writeCompressed(message []byte) error {
+	frame := c.prepare(message[1:])
+
+	var header compressedFrameHeader
+	json.Unmarshal(frame, &header)
+	header.Compression = "gzip"
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(headerLine)
+	buf.WriteByte('\n')
+	gz := gzip.NewWriter(&buf)
+	gz.Write(frame)
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.Conn.WriteMessage(websocket.BinaryMessage, buf.Bytes()); err != nil {
+		return err
+	}
+	c.countSent(buf.Len())
+	return nil
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCompressedSnapshotThenPlainUpdates(t *testing.T) {
+	snapshot := `{"channel":"l2Book","data":{"coin":"BTC","levels":[[{"px":"100","sz":"1","n":1}],[]]}}`
+	update := `{"channel":"l2Book","data":{"coin":"BTC","levels":[[],[]]}}`
+	conn := serveQueued(t, CodecJSON, []string{string(CompressedFrame([]byte(snapshot))), update})
+
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.BinaryMessage {
+		t.Fatalf("snapshot came as a frame of type %d, want binary", messageType)
+	}
+	headerLine, compressed, found := bytes.Cut(message, []byte{'\n'})
+	if !found {
+		t.Fatalf("compressed frame %q has no header line", message)
+	}
+	var header compressedFrameHeader
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Channel != "l2Book" || header.Compression != "gzip" {
+		t.Fatalf("header = %+v, want l2Book with gzip", header)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != snapshot {
+		t.Fatalf("decompressed snapshot = %s, want %s", decoded, snapshot)
+	}
+
+	// The update after it stays a plain JSON text frame
+	messageType, message, err = conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.TextMessage || string(message) != update {
+		t.Fatalf("update came as %q of type %d, want text frame %q", message, messageType, update)
+	}
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errOverflowFull is returned when a frame would grow the overflow queue past its cap
+var errOverflowFull = errors.New("overflow queue full")
+
+// overflowCopyChunk is the buffer size used to move queued frames when compacting
+const overflowCopyChunk = 64 * 1024
+
+// overflowQueue is a bounded FIFO of frames kept in a file, holding what doesn't
+// fit in a client's Send buffer until the connection catches up. Frames are
+// stored length-prefixed and maxBytes bounds the frames queued at once. The file
+// is truncated whenever the queue empties, and the frames still queued are moved
+// to its start once maxBytes were read, so a client that stays slightly behind
+// keeps the file under twice maxBytes.
+type overflowQueue struct {
+	mu       sync.Mutex
+	file     *os.File
+	readOff  int64
+	writeOff int64
+	maxBytes int64
+	count    int
+	spilling bool          // frames must go through the queue to keep their order
+	wake     chan struct{} // signals the drain loop that frames were queued
+}
+
+// newOverflowQueue creates an overflow queue backed by a new file in dir
+func newOverflowQueue(dir string, maxBytes int64) (*overflowQueue, error) {
+	file, err := os.CreateTemp(dir, "client-*.queue")
+	if err != nil {
+		return nil, err
+	}
+	return &overflowQueue{
+		file:     file,
+		maxBytes: maxBytes,
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+// push appends a frame to the queue. Caller must hold q.mu.
+func (q *overflowQueue) push(data []byte) error {
+	size := int64(4 + len(data))
+	if q.writeOff-q.readOff+size > q.maxBytes {
+		return errOverflowFull
+	}
+
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+	if _, err := q.file.WriteAt(record, q.writeOff); err != nil {
+		return err
+	}
+
+	q.writeOff += size
+	q.count++
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// peek reads the oldest frame without removing it. Caller must hold q.mu.
+func (q *overflowQueue) peek() ([]byte, error) {
+	var header [4]byte
+	if _, err := q.file.ReadAt(header[:], q.readOff); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := q.file.ReadAt(data, q.readOff+4); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// pop removes the oldest frame, truncating the file once the queue is empty and
+// compacting it once maxBytes were read. Caller must hold q.mu.
+func (q *overflowQueue) pop(size int) {
+	q.readOff += int64(4 + size)
+	q.count--
+
+	if q.count == 0 {
+		q.readOff = 0
+		q.writeOff = 0
+		if err := q.file.Truncate(0); err != nil {
+			logrus.WithError(err).Warn("Failed to truncate client overflow file")
+		}
+		return
+	}
+
+	if q.readOff >= q.maxBytes {
+		if err := q.compact(); err != nil {
+			logrus.WithError(err).Warn("Failed to compact client overflow file")
+		}
+	}
+}
+
+// compact moves the queued frames to the start of the file and truncates the
+// consumed prefix. Caller must hold q.mu.
+func (q *overflowQueue) compact() error {
+	queued := q.writeOff - q.readOff
+	buf := make([]byte, overflowCopyChunk)
+	for moved := int64(0); moved < queued; {
+		chunk := buf
+		if remaining := queued - moved; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		// The destination never overlaps unread data, it is behind readOff
+		if _, err := q.file.ReadAt(chunk, q.readOff+moved); err != nil {
+			return err
+		}
+		if _, err := q.file.WriteAt(chunk, moved); err != nil {
+			return err
+		}
+		moved += int64(len(chunk))
+	}
+
+	q.readOff = 0
+	q.writeOff = queued
+	return q.file.Truncate(queued)
+}
+
+// remove closes and deletes the backing file, refusing further frames
+func (q *overflowQueue) remove() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.maxBytes = 0
+	q.file.Close()
+	os.Remove(q.file.Name())
+}
+
+// spill queues a frame to the client's overflow queue when Send is full or
+// earlier frames are still spilled. Returns false if the queue is at its cap.
+// Caller must hold c.sendMu.
+func (c *Client) spill(data []byte) bool {
+	q := c.overflow
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.spilling {
+		select {
+		case c.Send <- data:
+			return true
+		default:
+		}
+		q.spilling = true
+	}
+
+	if err := q.push(data); err != nil {
+		if err != errOverflowFull {
+			logrus.WithError(err).WithField("client_id", c.ID).Error("Failed to write client overflow frame")
+		}
+		return false
+	}
+	return true
+}
+
+// drainOverflow moves spilled frames back into Send, oldest first, as the
+// connection catches up. It runs until the client is closed.
+func (c *Client) drainOverflow() {
+	q := c.overflow
+	defer q.remove()
+
+	for {
+		select {
+		case <-q.wake:
+		case <-c.done:
+			return
+		}
+
+		for {
+			q.mu.Lock()
+			if q.count == 0 {
+				// Nothing spilled anymore, new frames may go straight to Send
+				q.spilling = false
+				q.mu.Unlock()
+				break
+			}
+			data, err := q.peek()
+			q.mu.Unlock()
+
+			if err != nil {
+				logrus.WithError(err).WithField("client_id", c.ID).Error("Failed to read client overflow frame")
+				return
+			}
+			if !c.sendBlocking(data) {
+				return
+			}
+
+			q.mu.Lock()
+			q.pop(len(data))
+			q.mu.Unlock()
+		}
+	}
+}
+
+// sendBlocking queues a frame, waiting for buffer space until the client is closed
+func (c *Client) sendBlocking(data []byte) bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+
+	select {
+	case c.Send <- data:
+		return true
+	case <-c.done:
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newOverflowClient creates a client spilling to an overflow queue capped at
+// maxBytes, with its drain loop running
+func newOverflowClient(t *testing.T, maxBytes int64) *Client {
+	t.Helper()
+
+	q, err := newOverflowQueue(t.TempDir(), maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(nil, NewHub())
+	c.overflow = q
+	go c.drainOverflow()
+	t.Cleanup(c.close)
+	return c
+}
+
+func TestOverflowSpillsAndDrainsWithoutLoss(t *testing.T) {
+	c := newOverflowClient(t, 1024*1024)
+
+	total := cap(c.Send) + 500
+	for i := 0; i < total; i++ {
+		if !c.TrySend([]byte(fmt.Sprintf("frame-%d", i))) {
+			t.Fatalf("frame %d refused below the overflow cap", i)
+		}
+	}
+
+	timeout := time.After(2 * time.Second)
+	for i := 0; i < total; i++ {
+		select {
+		case frame := <-c.Send:
+			if want := fmt.Sprintf("frame-%d", i); string(frame) != want {
+				t.Fatalf("received %q, want %q", frame, want)
+			}
+		case <-timeout:
+			t.Fatalf("only %d of %d frames drained", i, total)
+		}
+	}
+}
+
+func TestOverflowRefusesFramesPastTheCap(t *testing.T) {
+	frame := []byte("0123456789")
+	// Room for exactly 3 length-prefixed frames
+	c := newOverflowClient(t, 3*int64(4+len(frame)))
+	fillSendBuffer(c)
+
+	c.overflow.mu.Lock()
+	c.overflow.spilling = true
+	for i := 0; i < 3; i++ {
+		if err := c.overflow.push(frame); err != nil {
+			t.Fatalf("push %d failed below the cap: %v", i, err)
+		}
+	}
+	if err := c.overflow.push(frame); err != errOverflowFull {
+		t.Fatalf("push past the cap = %v, want errOverflowFull", err)
+	}
+	c.overflow.mu.Unlock()
+}
+
+func TestOverflowCapCountsQueuedBytesOnly(t *testing.T) {
+	frame := []byte("0123456789")
+	size := int64(4 + len(frame))
+	q, err := newOverflowQueue(t.TempDir(), 4*size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.remove()
+
+	// A reader that stays one frame behind writes far more than the cap in total
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.push(frame); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := q.push(frame); err != nil {
+			t.Fatalf("push %d failed with one frame queued: %v", i, err)
+		}
+		data, err := q.peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(frame) {
+			t.Fatalf("peek returned %q, want %q", data, frame)
+		}
+		q.pop(len(data))
+
+		stat, err := q.file.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Size() > 2*q.maxBytes {
+			t.Fatalf("overflow file grew to %d bytes with one frame queued, cap %d", stat.Size(), q.maxBytes)
+		}
+	}
+	if q.count != 1 || q.writeOff-q.readOff != size {
+		t.Fatalf("queue holds %d frames, %d bytes, want the one frame behind", q.count, q.writeOff-q.readOff)
+	}
+}
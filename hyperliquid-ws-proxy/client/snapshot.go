@@ -0,0 +1,85 @@
+package client
+
+import (
+	"time"
+)
+
+// queuedSnapshot is an initial snapshot, or a frame held behind one, waiting to be sent
+type queuedSnapshot struct {
+	frames  [][]byte
+	timeout time.Duration // for the whole snapshot, zero for a held frame
+	onDrop  func(sent int)
+}
+
+// QueueSnapshot sends the frames of an initial snapshot, in order, from a
+// goroutine of the client so the caller never waits on buffer space. The whole
+// snapshot has timeout to fit in the buffer: sending stops at the first frame
+// that doesn't fit in time, and onDrop, if set, is called with how many frames
+// were sent. Snapshots are sent one after another in the order they were queued,
+// and frames passed to TrySend meanwhile are held until the snapshots before
+// them are sent, so no update overtakes the snapshot it applies to.
+func (c *Client) QueueSnapshot(frames [][]byte, timeout time.Duration, onDrop func(sent int)) {
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+
+	c.snapshots = append(c.snapshots, queuedSnapshot{frames: frames, timeout: timeout, onDrop: onDrop})
+	if !c.sendingSnapshot {
+		c.sendingSnapshot = true
+		go c.sendSnapshots()
+	}
+}
+
+// holdBehindSnapshot queues a frame behind the snapshots still being sent.
+// pending is false when no snapshot is being sent and the frame can go straight
+// to Send; ok is false when too many frames are held already.
+func (c *Client) holdBehindSnapshot(data []byte) (pending bool, ok bool) {
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+
+	if !c.sendingSnapshot {
+		return false, false
+	}
+	if c.heldFrames >= cap(c.Send) {
+		return true, false
+	}
+	c.heldFrames++
+	c.snapshots = append(c.snapshots, queuedSnapshot{frames: [][]byte{data}})
+	return true, true
+}
+
+// sendSnapshots sends the queued snapshots and held frames until none is left.
+// Held frames wait for buffer space until the deadline of the snapshot before them.
+func (c *Client) sendSnapshots() {
+	var deadline time.Time
+	for {
+		c.snapshotMu.Lock()
+		if len(c.snapshots) == 0 {
+			c.sendingSnapshot = false
+			c.snapshotMu.Unlock()
+			return
+		}
+		snapshot := c.snapshots[0]
+		c.snapshots = c.snapshots[1:]
+		if snapshot.timeout == 0 {
+			c.heldFrames--
+		}
+		c.snapshotMu.Unlock()
+
+		if snapshot.timeout == 0 {
+			if !c.sendBefore(snapshot.frames[0], deadline) {
+				c.Hub.heldDropped(c, snapshot.frames[0])
+			}
+			continue
+		}
+
+		deadline = time.Now().Add(snapshot.timeout)
+		for sent, frame := range snapshot.frames {
+			if !c.sendBefore(frame, deadline) {
+				if snapshot.onDrop != nil {
+					snapshot.onDrop(sent)
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+// fillSendBuffer fills a client's Send buffer with placeholder frames
+func fillSendBuffer(c *Client) {
+	for len(c.Send) < cap(c.Send) {
+		c.Send <- []byte("filler")
+	}
+}
+
+func TestQueueSnapshotArrivesWhenBufferStartsFull(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	fillSendBuffer(c)
+
+	queued := time.Now()
+	c.QueueSnapshot([][]byte{[]byte("a"), []byte("b")}, 2*time.Second, func(sent int) {
+		t.Errorf("snapshot dropped after %d frames", sent)
+	})
+	c.QueueSnapshot([][]byte{[]byte("c")}, 2*time.Second, func(sent int) {
+		t.Errorf("second snapshot dropped after %d frames", sent)
+	})
+	if waited := time.Since(queued); waited > 100*time.Millisecond {
+		t.Fatalf("QueueSnapshot blocked for %s on a full buffer", waited)
+	}
+
+	// The connection catches up after a while
+	time.Sleep(50 * time.Millisecond)
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < 3 {
+		select {
+		case frame := <-c.Send:
+			if string(frame) != "filler" {
+				got = append(got, string(frame))
+			}
+		case <-timeout:
+			t.Fatalf("snapshot frames received %v, want [a b c]", got)
+		}
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("snapshot frames received %v, want [a b c]", got)
+	}
+}
+
+func TestQueueSnapshotStopsAtFirstFrameThatDoesNotFit(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	fillSendBuffer(c)
+
+	dropped := make(chan int, 1)
+	queued := time.Now()
+	c.QueueSnapshot([][]byte{[]byte("a"), []byte("b"), []byte("c")}, 100*time.Millisecond, func(sent int) {
+		dropped <- sent
+	})
+
+	select {
+	case sent := <-dropped:
+		if sent != 0 {
+			t.Fatalf("onDrop got %d sent frames, want 0", sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("snapshot never reported as dropped")
+	}
+
+	// One deadline for the whole snapshot, not one per frame
+	if waited := time.Since(queued); waited > 250*time.Millisecond {
+		t.Fatalf("snapshot waited %s for buffer space, want about 100ms", waited)
+	}
+	if len(c.Send) != cap(c.Send) {
+		t.Fatalf("Send holds %d frames, want it left full", len(c.Send))
+	}
+}
+
+func TestLiveUpdateWaitsForPendingSnapshot(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	fillSendBuffer(c)
+
+	c.QueueSnapshot([][]byte{[]byte("snapshot"), []byte("snapshot-complete")}, 2*time.Second, nil)
+	if !c.TrySend([]byte("update")) {
+		t.Fatal("TrySend refused an update while a snapshot was pending")
+	}
+	c.QueueSnapshot([][]byte{[]byte("second-snapshot")}, 2*time.Second, nil)
+	if !c.TrySend([]byte("second-update")) {
+		t.Fatal("TrySend refused an update behind the second snapshot")
+	}
+
+	want := []string{"snapshot", "snapshot-complete", "update", "second-snapshot", "second-update"}
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case frame := <-c.Send:
+			if string(frame) != "filler" {
+				got = append(got, string(frame))
+			}
+		case <-timeout:
+			t.Fatalf("frames received %v, want %v", got, want)
+		}
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frames received %v, want %v", got, want)
+		}
+	}
+
+	// Once the snapshots are out, updates go straight to Send again
+	time.Sleep(50 * time.Millisecond)
+	if !c.TrySend([]byte("direct")) || len(c.Send) == 0 {
+		t.Fatal("update after the snapshots was not queued directly")
+	}
+}
+
+func TestHeldUpdateDroppedWithItsSnapshot(t *testing.T) {
+	hub := NewHub()
+	dropped := make(chan string, 1)
+	hub.SetOnHeldDrop(func(c *Client, frame []byte) {
+		dropped <- string(frame)
+	})
+	c := NewClient(nil, hub)
+	fillSendBuffer(c)
+
+	c.QueueSnapshot([][]byte{[]byte("snapshot")}, 50*time.Millisecond, nil)
+	c.TrySend([]byte("update"))
+
+	select {
+	case frame := <-dropped:
+		if frame != "update" {
+			t.Fatalf("held drop reported %q, want update", frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dropped held update was never reported")
+	}
+}
+
+func TestHeldUpdatesAreBounded(t *testing.T) {
+	c := NewClient(nil, NewHub())
+	fillSendBuffer(c)
+
+	c.QueueSnapshot([][]byte{[]byte("snapshot")}, time.Second, nil)
+	for i := 0; i < cap(c.Send); i++ {
+		if !c.TrySend([]byte("update")) {
+			t.Fatalf("TrySend refused held update %d", i)
+		}
+	}
+	if c.TrySend([]byte("update")) {
+		t.Fatal("TrySend held more updates than the Send buffer holds")
+	}
+}
@@ -3,35 +3,93 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
 	Server struct {
-		Host string `yaml:"host"`
-		Port int    `yaml:"port"`
+		Host               string   `yaml:"host"`
+		Port               int      `yaml:"port"`
+		ShutdownTimeoutSec int      `yaml:"shutdown_timeout_sec"` // Time allowed to drain connections on stop
+		AllowedOrigins     []string `yaml:"allowed_origins"`      // WebSocket Origin allowlist, empty allows all
 	} `yaml:"server"`
 	
 	Hyperliquid struct {
 		MainnetURL string `yaml:"mainnet_url"`
 		TestnetURL string `yaml:"testnet_url"`
 		Network    string `yaml:"network"` // "mainnet" or "testnet"
+		InfoTimeoutSec        int `yaml:"info_timeout_sec"`         // timeout of each asset metadata request to the info endpoint
+		InfoInitialTimeoutSec int `yaml:"info_initial_timeout_sec"` // startup waits this long for asset metadata before using fallback names, 0 waits indefinitely
 	} `yaml:"hyperliquid"`
 	
 	Logging struct {
-		Level  string `yaml:"level"`
-		Format string `yaml:"format"`
+		Level            string `yaml:"level"`
+		Format           string `yaml:"format"`
+		StatsIntervalSec int    `yaml:"stats_interval_sec"` // 0 disables periodic stats logging
+		StatsLevel       string `yaml:"stats_level"`
+		DropLogIntervalSec int  `yaml:"drop_log_interval_sec"` // at most one dropped-message log per channel per interval, 0 disables
+		StatsResetIntervalSec int `yaml:"stats_reset_interval_sec"` // start a new since-reset window of /stats counters every interval, 0 disables
 	} `yaml:"logging"`
 	
 	Proxy struct {
 		MaxClients           int  `yaml:"max_clients"`
+		MaxSubscriptionsPerClient int `yaml:"max_subscriptions_per_client"` // 0 means unlimited
 		EnableHeartbeat      bool `yaml:"enable_heartbeat"`
 		HeartbeatInterval    int  `yaml:"heartbeat_interval"`
-		ReconnectMaxRetries  int  `yaml:"reconnect_max_retries"`
-		ReconnectInterval    int  `yaml:"reconnect_interval"`
+		ReconnectMaxRetries  int  `yaml:"reconnect_max_retries"` // 0 retries forever
+		ReconnectInterval    int  `yaml:"reconnect_interval"`    // delay before the first reconnection attempt, doubled on each further one
+		ReconnectMaxDelay    int  `yaml:"reconnect_max_delay"`   // cap on the reconnection delay in seconds
+		UpstreamDialRate     float64 `yaml:"upstream_dial_rate"` // dials to Hyperliquid per second across all connectors, 0 means unlimited
+		UpstreamSubscribeBatch int `yaml:"upstream_subscribe_batch"` // subscribe messages sent upstream per second, 0 means unpaced
 		BufferSize           int  `yaml:"buffer_size"`
 		EnableLocalNode      bool `yaml:"enable_local_node"`
 		LocalNodeDataPath    string `yaml:"local_node_data_path"`
+		MaxConcurrentPosts   int    `yaml:"max_concurrent_posts"` // 0 means unlimited
+		FanOutWorkers        int    `yaml:"fan_out_workers"`      // concurrent sends per message to large subscriptions, 0 or 1 sends sequentially
+		InfoCacheDefaultTTLMs int            `yaml:"info_cache_default_ttl_ms"` // 0 disables caching for unlisted info types
+		InfoCacheTTLMs        map[string]int `yaml:"info_cache_ttl_ms"`         // info request type -> TTL
+		MaxBlockAgeSec        int            `yaml:"max_block_age_sec"`         // 0 uses the network default
+		MaxUpstreamStaleSec   int            `yaml:"max_upstream_stale_sec"`    // 0 uses the default
+		EnrichTradesNotional  bool           `yaml:"enrich_trades_notional"`    // add px*sz "notional" to trades
+		MaxTotalTrades        int            `yaml:"max_total_trades"`          // cap across all coins, 0 means unlimited
+		MaxTradesPerCoin      int            `yaml:"max_trades_per_coin"`       // trades retained per coin, 0 means unlimited
+		MaxBlocksInMemory     int            `yaml:"max_blocks_in_memory"`      // recent blocks retained, 0 means unlimited
+		TradeEvictionPolicy   string         `yaml:"trade_eviction_policy"`     // "least_recent" or "largest"
+		SubscriptionKeepaliveSec int         `yaml:"subscription_keepalive_sec"` // 0 disables keepalive data frames
+		MaxConnectionDurationSec int         `yaml:"max_connection_duration_sec"` // close connections older than this so clients reconnect, 0 disables
+		BackpressureAdvisorySec  int         `yaml:"backpressure_advisory_sec"`   // notify clients whose buffer stays near capacity this long, 0 disables
+		BackpressureAdvisoryFillPct int      `yaml:"backpressure_advisory_fill_pct"` // buffer fill, in percent, counted as near capacity
+		AssetCtxIntervalSec   int            `yaml:"asset_ctx_interval_sec"`    // fetch perp funding and open interest from the info API this often, 0 disables
+		DataSourceGraceSec    int            `yaml:"data_source_grace_sec"`     // time allowed for the first local block at startup, 0 skips the wait
+		EnableNamespaces      bool           `yaml:"enable_namespaces"`         // allow ?namespace= to prefix client channels
+		AssetMapFile          string         `yaml:"asset_map_file"`            // JSON asset id -> symbol mapping, reloaded on SIGHUP
+		AssetMapOverride      bool           `yaml:"asset_map_override"`        // prefer the file over API metadata
+		InitialSnapshotDepths map[string]int `yaml:"initial_snapshot_depths"`   // channel -> items sent on subscribe
+		ColdStartPolicy       string         `yaml:"cold_start_policy"`         // "catch_up" or "skip_to_live"
+		ColdStartTailBytes    int64          `yaml:"cold_start_tail_bytes"`     // tail of the latest block file read on skip_to_live
+		EnableCompression     bool           `yaml:"enable_compression"`        // permessage-deflate to clients and upstream
+		CompressionLevel      int            `yaml:"compression_level"`         // flate level, 1 (fastest) to 9 (smallest)
+		NotifyUpstreamStatus  bool           `yaml:"notify_upstream_status"`    // notify clients when upstream data pauses and resumes
+		MinUpdateIntervalMs   map[string]int `yaml:"min_update_interval_ms"`    // subscription type -> minimum time between updates
+		DuplicateTIDPolicy    string         `yaml:"duplicate_tid_policy"`      // "keep", "drop" or "restamp"
+		DuplicateTIDWindowSec int            `yaml:"duplicate_tid_window_sec"`  // how long TIDs are remembered per coin
+		CandleBackfillCount   int            `yaml:"candle_backfill_count"`     // closed candles rebuilt from trades on subscribe, 0 disables
+		CandleBackfillGaps    string         `yaml:"candle_backfill_gaps"`      // "flat" or "skip" for buckets without trades
+		CandleStateFile       string         `yaml:"candle_state_file"`         // open candles persisted across restarts, empty disables
+		CandleVolumeUnit      string         `yaml:"candle_volume_unit"`        // "base" (summed sizes) or "quote" (summed notionals) for local node candle v
+		ReadPositionsFile     string         `yaml:"read_positions_file"`       // block file read positions resumed from on restart, empty disables
+		ReadPositionsIntervalSec int         `yaml:"read_positions_interval_sec"` // how often read positions are checkpointed
+		ReplayFromTimestamp   string         `yaml:"replay_from_timestamp"`     // RFC 3339 time to replay local node blocks from on startup, empty disables
+		IncludeBuilderFees    bool           `yaml:"include_builder_fees"`      // builderFee and feeToken on local node fills of builder-routed orders
+		APIKeys               map[string]string `yaml:"api_keys"`               // key name -> key required to connect, empty allows anyone
+		DiskOverflowDir       string         `yaml:"disk_overflow_dir"`         // spill frames beyond a client's buffer to files here, empty disables
+		DiskOverflowMaxBytes  int64          `yaml:"disk_overflow_max_bytes"`   // cap on the bytes queued in each client's overflow file
+		DiskOverflowKeys      []string       `yaml:"disk_overflow_keys"`        // API key names eligible for overflow, empty means every client
+		CompressedSnapshotMinBytes int       `yaml:"compressed_snapshot_min_bytes"` // l2Book snapshots smaller than this stay JSON even with compressSnapshot
+		SnapshotCompleteMarker bool          `yaml:"snapshot_complete_marker"`  // follow user channel snapshots with a snapshotComplete frame
+		TradeUsersRedaction   string         `yaml:"trade_users_redaction"`     // "redact" or "hash" the users of trades sent to clients without an API key, empty keeps them
 	} `yaml:"proxy"`
 }
 
@@ -41,19 +99,46 @@ func LoadConfig(configPath string) (*Config, error) {
 	// Default values
 	config.Server.Host = "0.0.0.0"
 	config.Server.Port = 8080
+	config.Server.ShutdownTimeoutSec = 10
 	config.Hyperliquid.MainnetURL = "wss://api.hyperliquid.xyz/ws"
 	config.Hyperliquid.TestnetURL = "wss://api.hyperliquid-testnet.xyz/ws"
 	config.Hyperliquid.Network = "mainnet"
+	config.Hyperliquid.InfoTimeoutSec = 10
+	config.Hyperliquid.InfoInitialTimeoutSec = 15
 	config.Logging.Level = "info"
 	config.Logging.Format = "text"
+	config.Logging.StatsIntervalSec = 10
+	config.Logging.StatsLevel = "debug"
+	config.Logging.DropLogIntervalSec = 10
 	config.Proxy.MaxClients = 1000
+	config.Proxy.MaxSubscriptionsPerClient = 100
 	config.Proxy.EnableHeartbeat = true
 	config.Proxy.HeartbeatInterval = 30
 	config.Proxy.ReconnectMaxRetries = 5
 	config.Proxy.ReconnectInterval = 5
+	config.Proxy.ReconnectMaxDelay = 60
 	config.Proxy.BufferSize = 1024
 	config.Proxy.EnableLocalNode = false
 	config.Proxy.LocalNodeDataPath = "/home/hluser/hl/data"
+	config.Proxy.MaxConcurrentPosts = 100
+	config.Proxy.DataSourceGraceSec = 30
+	config.Proxy.MaxTotalTrades = 200000
+	config.Proxy.MaxTradesPerCoin = 1000
+	config.Proxy.MaxBlocksInMemory = 100
+	config.Proxy.TradeEvictionPolicy = "least_recent"
+	config.Proxy.InitialSnapshotDepths = map[string]int{"trades": 5}
+	config.Proxy.ColdStartPolicy = "catch_up"
+	config.Proxy.DuplicateTIDPolicy = "keep"
+	config.Proxy.DuplicateTIDWindowSec = 60
+	config.Proxy.CandleBackfillCount = 10
+	config.Proxy.CandleBackfillGaps = "flat"
+	config.Proxy.CandleVolumeUnit = "base"
+	config.Proxy.ReadPositionsIntervalSec = 10
+	config.Proxy.DiskOverflowMaxBytes = 64 * 1024 * 1024
+	config.Proxy.EnableCompression = true
+	config.Proxy.CompressionLevel = 1
+	config.Proxy.ColdStartTailBytes = 10 * 1024 * 1024
+	config.Proxy.BackpressureAdvisoryFillPct = 80
 	
 	if configPath == "" {
 		return config, nil
@@ -70,9 +155,62 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("error decoding config file: %v", err)
 	}
 	
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	
 	return config, nil
 }
 
+// Validate checks that configured values are within their allowed ranges
+func (c *Config) Validate() error {
+	if c.Proxy.MaxTradesPerCoin < 0 {
+		return fmt.Errorf("max_trades_per_coin must be 0 (unlimited) or positive, got %d", c.Proxy.MaxTradesPerCoin)
+	}
+	if c.Proxy.MaxBlocksInMemory < 0 {
+		return fmt.Errorf("max_blocks_in_memory must be 0 (unlimited) or positive, got %d", c.Proxy.MaxBlocksInMemory)
+	}
+	if c.Proxy.TradeUsersRedaction != "" && c.Proxy.TradeUsersRedaction != "redact" && c.Proxy.TradeUsersRedaction != "hash" {
+		return fmt.Errorf("trade_users_redaction must be empty, \"redact\" or \"hash\", got %q", c.Proxy.TradeUsersRedaction)
+	}
+	if c.Proxy.CandleVolumeUnit != "base" && c.Proxy.CandleVolumeUnit != "quote" {
+		return fmt.Errorf("candle_volume_unit must be \"base\" or \"quote\", got %q", c.Proxy.CandleVolumeUnit)
+	}
+	if c.Hyperliquid.InfoTimeoutSec <= 0 {
+		return fmt.Errorf("info_timeout_sec must be positive, got %d", c.Hyperliquid.InfoTimeoutSec)
+	}
+	if c.Hyperliquid.InfoInitialTimeoutSec < 0 {
+		return fmt.Errorf("info_initial_timeout_sec must be 0 (wait indefinitely) or positive, got %d", c.Hyperliquid.InfoInitialTimeoutSec)
+	}
+	if c.Proxy.AssetCtxIntervalSec < 0 {
+		return fmt.Errorf("asset_ctx_interval_sec must be 0 (off) or positive, got %d", c.Proxy.AssetCtxIntervalSec)
+	}
+	if c.Proxy.MaxConnectionDurationSec < 0 {
+		return fmt.Errorf("max_connection_duration_sec must be 0 (unlimited) or positive, got %d", c.Proxy.MaxConnectionDurationSec)
+	}
+	if c.Proxy.BackpressureAdvisorySec < 0 {
+		return fmt.Errorf("backpressure_advisory_sec must be 0 (off) or positive, got %d", c.Proxy.BackpressureAdvisorySec)
+	}
+	if c.Proxy.BackpressureAdvisoryFillPct < 1 || c.Proxy.BackpressureAdvisoryFillPct > 100 {
+		return fmt.Errorf("backpressure_advisory_fill_pct must be between 1 and 100, got %d", c.Proxy.BackpressureAdvisoryFillPct)
+	}
+	if c.Proxy.ReconnectMaxRetries < 0 {
+		return fmt.Errorf("reconnect_max_retries must be 0 (forever) or positive, got %d", c.Proxy.ReconnectMaxRetries)
+	}
+	if c.Proxy.ReplayFromTimestamp != "" {
+		if _, err := time.Parse(time.RFC3339, c.Proxy.ReplayFromTimestamp); err != nil {
+			return fmt.Errorf("replay_from_timestamp must be an RFC 3339 time such as 2025-01-02T15:04:05Z, got %q", c.Proxy.ReplayFromTimestamp)
+		}
+	}
+	if c.Proxy.UpstreamDialRate < 0 {
+		return fmt.Errorf("upstream_dial_rate must be 0 (unlimited) or positive, got %v", c.Proxy.UpstreamDialRate)
+	}
+	if c.Proxy.ReconnectInterval <= 0 {
+		return fmt.Errorf("reconnect_interval must be positive, got %d", c.Proxy.ReconnectInterval)
+	}
+	return nil
+}
+
 func (c *Config) GetHyperliquidURL() string {
 	if c.Hyperliquid.Network == "testnet" {
 		return c.Hyperliquid.TestnetURL
@@ -80,6 +218,56 @@ func (c *Config) GetHyperliquidURL() string {
 	return c.Hyperliquid.MainnetURL
 }
 
+// GetInfoURL returns the HTTP info endpoint of the configured network, derived
+// from its WebSocket URL so asset metadata always matches the network
+func (c *Config) GetInfoURL() string {
+	url := c.GetHyperliquidURL()
+	url = strings.Replace(url, "wss://", "https://", 1)
+	url = strings.Replace(url, "ws://", "http://", 1)
+	return strings.TrimSuffix(url, "/ws") + "/info"
+}
+
+// GetMaxBlockAge returns how old the latest local node block may be before /health
+// reports unhealthy. Mainnet produces blocks continuously, so the default is tight;
+// testnet can be quiet for longer stretches.
+func (c *Config) GetMaxBlockAge() time.Duration {
+	if c.Proxy.MaxBlockAgeSec > 0 {
+		return time.Duration(c.Proxy.MaxBlockAgeSec) * time.Second
+	}
+	if c.Hyperliquid.Network == "testnet" {
+		return 30 * time.Second
+	}
+	return 5 * time.Second
+}
+
+// GetReplayFrom returns the time local node blocks are replayed from on
+// startup, zero when replay is disabled
+func (c *Config) GetReplayFrom() time.Time {
+	if c.Proxy.ReplayFromTimestamp == "" {
+		return time.Time{}
+	}
+	from, err := time.Parse(time.RFC3339, c.Proxy.ReplayFromTimestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return from
+}
+
+// GetMaxUpstreamStale returns how long the upstream connection may stay silent
+// before /health reports unhealthy. Heartbeats are exchanged every 50 seconds.
+func (c *Config) GetMaxUpstreamStale() time.Duration {
+	if c.Proxy.MaxUpstreamStaleSec > 0 {
+		return time.Duration(c.Proxy.MaxUpstreamStaleSec) * time.Second
+	}
+	return 90 * time.Second
+}
+
+// GetInitialSnapshotDepth returns how many items of a channel's history are sent
+// to a client when it subscribes, 0 if the channel has no configured depth
+func (c *Config) GetInitialSnapshotDepth(channel string) int {
+	return c.Proxy.InitialSnapshotDepths[channel]
+}
+
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 } 
\ No newline at end of file
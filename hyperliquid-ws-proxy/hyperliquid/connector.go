@@ -3,6 +3,7 @@ package hyperliquid
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 	"hyperliquid-ws-proxy/types"
 )
 
+// Window in which at most the configured batch of subscribe messages is sent
+const subscribeBatchInterval = time.Second
+
 // Connector manages the connection to Hyperliquid WebSocket API
 type Connector struct {
 	URL         string
@@ -24,6 +28,7 @@ type Connector struct {
 	
 	// Subscription management
 	subscriptions    map[string]*types.SubscriptionRequest
+	refCounts       map[string]int // subscription key -> Subscribe calls not yet undone
 	subMu           sync.RWMutex
 	
 	// Post request management
@@ -31,21 +36,34 @@ type Connector struct {
 	postMu          sync.RWMutex
 	nextRequestID   int64
 	
-	// Reconnection settings
+	// Reconnection settings, the delay doubles from retryInterval up to
+	// maxRetryDelay; maxRetries 0 retries forever
 	maxRetries      int
 	retryInterval   time.Duration
-	currentRetries  int
+	maxRetryDelay   time.Duration
+	stopped         bool // set by Disconnect, ends any reconnection
+	
+	// Negotiate permessage-deflate on the upstream connection
+	enableCompression bool
+	
+	// Subscribe pacing, at most subscribeBatch messages per subscribeBatchInterval
+	paceMu          sync.Mutex
+	subscribeBatch  int
+	batchStart      time.Time
+	batchSent       int
 	
 	// Heartbeat
 	enableHeartbeat bool
 	heartbeatInterval time.Duration
 	lastPong        time.Time
+	lastMessage     time.Time // last frame of any kind received from Hyperliquid
 	
 	// Event handlers
 	onMessage       func([]byte)
-	onConnect       func()
+	onConnect       func(reconnected bool)
 	onDisconnect    func(error)
 	onError         func(error)
+	onResubscribed  func(count int)
 }
 
 // NewConnector creates a new Hyperliquid connector
@@ -55,19 +73,53 @@ func NewConnector(url string) *Connector {
 		incomingMessages:  make(chan []byte, 1000),
 		outgoingMessages:  make(chan []byte, 1000),
 		subscriptions:     make(map[string]*types.SubscriptionRequest),
+		refCounts:         make(map[string]int),
 		postRequests:      make(map[int64]chan *types.PostResponse),
 		maxRetries:        5,
 		retryInterval:     5 * time.Second,
+		maxRetryDelay:     time.Minute,
 		enableHeartbeat:   true,
 		heartbeatInterval: 30 * time.Second,
 		nextRequestID:     1,
 	}
 }
 
+// SetCompression sets whether permessage-deflate is negotiated on the next dial
+func (c *Connector) SetCompression(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enableCompression = enabled
+}
+
+// SetSubscribeBatch sets how many subscribe messages are sent per second, 0 means unpaced
+func (c *Connector) SetSubscribeBatch(size int) {
+	c.paceMu.Lock()
+	defer c.paceMu.Unlock()
+	c.subscribeBatch = size
+}
+
+// SetReconnectPolicy sets how many reconnection attempts are made, 0 meaning
+// forever, and the delay before the first one, which doubles on each further
+// attempt up to maxDelay
+func (c *Connector) SetReconnectPolicy(maxRetries int, interval, maxDelay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxRetries = maxRetries
+	c.retryInterval = interval
+	c.maxRetryDelay = maxDelay
+}
+
+// SetOnResubscribed sets a callback run once subscriptions were restored after a connect
+func (c *Connector) SetOnResubscribed(onResubscribed func(count int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onResubscribed = onResubscribed
+}
+
 // SetEventHandlers sets the event handlers
 func (c *Connector) SetEventHandlers(
 	onMessage func([]byte),
-	onConnect func(),
+	onConnect func(reconnected bool),
 	onDisconnect func(error),
 	onError func(error),
 ) {
@@ -79,9 +131,23 @@ func (c *Connector) SetEventHandlers(
 
 // Connect establishes connection to Hyperliquid WebSocket
 func (c *Connector) Connect() error {
+	c.mu.Lock()
+	c.stopped = false
+	c.mu.Unlock()
+	
+	return c.connect(false)
+}
+
+// connect dials Hyperliquid, reporting to onConnect whether it is a reconnection
+func (c *Connector) connect(reconnected bool) error {
 	logrus.WithField("url", c.URL).Info("Connecting to Hyperliquid WebSocket")
 	
-	conn, _, err := websocket.DefaultDialer.Dial(c.URL, nil)
+	// Take a turn among every connector's dials
+	upstreamDials.wait()
+	
+	dialer := *websocket.DefaultDialer
+	dialer.EnableCompression = c.enableCompression
+	conn, _, err := dialer.Dial(c.URL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Hyperliquid: %v", err)
 	}
@@ -89,7 +155,6 @@ func (c *Connector) Connect() error {
 	c.mu.Lock()
 	c.conn = conn
 	c.isConnected = true
-	c.currentRetries = 0
 	c.lastPong = time.Now()
 	c.mu.Unlock()
 	
@@ -105,7 +170,7 @@ func (c *Connector) Connect() error {
 	go c.resubscribeAll()
 	
 	if c.onConnect != nil {
-		c.onConnect()
+		c.onConnect(reconnected)
 	}
 	
 	return nil
@@ -116,6 +181,7 @@ func (c *Connector) Disconnect() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
+	c.stopped = true
 	if c.conn != nil && c.isConnected {
 		c.isConnected = false
 		c.conn.Close()
@@ -130,20 +196,50 @@ func (c *Connector) IsConnected() bool {
 	return c.isConnected
 }
 
-// Subscribe sends a subscription request to Hyperliquid
+// LastActivity returns when the last frame was received from Hyperliquid
+func (c *Connector) LastActivity() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastMessage
+}
+
+// Subscribe takes a reference on a subscription, sending the subscription
+// request to Hyperliquid only for the first one. Each successful Subscribe must
+// be undone by one Unsubscribe.
 func (c *Connector) Subscribe(subscription *types.SubscriptionRequest) error {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected to Hyperliquid")
+		return types.NewProxyError(types.ErrCodeNotConnected, "not connected to Hyperliquid", true)
 	}
 	
 	// Create subscription key
-	key := c.createSubscriptionKey(subscription)
+	key := subscription.Key()
 	
 	// Store subscription
 	c.subMu.Lock()
-	c.subscriptions[key] = subscription
+	c.refCounts[key]++
+	first := c.refCounts[key] == 1
+	if first {
+		c.subscriptions[key] = subscription
+	}
 	c.subMu.Unlock()
 	
+	if !first {
+		return nil
+	}
+	
+	if err := c.sendSubscribe(subscription); err != nil {
+		// Nothing was subscribed, give the reference back
+		c.release(key)
+		return err
+	}
+	return nil
+}
+
+// sendSubscribe sends a subscription request to Hyperliquid, paced when
+// configured, without touching the reference counts
+func (c *Connector) sendSubscribe(subscription *types.SubscriptionRequest) error {
+	c.paceSubscribe()
+	
 	// Send subscription message
 	message := types.WSMessage{
 		Method:       "subscribe",
@@ -153,19 +249,56 @@ func (c *Connector) Subscribe(subscription *types.SubscriptionRequest) error {
 	return c.sendMessage(message)
 }
 
-// Unsubscribe sends an unsubscription request to Hyperliquid
-func (c *Connector) Unsubscribe(subscription *types.SubscriptionRequest) error {
-	if !c.IsConnected() {
-		return fmt.Errorf("not connected to Hyperliquid")
+// release drops one reference on a subscription, forgetting it once none is
+// left. Returns true if that was the last reference.
+func (c *Connector) release(key string) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	
+	if c.refCounts[key] == 0 {
+		return false
+	}
+	c.refCounts[key]--
+	if c.refCounts[key] > 0 {
+		return false
 	}
+	delete(c.refCounts, key)
+	delete(c.subscriptions, key)
+	return true
+}
+
+// paceSubscribe blocks until the current batch window has room for another
+// subscribe message, so a burst of subscriptions reaches Hyperliquid spread out
+func (c *Connector) paceSubscribe() {
+	c.paceMu.Lock()
+	defer c.paceMu.Unlock()
 	
-	// Create subscription key
-	key := c.createSubscriptionKey(subscription)
+	if c.subscribeBatch <= 0 {
+		return
+	}
 	
-	// Remove subscription
-	c.subMu.Lock()
-	delete(c.subscriptions, key)
-	c.subMu.Unlock()
+	now := time.Now()
+	if now.Sub(c.batchStart) >= subscribeBatchInterval {
+		c.batchStart = now
+		c.batchSent = 0
+	}
+	if c.batchSent >= c.subscribeBatch {
+		wait := c.batchStart.Add(subscribeBatchInterval).Sub(now)
+		logrus.WithField("wait", wait).Debug("Subscribe batch full, pacing upstream subscriptions")
+		time.Sleep(wait)
+		c.batchStart = time.Now()
+		c.batchSent = 0
+	}
+	c.batchSent++
+}
+
+// Unsubscribe drops a reference taken by Subscribe, sending the unsubscription
+// request to Hyperliquid only when the last one goes. Without a connection the
+// reference is still dropped, so the subscription isn't restored on reconnect.
+func (c *Connector) Unsubscribe(subscription *types.SubscriptionRequest) error {
+	if !c.release(subscription.Key()) || !c.IsConnected() {
+		return nil
+	}
 	
 	// Send unsubscription message
 	message := types.WSMessage{
@@ -176,10 +309,21 @@ func (c *Connector) Unsubscribe(subscription *types.SubscriptionRequest) error {
 	return c.sendMessage(message)
 }
 
+// ForgetSubscription drops a subscription Hyperliquid never accepted, with all
+// its references, so it isn't restored on reconnect, without sending an unsubscribe
+func (c *Connector) ForgetSubscription(subscription *types.SubscriptionRequest) {
+	key := subscription.Key()
+	
+	c.subMu.Lock()
+	delete(c.refCounts, key)
+	delete(c.subscriptions, key)
+	c.subMu.Unlock()
+}
+
 // PostRequest sends a POST request via WebSocket
 func (c *Connector) PostRequest(requestType string, payload json.RawMessage) (*types.PostResponse, error) {
 	if !c.IsConnected() {
-		return nil, fmt.Errorf("not connected to Hyperliquid")
+		return nil, types.NewProxyError(types.ErrCodeNotConnected, "not connected to Hyperliquid", true)
 	}
 	
 	// Generate request ID
@@ -192,12 +336,12 @@ func (c *Connector) PostRequest(requestType string, payload json.RawMessage) (*t
 	c.postRequests[requestID] = responseChan
 	c.postMu.Unlock()
 	
-	// Clean up channel after timeout
+	// Stop routing responses to this request once it returns. The channel is
+	// left open: a response racing the timeout finds it buffered, never closed.
 	defer func() {
 		c.postMu.Lock()
 		delete(c.postRequests, requestID)
 		c.postMu.Unlock()
-		close(responseChan)
 	}()
 	
 	// Send request
@@ -219,7 +363,7 @@ func (c *Connector) PostRequest(requestType string, payload json.RawMessage) (*t
 	case response := <-responseChan:
 		return response, nil
 	case <-time.After(30 * time.Second):
-		return nil, fmt.Errorf("request timeout")
+		return nil, types.NewProxyError(types.ErrCodeTimeout, "request timeout", true)
 	}
 }
 
@@ -320,6 +464,10 @@ func (c *Connector) heartbeatLoop() {
 
 // processMessage processes incoming messages from Hyperliquid
 func (c *Connector) processMessage(data []byte) {
+	c.mu.Lock()
+	c.lastMessage = time.Now()
+	c.mu.Unlock()
+	
 	// Check for heartbeat response (pong) - ignore it
 	if string(data) == `{"method":"pong"}` || string(data) == `{"status":"pong"}` {
 		logrus.Debug("Received JSON pong from Hyperliquid")
@@ -334,9 +482,9 @@ func (c *Connector) processMessage(data []byte) {
 		return
 	}
 	
-	// Handle POST responses
-	if msg.Channel == "post" && msg.ID != nil {
-		c.handlePostResponse(msg.ID, data)
+	// POST responses go only to the request waiting on them, never to subscribers
+	if msg.Channel == "post" {
+		c.handlePostResponse(msg.Data)
 		return
 	}
 	
@@ -346,24 +494,22 @@ func (c *Connector) processMessage(data []byte) {
 	}
 }
 
-// handlePostResponse handles POST request responses
-func (c *Connector) handlePostResponse(requestID *int64, data []byte) {
-	if requestID == nil {
+// handlePostResponse routes a POST response to the request that issued it. The
+// request ID is carried inside the frame's data, {"channel":"post","data":{"id":..,"response":..}};
+// IDs are unique per connector, so each response matches exactly one waiting request.
+func (c *Connector) handlePostResponse(data json.RawMessage) {
+	var response types.PostResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		logrus.WithError(err).Error("Failed to parse POST response")
 		return
 	}
 	
 	c.postMu.RLock()
-	responseChan, exists := c.postRequests[*requestID]
+	responseChan, exists := c.postRequests[response.ID]
 	c.postMu.RUnlock()
 	
 	if !exists {
-		return
-	}
-	
-	// Parse response
-	var response types.PostResponse
-	if err := json.Unmarshal(data, &response); err != nil {
-		logrus.WithError(err).Error("Failed to parse POST response")
+		logrus.WithField("request_id", response.ID).Debug("Dropping POST response with no pending request")
 		return
 	}
 	
@@ -386,7 +532,7 @@ func (c *Connector) sendMessage(message interface{}) error {
 	case c.outgoingMessages <- data:
 		return nil
 	case <-time.After(5 * time.Second):
-		return fmt.Errorf("send timeout")
+		return types.NewProxyError(types.ErrCodeTimeout, "send timeout", true)
 	}
 }
 
@@ -413,26 +559,37 @@ func (c *Connector) handleDisconnect(err error) {
 	}
 }
 
-// attemptReconnect attempts to reconnect with exponential backoff
+// attemptReconnect attempts to reconnect with exponential backoff, until
+// connected, out of retries or stopped by Disconnect
 func (c *Connector) attemptReconnect() {
-	for c.currentRetries < c.maxRetries {
-		c.currentRetries++
-		
-		delay := time.Duration(c.currentRetries) * c.retryInterval
+	c.mu.RLock()
+	maxRetries := c.maxRetries
+	c.mu.RUnlock()
+	
+	for attempt := 1; maxRetries == 0 || attempt <= maxRetries; attempt++ {
+		delay := c.reconnectDelay(attempt)
 		logrus.WithFields(logrus.Fields{
-			"attempt": c.currentRetries,
+			"attempt": attempt,
 			"delay":   delay,
 		}).Info("Attempting to reconnect...")
 		
 		time.Sleep(delay)
 		
-		if err := c.Connect(); err != nil {
+		c.mu.RLock()
+		stopped := c.stopped
+		c.mu.RUnlock()
+		if stopped {
+			logrus.Info("Reconnection cancelled, connector was disconnected")
+			return
+		}
+		
+		if err := c.connect(true); err != nil {
 			logrus.WithError(err).Error("Reconnection failed")
 			if c.onError != nil {
 				c.onError(err)
 			}
 		} else {
-			logrus.Info("Reconnected successfully")
+			logrus.WithField("attempts", attempt).Info("Reconnected successfully")
 			return
 		}
 	}
@@ -440,6 +597,29 @@ func (c *Connector) attemptReconnect() {
 	logrus.Error("Max reconnection attempts reached")
 }
 
+// reconnectDelay returns the wait before a reconnection attempt: retryInterval
+// doubled for each earlier attempt, capped at maxRetryDelay (no growth when it
+// is below retryInterval), with up to 20% random jitter taken off so proxies
+// dropped together don't redial in lockstep
+func (c *Connector) reconnectDelay(attempt int) time.Duration {
+	c.mu.RLock()
+	interval, maxDelay := c.retryInterval, c.maxRetryDelay
+	c.mu.RUnlock()
+	
+	delay := interval
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay && maxDelay >= interval {
+		delay = maxDelay
+	}
+	
+	if jitter := int64(delay / 5); jitter > 0 {
+		delay -= time.Duration(rand.Int63n(jitter))
+	}
+	return delay
+}
+
 // resubscribeAll resubscribes to all active subscriptions
 func (c *Connector) resubscribeAll() {
 	// Wait a bit for connection to stabilize
@@ -452,36 +632,51 @@ func (c *Connector) resubscribeAll() {
 	}
 	c.subMu.RUnlock()
 	
+	c.paceMu.Lock()
+	paced := c.subscribeBatch > 0
+	c.paceMu.Unlock()
+	
 	for _, sub := range subs {
-		if err := c.Subscribe(sub); err != nil {
+		if !c.IsConnected() {
+			logrus.Warn("Connection lost while resubscribing")
+			break
+		}
+		
+		// Restore the subscription without taking another reference; sendSubscribe
+		// paces batches itself when configured
+		if err := c.sendSubscribe(sub); err != nil {
 			logrus.WithError(err).Error("Failed to resubscribe")
 		} else {
 			logrus.WithField("type", sub.Type).Debug("Resubscribed")
 		}
 		
 		// Small delay between subscriptions
-		time.Sleep(100 * time.Millisecond)
+		if !paced {
+			time.Sleep(100 * time.Millisecond)
+		}
 	}
 	
 	logrus.WithField("count", len(subs)).Info("Resubscribed to all subscriptions")
+	
+	c.mu.RLock()
+	onResubscribed := c.onResubscribed
+	c.mu.RUnlock()
+	if onResubscribed != nil {
+		onResubscribed(len(subs))
+	}
 }
 
-// createSubscriptionKey creates a unique key for a subscription
-func (c *Connector) createSubscriptionKey(sub *types.SubscriptionRequest) string {
-	key := sub.Type
-	if sub.User != "" {
-		key += "-" + sub.User
-	}
-	if sub.Coin != "" {
-		key += "-" + sub.Coin
-	}
-	if sub.Interval != "" {
-		key += "-" + sub.Interval
-	}
-	if sub.Dex != "" {
-		key += "-" + sub.Dex
+// GetSubscriptionRefCounts returns how many references each active
+// subscription holds, by subscription key
+func (c *Connector) GetSubscriptionRefCounts() map[string]int {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	
+	refCounts := make(map[string]int, len(c.refCounts))
+	for k, v := range c.refCounts {
+		refCounts[k] = v
 	}
-	return key
+	return refCounts
 }
 
 // GetSubscriptions returns a copy of all active subscriptions
@@ -0,0 +1,85 @@
+package hyperliquid
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestMain(m *testing.M) {
+	// Connection and subscription info logs drown test output
+	logrus.SetLevel(logrus.WarnLevel)
+	os.Exit(m.Run())
+}
+
+// newConnectedConnector returns a connector marked connected without dialing,
+// its subscribe messages left in outgoingMessages
+func newConnectedConnector() *Connector {
+	c := NewConnector("ws://unused")
+	c.isConnected = true
+	return c
+}
+
+func TestSubscribeBurstIsSentInBatches(t *testing.T) {
+	coins := []string{"BTC", "ETH", "SOL", "ARB", "DOGE"}
+	
+	cases := []struct {
+		name  string
+		batch int
+		want  []int // messages sent by the end of each window
+	}{
+		{"unpaced", 0, []int{5}},
+		{"batches of 2", 2, []int{2, 4, 5}},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newConnectedConnector()
+			c.SetSubscribeBatch(tc.batch)
+			
+			go func() {
+				for _, coin := range coins {
+					if err := c.Subscribe(&types.SubscriptionRequest{Type: "trades", Coin: coin}); err != nil {
+						t.Error(err)
+					}
+				}
+			}()
+			
+			// Sample half way through each window
+			time.Sleep(subscribeBatchInterval / 2)
+			for window, want := range tc.want {
+				if window > 0 {
+					time.Sleep(subscribeBatchInterval)
+				}
+				if sent := len(c.outgoingMessages); sent != want {
+					t.Fatalf("%d subscribe messages sent in window %d, want %d", sent, window, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconnectDelayGrowsExponentiallyUpToCap(t *testing.T) {
+	c := NewConnector("ws://unused")
+	c.SetReconnectPolicy(0, time.Second, 10*time.Second)
+	
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, base := range want {
+		attempt := i + 1
+		// Jitter takes up to 20% off, never adds
+		for run := 0; run < 20; run++ {
+			if delay := c.reconnectDelay(attempt); delay > base || delay < base-base/5 {
+				t.Fatalf("attempt %d waited %s, want within 20%% below %s", attempt, delay, base)
+			}
+		}
+	}
+	
+	// A cap below the interval keeps the delay at the interval
+	c.SetReconnectPolicy(0, 5*time.Second, time.Second)
+	if delay := c.reconnectDelay(4); delay > 5*time.Second || delay < 4*time.Second {
+		t.Fatalf("attempt 4 with a cap below the interval waited %s, want about 5s", delay)
+	}
+}
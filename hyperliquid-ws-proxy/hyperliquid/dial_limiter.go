@@ -0,0 +1,47 @@
+package hyperliquid
+
+import (
+	"sync"
+	"time"
+)
+
+// dialLimiter spaces dials to Hyperliquid evenly, so connectors dropped
+// together don't all redial at once
+type dialLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // minimum time between dials, 0 means unlimited
+	next     time.Time     // earliest time the next dial may start
+}
+
+// upstreamDials paces the dials of every Connector in the process
+var upstreamDials = &dialLimiter{}
+
+// SetDialRate sets how many dials per second all connectors of the process may
+// make together, 0 means unlimited
+func SetDialRate(perSecond float64) {
+	upstreamDials.mu.Lock()
+	defer upstreamDials.mu.Unlock()
+	
+	upstreamDials.interval = 0
+	if perSecond > 0 {
+		upstreamDials.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+}
+
+// wait blocks until the caller may dial, reserving the next slot for it
+func (l *dialLimiter) wait() {
+	l.mu.Lock()
+	if l.interval <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+	
+	time.Sleep(time.Until(slot))
+}
@@ -0,0 +1,68 @@
+package hyperliquid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectingConnectorsArePacedUnderDialRate(t *testing.T) {
+	var mu sync.Mutex
+	var dials []time.Time
+	upgrader := websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		dials = append(dials, time.Now())
+		mu.Unlock()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+	url := "ws" + strings.TrimPrefix(upstream.URL, "http")
+	
+	const interval = 50 * time.Millisecond
+	SetDialRate(float64(time.Second / interval))
+	defer SetDialRate(0)
+	
+	// Connectors dropped together all redial at once
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		c := NewConnector(url)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.connect(true); err != nil {
+				t.Error(err)
+			}
+		}()
+		defer c.Disconnect()
+	}
+	wg.Wait()
+	
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Slice(dials, func(i, j int) bool { return dials[i].Before(dials[j]) })
+	if len(dials) != 6 {
+		t.Fatalf("%d dials reached the upstream, want 6", len(dials))
+	}
+	for i := 1; i < len(dials); i++ {
+		// Allow for scheduling jitter between the limiter and the server
+		if gap := dials[i].Sub(dials[i-1]); gap < interval*8/10 {
+			t.Fatalf("dials %d and %d were %s apart, want at least %s", i-1, i, gap, interval)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"hyperliquid-ws-proxy/config"
@@ -21,11 +22,13 @@ const (
 func main() {
 	// Parse command line flags
 	var (
-		configPath = flag.String("config", "", "Path to configuration file")
-		logLevel   = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
-		logFormat  = flag.String("log-format", "text", "Log format (text, json)")
-		version    = flag.Bool("version", false, "Show version information")
-		help       = flag.Bool("help", false, "Show help information")
+		configPath      = flag.String("config", "", "Path to configuration file")
+		logLevel        = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+		logFormat       = flag.String("log-format", "text", "Log format (text, json)")
+		version         = flag.Bool("version", false, "Show version information")
+		help            = flag.Bool("help", false, "Show help information")
+		selfTest        = flag.Bool("self-test", false, "Start, verify a loopback client receives allMids data, then exit 0 on success or 1 on failure")
+		selfTestTimeout = flag.Duration("self-test-timeout", 30*time.Second, "Time allowed for the self-test to receive data")
 	)
 	flag.Parse()
 
@@ -86,11 +89,34 @@ func main() {
 	logrus.Info("Health endpoint: http://" + cfg.GetServerAddress() + "/health")
 	logrus.Info("Stats endpoint: http://" + cfg.GetServerAddress() + "/stats")
 
-	// Wait for interrupt signal
+	if *selfTest {
+		exitCode := 0
+		if err := runSelfTest(cfg, *selfTestTimeout); err != nil {
+			logrus.WithError(err).Error("Self-test failed")
+			exitCode = 1
+		} else {
+			logrus.Info("Self-test passed")
+		}
+		if err := srv.Stop(); err != nil {
+			logrus.WithError(err).Error("Error stopping server")
+		}
+		p.Stop()
+		os.Exit(exitCode)
+	}
+
+	// Wait for interrupt signal, reloading the asset map on SIGHUP
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	<-c
+	for sig := range c {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		logrus.Info("Received SIGHUP, reloading asset map")
+		if err := p.ReloadAssetMap(); err != nil {
+			logrus.WithError(err).Error("Failed to reload asset map")
+		}
+	}
 	logrus.Info("Received shutdown signal")
 
 	// Graceful shutdown
@@ -153,17 +179,24 @@ func showHelp() {
 	fmt.Println("        Log level (debug, info, warn, error) (default \"info\")")
 	fmt.Println("  -log-format string")
 	fmt.Println("        Log format (text, json) (default \"text\")")
+	fmt.Println("  -self-test")
+	fmt.Println("        Start, check a loopback client receives allMids data, then exit 0 or 1")
+	fmt.Println("  -self-test-timeout duration")
+	fmt.Println("        Time allowed for the self-test to receive data (default 30s)")
 	fmt.Println("  -version")
 	fmt.Println("        Show version information")
 	fmt.Println("  -help")
 	fmt.Println("        Show this help message")
 	fmt.Println()
 	fmt.Println("ENDPOINTS:")
-	fmt.Println("  WebSocket: ws://localhost:8080/ws")
+	fmt.Println("  WebSocket: ws://localhost:8080/ws  (?symbols=raw for ASSET_<n>/@<n> symbols instead of names)")
 	fmt.Println("  Health:    http://localhost:8080/health")
 	fmt.Println("  Stats:     http://localhost:8080/stats")
 	fmt.Println("  Info:      http://localhost:8080/info")
 	fmt.Println("  Assets:    http://localhost:8080/assets")
+	fmt.Println("  Subscriptions: http://localhost:8080/subscriptions")
+	fmt.Println("  Clients:   http://localhost:8080/clients")
+	fmt.Println("  Metrics:   http://localhost:8080/metrics")
 	fmt.Println()
 	fmt.Println("EXAMPLE USAGE:")
 	fmt.Println("  # Start with default configuration")
@@ -175,6 +208,9 @@ func showHelp() {
 	fmt.Println("  # Start with debug logging")
 	fmt.Println("  ./hyperliquid-ws-proxy -log-level debug")
 	fmt.Println()
+	fmt.Println("  # Deployment smoke test")
+	fmt.Println("  ./hyperliquid-ws-proxy -config config.yaml -self-test")
+	fmt.Println()
 	fmt.Println("SUPPORTED SUBSCRIPTIONS:")
 	fmt.Println("  - allMids: All mid prices")
 	fmt.Println("  - l2Book: Order book snapshots")
@@ -192,6 +228,8 @@ func showHelp() {
 	fmt.Println("  - activeAssetData: Active asset data")
 	fmt.Println("  - userTwapSliceFills: TWAP slice fills")
 	fmt.Println("  - userTwapHistory: TWAP history")
+	fmt.Println("  - midPx: Single coin mid price changes (local node only)")
+	fmt.Println("  - midsBbo: Mid, best bid and best ask of every coin (local node only)")
 	fmt.Println()
 	fmt.Println("FEATURES:")
 	fmt.Println("  ✓ No rate limits")
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+// perpPrices are the oracle and mark prices validators last set for a perp
+type perpPrices struct {
+	oraclePx float64
+	markPx   float64
+}
+
+// processGlobalPrices caches the prices of a validator SetGlobalAction. Its pxs
+// hold one [oraclePx, markPx] pair per perp, in universe order; null or
+// unparsable prices leave the cached value unchanged.
+func (r *LocalNodeReader) processGlobalPrices(pxs []json.RawMessage) {
+	type update struct {
+		symbol           string
+		oraclePx, markPx string
+	}
+	
+	updates := make([]update, 0, len(pxs))
+	for index, rawPair := range pxs {
+		var pair []json.RawMessage
+		if err := json.Unmarshal(rawPair, &pair); err != nil || len(pair) < 2 {
+			continue
+		}
+		oraclePx, err := decimalString(pair[0])
+		if err != nil {
+			continue
+		}
+		markPx, err := decimalString(pair[1])
+		if err != nil {
+			continue
+		}
+		updates = append(updates, update{symbol: r.getAssetSymbol(index), oraclePx: oraclePx, markPx: markPx})
+	}
+	
+	r.dataMu.Lock()
+	defer r.dataMu.Unlock()
+	
+	for _, u := range updates {
+		prices := r.perpPrices[u.symbol]
+		if px, err := strconv.ParseFloat(u.oraclePx, 64); err == nil {
+			prices.oraclePx = px
+		}
+		if px, err := strconv.ParseFloat(u.markPx, 64); err == nil {
+			prices.markPx = px
+		}
+		r.perpPrices[u.symbol] = prices
+	}
+}
+
+// GetAssetCtx returns the context of a coin: a *types.WsActiveSpotAssetCtx for
+// spot pairs, a *types.WsActiveAssetCtx otherwise, or nil when nothing is known
+// about it. Mark prices come from validators for perps and from the last fill
+// for spot, the mid from the order book. Funding, open interest, day volume and
+// previous day price of perps come from the info API when asset_ctx_interval_sec
+// is set, which also supplies the oracle price until validators set one.
+// Otherwise they stay 0, as does circulating supply.
+func (r *LocalNodeReader) GetAssetCtx(coin string) interface{} {
+	isSpot := r.isSpotCoin(coin)
+	var info infoPerpCtx
+	var fetched bool
+	if !isSpot && r.assetFetcher != nil {
+		info, fetched = r.assetFetcher.GetPerpInfoCtx(coin)
+	}
+	
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	var shared types.SharedAssetCtx
+	lastPx, traded := r.latestPrices[coin]
+	if traded {
+		shared.MarkPx, _ = strconv.ParseFloat(lastPx, 64)
+	}
+	if book, exists := r.orderBooks[coin]; exists {
+		bid, hasBid := book.best(true)
+		ask, hasAsk := book.best(false)
+		if hasBid && hasAsk {
+			mid := (bid + ask) / 2
+			shared.MidPx = &mid
+		}
+	}
+	
+	if isSpot {
+		if !traded {
+			return nil
+		}
+		return &types.WsActiveSpotAssetCtx{
+			Coin: coin,
+			Ctx:  types.SpotAssetCtx{SharedAssetCtx: shared},
+		}
+	}
+	
+	prices, priced := r.perpPrices[coin]
+	if !traded && !priced && !fetched {
+		return nil
+	}
+	if priced && prices.markPx > 0 {
+		shared.MarkPx = prices.markPx
+	}
+	
+	ctx := types.PerpsAssetCtx{
+		SharedAssetCtx: shared,
+		OraclePx:       prices.oraclePx,
+	}
+	if fetched {
+		ctx.Funding = info.Funding
+		ctx.OpenInterest = info.OpenInterest
+		ctx.DayNtlVlm = info.DayNtlVlm
+		ctx.PrevDayPx = info.PrevDayPx
+		if ctx.OraclePx == 0 {
+			ctx.OraclePx = info.OraclePx
+		}
+	}
+	return &types.WsActiveAssetCtx{
+		Coin: coin,
+		Ctx:  ctx,
+	}
+}
+
+// isSpotCoin reports whether a coin is a spot pair, per the AssetFetcher when it
+// knows the coin and by its PURR/USDC or @N form otherwise
+func (r *LocalNodeReader) isSpotCoin(coin string) bool {
+	if r.assetFetcher != nil {
+		if asset, exists := r.assetFetcher.GetAssetByName(coin); exists {
+			return asset.IsSpot
+		}
+	}
+	return strings.Contains(coin, "/") || strings.HasPrefix(coin, "@")
+}
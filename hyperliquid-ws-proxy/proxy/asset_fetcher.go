@@ -2,8 +2,11 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -11,11 +14,25 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// spotAssetIDOffset is added to a spot pair index to form its asset ID.
+// Block actions use the same convention: perpetuals are referenced by their
+// universe index and spot pairs by 10000 + pair index, so the two ranges
+// never overlap even when a perp and a spot pair share the same index.
+const spotAssetIDOffset = 10000
+
+// Metadata requests failing transiently, on network errors, 429 or 5xx, are
+// retried up to infoFetchAttempts times in all, waiting infoRetryBaseDelay
+// before the first retry and twice as long before each further one
+const (
+	infoFetchAttempts  = 3
+	infoRetryBaseDelay = time.Second
+)
+
 // AssetInfo represents metadata for an asset
 type AssetInfo struct {
 	Index       int    `json:"index"`
 	Name        string `json:"name"`
-	SzDecimals  int    `json:"szDecimals"`
+	SzDecimals  int    `json:"szDecimals"`            // Size decimals, of the base token for spot pairs
 	MaxLeverage int    `json:"maxLeverage,omitempty"`
 	IsSpot      bool   `json:"isSpot"`
 	TokenIndex  int    `json:"tokenIndex,omitempty"` // For spot assets
@@ -30,7 +47,14 @@ type AssetFetcher struct {
 	lastUpdated    time.Time
 	apiURL         string
 	updateInterval time.Duration
+	httpClient     *http.Client
+	initialTimeout time.Duration // bound on the initial fetch in Start, 0 waits for it
+	perpCtxs       map[string]infoPerpCtx // coin -> funding, open interest and more from the info API
+	ctxInterval    time.Duration          // how often perpCtxs are fetched, 0 disables it
 	stopChan       chan struct{}
+	ctx            context.Context // cancelled by Stop, aborting requests in flight
+	cancel         context.CancelFunc
+	onUpdate       func() // called after each successful metadata refresh
 }
 
 // HyperliquidMetaResponse represents the perpetuals metadata response
@@ -56,35 +80,108 @@ type HyperliquidSpotMetaResponse struct {
 	} `json:"universe"`
 }
 
-// NewAssetFetcher creates a new AssetFetcher
-func NewAssetFetcher() *AssetFetcher {
+// NewAssetFetcher creates a new AssetFetcher querying the given info endpoint
+func NewAssetFetcher(apiURL string) *AssetFetcher {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &AssetFetcher{
 		perpAssets:     make(map[int]*AssetInfo),
 		spotAssets:     make(map[int]*AssetInfo),
 		assetsByName:   make(map[string]*AssetInfo),
-		apiURL:         "https://api.hyperliquid.xyz/info",
+		apiURL:         apiURL,
 		updateInterval: 5 * time.Minute, // Update every 5 minutes
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		initialTimeout: 15 * time.Second,
 		stopChan:       make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 }
 
-// Start initializes the asset fetcher and starts periodic updates
+// SetTimeouts sets the timeout of each metadata request and the bound on the
+// initial fetch in Start, 0 waiting for it however long it takes
+func (af *AssetFetcher) SetTimeouts(requestTimeout, initialTimeout time.Duration) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	af.httpClient = &http.Client{Timeout: requestTimeout}
+	af.initialTimeout = initialTimeout
+}
+
+// SetOnUpdate sets a callback invoked after each successful metadata refresh
+func (af *AssetFetcher) SetOnUpdate(onUpdate func()) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	af.onUpdate = onUpdate
+}
+
+// Start initializes the asset fetcher and starts periodic updates. A slow API
+// doesn't hold up startup: when the initial fetch times out, the proxy starts
+// with fallback asset names and picks up the metadata once the fetch completes.
+// Other failures of the initial fetch are returned.
 func (af *AssetFetcher) Start() error {
-	logrus.Info("Starting asset fetcher - fetching initial asset metadata from Hyperliquid API")
+	logrus.WithField("url", af.apiURL).Info("Starting asset fetcher - fetching initial asset metadata from Hyperliquid API")
+	
+	af.mu.RLock()
+	initialTimeout := af.initialTimeout
+	af.mu.RUnlock()
 	
 	// Initial fetch
-	if err := af.fetchAssets(); err != nil {
-		return fmt.Errorf("failed to fetch initial assets: %w", err)
+	done := make(chan error, 1)
+	go func() {
+		done <- af.fetchAssets()
+	}()
+	
+	var timeout <-chan time.Time
+	if initialTimeout > 0 {
+		timer := time.NewTimer(initialTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	
+	select {
+	case err := <-done:
+		if err != nil && !isTimeout(err) {
+			return fmt.Errorf("failed to fetch initial assets: %w", err)
+		}
+		if err != nil {
+			logrus.WithError(err).Warn("Initial asset metadata fetch timed out, starting with fallback asset names")
+		}
+	case <-timeout:
+		logrus.WithField("timeout", initialTimeout).Warn("Initial asset metadata fetch is slow, starting with fallback asset names")
+		go af.completeInitialFetch(done)
 	}
 	
 	// Start periodic updates
 	go af.periodicUpdate()
 	
+	af.mu.RLock()
+	ctxInterval := af.ctxInterval
+	af.mu.RUnlock()
+	if ctxInterval > 0 {
+		go af.runAssetCtxUpdates(ctxInterval)
+	}
+	
 	return nil
 }
 
+// completeInitialFetch reports the outcome of an initial fetch Start stopped
+// waiting for, notifying the update callback once the metadata arrived
+func (af *AssetFetcher) completeInitialFetch(done <-chan error) {
+	if err := <-done; err != nil {
+		logrus.WithError(err).Error("Initial asset metadata fetch failed, retrying at the next periodic update")
+		return
+	}
+	af.notifyUpdate()
+}
+
+// isTimeout reports whether a fetch failed because a request timed out
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // Stop stops the periodic updates
 func (af *AssetFetcher) Stop() {
+	af.cancel()
 	close(af.stopChan)
 }
 
@@ -101,6 +198,7 @@ func (af *AssetFetcher) periodicUpdate() {
 				logrus.WithError(err).Error("Failed to update assets during periodic fetch")
 			} else {
 				logrus.Debug("Periodic asset metadata update completed successfully")
+				af.notifyUpdate()
 			}
 		case <-af.stopChan:
 			logrus.Info("Asset fetcher stopped")
@@ -109,59 +207,129 @@ func (af *AssetFetcher) periodicUpdate() {
 	}
 }
 
-// fetchAssets fetches both perpetuals and spot assets from Hyperliquid API
-func (af *AssetFetcher) fetchAssets() error {
-	af.mu.Lock()
-	defer af.mu.Unlock()
+// notifyUpdate invokes the update callback, if any
+func (af *AssetFetcher) notifyUpdate() {
+	af.mu.RLock()
+	onUpdate := af.onUpdate
+	af.mu.RUnlock()
 	
+	if onUpdate != nil {
+		onUpdate()
+	}
+}
+
+// fetchAssets fetches both perpetuals and spot assets from Hyperliquid API.
+// Requests are made without holding mu, which is only taken to swap in the new
+// metadata, so lookups never wait on the network. On failure the last known
+// metadata stays in place.
+func (af *AssetFetcher) fetchAssets() error {
 	// Fetch perpetuals
-	if err := af.fetchPerpetuals(); err != nil {
+	perpAssets, err := af.fetchPerpetuals()
+	if err != nil {
 		return fmt.Errorf("failed to fetch perpetuals: %w", err)
 	}
 	
 	// Fetch spot assets
-	if err := af.fetchSpotAssets(); err != nil {
+	spotAssets, err := af.fetchSpotAssets()
+	if err != nil {
 		return fmt.Errorf("failed to fetch spot assets: %w", err)
 	}
 	
+	assetsByName := make(map[string]*AssetInfo, len(perpAssets)+len(spotAssets))
+	for _, asset := range perpAssets {
+		assetsByName[asset.Name] = asset
+	}
+	for _, asset := range spotAssets {
+		assetsByName[asset.Name] = asset
+	}
+	
+	af.mu.Lock()
+	af.perpAssets = perpAssets
+	af.spotAssets = spotAssets
+	af.assetsByName = assetsByName
 	af.lastUpdated = time.Now()
+	af.mu.Unlock()
 	
 	logrus.WithFields(logrus.Fields{
-		"perp_assets": len(af.perpAssets),
-		"spot_assets": len(af.spotAssets),
-		"total_assets": len(af.assetsByName),
+		"perp_assets": len(perpAssets),
+		"spot_assets": len(spotAssets),
+		"total_assets": len(assetsByName),
 	}).Info("Successfully updated asset metadata from Hyperliquid API")
 	
 	return nil
 }
 
-// fetchPerpetuals fetches perpetual assets metadata
-func (af *AssetFetcher) fetchPerpetuals() error {
-	reqBody := map[string]interface{}{
-		"type": "meta",
+// postInfo posts a request to the info endpoint and decodes the response into
+// out, retrying transient failures with exponential backoff
+func (af *AssetFetcher) postInfo(requestType string, out interface{}) error {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"type": requestType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 	
-	bodyBytes, err := json.Marshal(reqBody)
+	delay := infoRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		retryable, err := af.postInfoOnce(bodyBytes, out)
+		if err == nil || !retryable || attempt >= infoFetchAttempts {
+			return err
+		}
+		
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"type":     requestType,
+			"attempt":  attempt,
+			"retry_in": delay,
+		}).Warn("Asset metadata request failed, retrying")
+		
+		select {
+		case <-time.After(delay):
+		case <-af.ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// postInfoOnce makes a single info request, reporting whether a failure is
+// transient and worth retrying
+func (af *AssetFetcher) postInfoOnce(bodyBytes []byte, out interface{}) (bool, error) {
+	af.mu.RLock()
+	httpClient := af.httpClient
+	af.mu.RUnlock()
+	
+	req, err := http.NewRequestWithContext(af.ctx, http.MethodPost, af.apiURL, bytes.NewReader(bodyBytes))
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 	
-	resp, err := http.Post(af.apiURL, "application/json", bytes.NewBuffer(bodyBytes))
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return af.ctx.Err() == nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
 	}
 	
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return false, nil
+}
+
+// fetchPerpetuals fetches perpetual assets metadata, keyed by universe index
+func (af *AssetFetcher) fetchPerpetuals() (map[int]*AssetInfo, error) {
 	var metaResp HyperliquidMetaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&metaResp); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if err := af.postInfo("meta", &metaResp); err != nil {
+		return nil, err
 	}
 	
 	// Process perpetuals
+	perpAssets := make(map[int]*AssetInfo, len(metaResp.Universe))
 	perpAssetNames := make([]string, 0)
 	for i, asset := range metaResp.Universe {
 		assetInfo := &AssetInfo{
@@ -172,8 +340,7 @@ func (af *AssetFetcher) fetchPerpetuals() error {
 			IsSpot:      false,
 		}
 		
-		af.perpAssets[i] = assetInfo
-		af.assetsByName[asset.Name] = assetInfo
+		perpAssets[i] = assetInfo
 		perpAssetNames = append(perpAssetNames, asset.Name)
 	}
 	
@@ -181,42 +348,26 @@ func (af *AssetFetcher) fetchPerpetuals() error {
 		"count": len(metaResp.Universe),
 		"assets": perpAssetNames,
 	}).Debug("Fetched perpetual assets")
-	return nil
+	return perpAssets, nil
 }
 
-// fetchSpotAssets fetches spot assets metadata
-func (af *AssetFetcher) fetchSpotAssets() error {
-	reqBody := map[string]interface{}{
-		"type": "spotMeta",
-	}
-	
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-	
-	resp, err := http.Post(af.apiURL, "application/json", bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
-	}
-	
+// fetchSpotAssets fetches spot assets metadata, keyed by asset ID
+func (af *AssetFetcher) fetchSpotAssets() (map[int]*AssetInfo, error) {
 	var spotResp HyperliquidSpotMetaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&spotResp); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if err := af.postInfo("spotMeta", &spotResp); err != nil {
+		return nil, err
 	}
 	
-	// Create token lookup
+	// Create token lookups
 	tokenMap := make(map[int]string)
+	tokenSzDecimals := make(map[int]int)
 	for _, token := range spotResp.Tokens {
 		tokenMap[token.Index] = token.Name
+		tokenSzDecimals[token.Index] = token.SzDecimals
 	}
 	
 	// Process spot pairs
+	spotAssets := make(map[int]*AssetInfo, len(spotResp.Universe))
 	spotAssetNames := make([]string, 0)
 	for _, pair := range spotResp.Universe {
 		assetName := pair.Name
@@ -230,16 +381,21 @@ func (af *AssetFetcher) fetchSpotAssets() error {
 			}
 		}
 		
+		// Sizes are quoted in the base token, the first of the pair
+		szDecimals := 0
+		if len(pair.Tokens) > 0 {
+			szDecimals = tokenSzDecimals[pair.Tokens[0]]
+		}
+		
 		assetInfo := &AssetInfo{
-			Index:      10000 + pair.Index, // Spot assets use 10000 + index
+			Index:      spotAssetIDOffset + pair.Index, // Spot assets use 10000 + index
 			Name:       assetName,
-			SzDecimals: 0, // Will be filled from token info if needed
+			SzDecimals: szDecimals,
 			IsSpot:     true,
 			TokenIndex: pair.Index,
 		}
 		
-		af.spotAssets[10000+pair.Index] = assetInfo
-		af.assetsByName[assetName] = assetInfo
+		spotAssets[spotAssetIDOffset+pair.Index] = assetInfo
 		spotAssetNames = append(spotAssetNames, fmt.Sprintf("%s(%d)", assetName, pair.Index))
 	}
 	
@@ -254,7 +410,7 @@ func (af *AssetFetcher) fetchSpotAssets() error {
 		"assets": assetsToShow,
 		"total": len(spotAssetNames),
 	}).Debug("Fetched spot assets")
-	return nil
+	return spotAssets, nil
 }
 
 // GetAssetByID returns asset info by ID (index)
@@ -275,7 +431,32 @@ func (af *AssetFetcher) GetAssetByID(id int) (*AssetInfo, bool) {
 	return nil, false
 }
 
+// GetPerpAsset returns perpetual asset info by universe index
+func (af *AssetFetcher) GetPerpAsset(index int) (*AssetInfo, bool) {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	
+	asset, exists := af.perpAssets[index]
+	return asset, exists
+}
+
+// GetSpotAsset returns spot asset info by spot pair index
+func (af *AssetFetcher) GetSpotAsset(pairIndex int) (*AssetInfo, bool) {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	
+	asset, exists := af.spotAssets[spotAssetIDOffset+pairIndex]
+	return asset, exists
+}
 
+// GetAssetByName returns asset info by name
+func (af *AssetFetcher) GetAssetByName(name string) (*AssetInfo, bool) {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	
+	asset, exists := af.assetsByName[name]
+	return asset, exists
+}
 
 // GetAllAssetNames returns all asset names
 func (af *AssetFetcher) GetAllAssetNames() []string {
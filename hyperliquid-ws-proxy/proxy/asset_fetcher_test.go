@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// startInfoServer serves an info endpoint answering meta and spotMeta
+// requests with the given bodies, and returns its URL
+func startInfoServer(t *testing.T, meta, spotMeta string) string {
+	t.Helper()
+	
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Type {
+		case "meta":
+			w.Write([]byte(meta))
+		case "spotMeta":
+			w.Write([]byte(spotMeta))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(info.Close)
+	return info.URL
+}
+
+// newStubFetcher returns an asset fetcher loaded from startInfoServer
+func newStubFetcher(t *testing.T, meta, spotMeta string) *AssetFetcher {
+	t.Helper()
+	
+	fetcher := NewAssetFetcher(startInfoServer(t, meta, spotMeta))
+	if err := fetcher.fetchAssets(); err != nil {
+		t.Fatal(err)
+	}
+	return fetcher
+}
+
+func TestPerpAndSpotSharingAnIndexBothResolve(t *testing.T) {
+	fetcher := newStubFetcher(t,
+		`{"universe":[{"name":"BTC","szDecimals":5},{"name":"ETH","szDecimals":4},{"name":"ATOM","szDecimals":2},{"name":"MATIC","szDecimals":1},{"name":"DYDX","szDecimals":1},{"name":"SOL","szDecimals":2}]}`,
+		`{"tokens":[{"name":"USDC","index":0,"szDecimals":8},{"name":"PURR","index":1,"szDecimals":0},{"name":"HFUN","index":7,"szDecimals":2}],"universe":[{"name":"PURR/USDC","tokens":[1,0],"index":0},{"name":"@5","tokens":[7,0],"index":5}]}`)
+	
+	perp, ok := fetcher.GetPerpAsset(5)
+	if !ok || perp.Name != "SOL" || perp.IsSpot {
+		t.Fatalf("perp 5 = %+v, want SOL", perp)
+	}
+	spot, ok := fetcher.GetSpotAsset(5)
+	if !ok || spot.Name != "@5" || !spot.IsSpot || spot.TokenIndex != 5 {
+		t.Fatalf("spot pair 5 = %+v, want @5", spot)
+	}
+	
+	// Block asset IDs carry the market: spot pairs are offset by 10000
+	r := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{})
+	if got := r.getAssetSymbol(5); got != "SOL" {
+		t.Fatalf("asset 5 resolves to %q, want the SOL perp", got)
+	}
+	if got := r.getAssetSymbol(spotAssetIDOffset + 5); got != "@5" {
+		t.Fatalf("asset %d resolves to %q, want spot pair @5", spotAssetIDOffset+5, got)
+	}
+	if got := r.getAssetSymbol(spotAssetIDOffset); got != "PURR/USDC" {
+		t.Fatalf("asset %d resolves to %q, want PURR/USDC", spotAssetIDOffset, got)
+	}
+}
+
+// startHangingInfoServer serves an info endpoint that holds every request until
+// release is closed, then answers like startInfoServer with BTC listed
+func startHangingInfoServer(t *testing.T) (url string, release chan struct{}) {
+	t.Helper()
+	
+	release = make(chan struct{})
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Type == "meta" {
+			w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5}]}`))
+			return
+		}
+		w.Write([]byte(`{"universe":[],"tokens":[]}`))
+	}))
+	t.Cleanup(info.Close)
+	return info.URL, release
+}
+
+func TestStartProceedsPastAHangingInfoAPI(t *testing.T) {
+	url, release := startHangingInfoServer(t)
+	fetcher := NewAssetFetcher(url)
+	fetcher.SetTimeouts(5*time.Second, 200*time.Millisecond)
+	updated := make(chan struct{}, 1)
+	fetcher.SetOnUpdate(func() { updated <- struct{}{} })
+	
+	begin := time.Now()
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("Start failed on a slow API: %v", err)
+	}
+	defer fetcher.Stop()
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Fatalf("Start returned after %s, want about the 200ms initial timeout", elapsed)
+	}
+	if _, exists := fetcher.GetAssetByName("BTC"); exists {
+		t.Fatal("metadata loaded although the API never answered")
+	}
+	
+	// The metadata is picked up once the API answers
+	close(release)
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update callback not called after the slow fetch completed")
+	}
+	if _, exists := fetcher.GetAssetByName("BTC"); !exists {
+		t.Fatal("BTC unknown after the slow fetch completed")
+	}
+}
+
+func TestStartProceedsWhenTheInitialRequestTimesOut(t *testing.T) {
+	url, release := startHangingInfoServer(t)
+	defer close(release)
+	fetcher := NewAssetFetcher(url)
+	fetcher.SetTimeouts(200*time.Millisecond, 0)
+	
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("Start failed on a request timeout: %v", err)
+	}
+	fetcher.Stop()
+}
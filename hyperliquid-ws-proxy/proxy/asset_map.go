@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AssetMap is a static asset ID -> symbol mapping loaded from a JSON file of
+// the form {"0": "BTC", "10107": "HYPE/USDC"}, keyed by the asset IDs used in
+// block actions. It keeps labels correct while the info API is unreachable.
+type AssetMap struct {
+	mu      sync.RWMutex
+	path    string
+	symbols map[int]string
+}
+
+// NewAssetMap creates an asset map backed by the given file
+func NewAssetMap(path string) *AssetMap {
+	return &AssetMap{
+		path:    path,
+		symbols: make(map[int]string),
+	}
+}
+
+// Load (re)reads the mapping file, keeping the previous mapping on error
+func (am *AssetMap) Load() error {
+	data, err := os.ReadFile(am.path)
+	if err != nil {
+		return fmt.Errorf("failed to read asset map file: %w", err)
+	}
+	
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to decode asset map file: %w", err)
+	}
+	
+	symbols := make(map[int]string, len(raw))
+	for key, symbol := range raw {
+		assetID, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid asset id %q in asset map file", key)
+		}
+		symbols[assetID] = symbol
+	}
+	
+	am.mu.Lock()
+	am.symbols = symbols
+	am.mu.Unlock()
+	
+	logrus.WithFields(logrus.Fields{
+		"path":    am.path,
+		"entries": len(symbols),
+	}).Info("Loaded asset map file")
+	return nil
+}
+
+// Lookup returns the symbol mapped to an asset ID
+func (am *AssetMap) Lookup(assetID int) (string, bool) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	
+	symbol, exists := am.symbols[assetID]
+	return symbol, exists
+}
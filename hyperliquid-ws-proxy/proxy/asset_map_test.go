@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeAssetMap writes an asset map file and returns its path
+func writeAssetMap(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAssetMapFillsAssetsTheFetcherLacks(t *testing.T) {
+	fetcher := NewAssetFetcher("")
+	fetcher.perpAssets[0] = &AssetInfo{Index: 0, Name: "BTC"}
+	path := writeAssetMap(t, filepath.Join(t.TempDir(), "assets.json"), `{"0": "XBT", "7": "NEW", "10042": "PAIR/USDC"}`)
+	r := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{AssetMapFile: path})
+	
+	cases := []struct {
+		assetID int
+		want    string
+	}{
+		{0, "BTC"}, // the fetcher wins unless the map is an override
+		{7, "NEW"},
+		{10042, "PAIR/USDC"},
+		{8, "ASSET_8"},
+	}
+	for _, tc := range cases {
+		if got := r.getAssetSymbol(tc.assetID); got != tc.want {
+			t.Errorf("getAssetSymbol(%d) = %q, want %q", tc.assetID, got, tc.want)
+		}
+	}
+	
+	override := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{AssetMapFile: path, AssetMapOverride: true})
+	if got := override.getAssetSymbol(0); got != "XBT" {
+		t.Errorf("getAssetSymbol(0) with the map as override = %q, want XBT", got)
+	}
+}
+
+func TestAssetMapReload(t *testing.T) {
+	path := writeAssetMap(t, filepath.Join(t.TempDir(), "assets.json"), `{"7": "NEW"}`)
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{AssetMapFile: path})
+	
+	writeAssetMap(t, path, `{"7": "RENAMED"}`)
+	if err := r.ReloadAssetMap(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.getAssetSymbol(7); got != "RENAMED" {
+		t.Fatalf("getAssetSymbol(7) after reload = %q, want RENAMED", got)
+	}
+	
+	// A broken file keeps the previous mapping
+	writeAssetMap(t, path, `{"seven": "BROKEN"}`)
+	if err := r.ReloadAssetMap(); err == nil {
+		t.Fatal("reload accepted a non-numeric asset id")
+	}
+	if got := r.getAssetSymbol(7); got != "RENAMED" {
+		t.Fatalf("getAssetSymbol(7) after a failed reload = %q, want RENAMED kept", got)
+	}
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/client"
+)
+
+// backpressureAdvisory is the notification sent to a client falling behind
+const backpressureAdvisory = "falling behind: the send buffer is near capacity, reduce subscriptions or enable compression to avoid being disconnected"
+
+// clientBacklog is the falling-behind state of a client
+type clientBacklog struct {
+	since   time.Time // when the buffer was first seen near capacity
+	advised bool      // the advisory was sent since then
+}
+
+// runBackpressureAdvisory periodically checks client buffers, notifying clients
+// whose buffer stays near capacity for backpressure_advisory_sec
+func (p *Proxy) runBackpressureAdvisory() {
+	sustained := time.Duration(p.config.Proxy.BackpressureAdvisorySec) * time.Second
+	threshold := float64(p.config.Proxy.BackpressureAdvisoryFillPct) / 100
+	backlogs := make(map[*client.Client]*clientBacklog)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	
+	for now := range ticker.C {
+		p.adviseSlowClients(backlogs, threshold, sustained, now)
+	}
+}
+
+// adviseSlowClients updates the falling-behind state of every client and sends
+// the advisory, once per episode, to those near capacity for sustained. A client
+// is back to normal as soon as its buffer drops below threshold.
+func (p *Proxy) adviseSlowClients(backlogs map[*client.Client]*clientBacklog, threshold float64, sustained time.Duration, now time.Time) {
+	connected := make(map[*client.Client]bool)
+	for _, c := range p.hub.GetClients() {
+		connected[c] = true
+		
+		fill := c.QueueFill()
+		if fill < threshold || c.Closing() {
+			delete(backlogs, c)
+			continue
+		}
+		
+		backlog, exists := backlogs[c]
+		if !exists {
+			backlog = &clientBacklog{since: now}
+			backlogs[c] = backlog
+		}
+		if backlog.advised || now.Sub(backlog.since) < sustained {
+			continue
+		}
+		
+		p.sendNotificationToClient(c, backpressureAdvisory)
+		backlog.advised = true
+		logrus.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"fill":      fill,
+			"behind":    now.Sub(backlog.since).Round(time.Second),
+		}).Info("Advised slow client it is falling behind")
+	}
+	
+	// Forget clients that went away
+	for c := range backlogs {
+		if !connected[c] {
+			delete(backlogs, c)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hyperliquid-ws-proxy/client"
+)
+
+// registerClient registers a client without a connection with p's hub and
+// waits for the hub to list it
+func registerClient(t *testing.T, p *Proxy) *client.Client {
+	t.Helper()
+	
+	c := client.NewClient(nil, p.hub)
+	registered := p.hub.GetClientCount() + 1
+	p.hub.Register <- c
+	deadline := time.Now().Add(2 * time.Second)
+	for p.hub.GetClientCount() < registered {
+		if time.Now().After(deadline) {
+			t.Fatal("client never registered with the hub")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return c
+}
+
+// fillTo queues filler frames until c's buffer is at least fill full
+func fillTo(c *client.Client, fill float64) {
+	for c.QueueFill() < fill {
+		c.Send <- []byte(`{"channel":"trades","data":[]}`)
+	}
+}
+
+// advisories empties c's buffer and returns how many backpressure advisories it held
+func advisories(c *client.Client) int {
+	count := 0
+	for _, frame := range framesOn(c, "notification") {
+		if strings.Contains(frame, backpressureAdvisory) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAdvisorySentWhenBufferStaysNearCapacity(t *testing.T) {
+	p := newTestProxy(t, nil)
+	slow := registerClient(t, p)
+	keepingUp := registerClient(t, p)
+	fillTo(slow, 0.9)
+	fillTo(keepingUp, 0.5)
+	
+	backlogs := make(map[*client.Client]*clientBacklog)
+	const sustained = 10 * time.Second
+	start := time.Now()
+	queued := len(slow.Send)
+	
+	// Near capacity, but not for long enough yet
+	p.adviseSlowClients(backlogs, 0.8, sustained, start)
+	p.adviseSlowClients(backlogs, 0.8, sustained, start.Add(sustained-time.Second))
+	if len(slow.Send) != queued {
+		t.Fatal("advisory sent before the buffer stayed near capacity for the sustained period")
+	}
+	
+	// Once the period is over the advisory goes out, once
+	p.adviseSlowClients(backlogs, 0.8, sustained, start.Add(sustained))
+	p.adviseSlowClients(backlogs, 0.8, sustained, start.Add(2*sustained))
+	if got := advisories(slow); got != 1 {
+		t.Fatalf("%d advisories sent to the slow client, want 1", got)
+	}
+	if got := advisories(keepingUp); got != 0 {
+		t.Fatalf("%d advisories sent to a client below the threshold, want 0", got)
+	}
+	
+	// Draining the buffer ends the episode, so the client is advised again
+	// only after falling behind for another sustained period
+	p.adviseSlowClients(backlogs, 0.8, sustained, start.Add(3*sustained))
+	if _, tracked := backlogs[slow]; tracked {
+		t.Fatal("client still tracked as falling behind after its buffer drained")
+	}
+	fillTo(slow, 0.9)
+	restart := start.Add(4 * sustained)
+	p.adviseSlowClients(backlogs, 0.8, sustained, restart)
+	p.adviseSlowClients(backlogs, 0.8, sustained, restart.Add(sustained))
+	if got := advisories(slow); got != 1 {
+		t.Fatalf("%d advisories after falling behind again, want 1", got)
+	}
+}
+
+func TestAdvisoryStateForgottenOnDisconnect(t *testing.T) {
+	p := newTestProxy(t, nil)
+	c := registerClient(t, p)
+	fillTo(c, 0.9)
+	
+	backlogs := make(map[*client.Client]*clientBacklog)
+	p.adviseSlowClients(backlogs, 0.8, time.Second, time.Now())
+	if _, tracked := backlogs[c]; !tracked {
+		t.Fatal("client near capacity not tracked")
+	}
+	
+	p.hub.Unregister <- c
+	deadline := time.Now().Add(2 * time.Second)
+	for p.hub.GetClientCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client never unregistered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.adviseSlowClients(backlogs, 0.8, time.Second, time.Now())
+	if len(backlogs) != 0 {
+		t.Fatalf("state of %d disconnected clients kept", len(backlogs))
+	}
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/types"
+)
+
+// handleBatchSubscribe subscribes a client to every coin of a request listing
+// several in coins, answering with a single subscriptionResponse carrying the
+// id of each coin's subscription in the order of its coins. The batch is refused as a whole if it would
+// take the client past its subscription limit.
+func (p *Proxy) handleBatchSubscribe(c *client.Client, sub *types.SubscriptionRequest) {
+	if !types.TakesCoin(sub.Type) {
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, sub.Type+" subscription does not take coins", false))
+		return
+	}
+	
+	subs := sub.Expand()
+	
+	// Coins the client already holds don't take another slot
+	maxSubs := p.config.Proxy.MaxSubscriptionsPerClient
+	if maxSubs > 0 {
+		held := c.GetSubscriptions()
+		count := len(held)
+		for _, single := range subs {
+			if _, exists := held[single.Key()]; !exists {
+				count++
+			}
+		}
+		if count > maxSubs {
+			p.sendErrorToClient(c, subscriptionLimitError(len(held), maxSubs))
+			return
+		}
+	}
+	
+	ids := make([]int64, 0, len(subs))
+	added := make([]*addedSubscriber, 0, len(subs))
+	for _, single := range subs {
+		key := single.Key()
+		id, _, _ := c.TryAddSubscription(key, single, 0) // the limit was checked for the whole batch
+		ids = append(ids, id)
+		added = append(added, p.addSubscriber(c, key, single, 0)) // answered below for the whole batch
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"client_id": c.ID,
+		"type":      sub.Type,
+		"coins":     len(subs),
+	}).Debug("Handled batch subscription")
+	
+	response := types.WSMessage{
+		Channel: "subscriptionResponse",
+		Data:    json.RawMessage(fmt.Sprintf(`{"method":"subscribe","subscription":%s,"subscriptionIds":%s}`, p.toJSON(batchRequest(sub, subs)), p.toJSON(ids))),
+	}
+	c.SendMessage(response)
+	
+	for _, subscriber := range added {
+		p.startSubscription(c, subscriber)
+	}
+}
+
+// handleBatchUnsubscribe unsubscribes a client from every coin of a request
+// listing several in coins, answering with a single subscriptionResponse. Ids
+// are 0 for coins the client wasn't subscribed to.
+func (p *Proxy) handleBatchUnsubscribe(c *client.Client, sub *types.SubscriptionRequest) {
+	subs := sub.Expand()
+	
+	ids := make([]int64, 0, len(subs))
+	for _, single := range subs {
+		ids = append(ids, p.removeSubscriber(c, single.Key()))
+	}
+	
+	response := types.WSMessage{
+		Channel: "subscriptionResponse",
+		Data:    json.RawMessage(fmt.Sprintf(`{"method":"unsubscribe","subscription":%s,"subscriptionIds":%s}`, p.toJSON(batchRequest(sub, subs)), p.toJSON(ids))),
+	}
+	c.SendMessage(response)
+}
+
+// batchRequest returns the batch request echoed in responses, listing the
+// expanded coins in coins so they line up with the subscription ids
+func batchRequest(sub *types.SubscriptionRequest, subs []*types.SubscriptionRequest) *types.SubscriptionRequest {
+	echoed := *sub
+	echoed.Coin = ""
+	echoed.Coins = make([]string, 0, len(subs))
+	for _, single := range subs {
+		echoed.Coins = append(echoed.Coins, single.Coin)
+	}
+	return &echoed
+}
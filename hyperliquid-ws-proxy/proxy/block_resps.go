@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// BlockResponses holds the execution results of a block, one entry per signed
+// action bundle in the same order as the block's bundles
+type BlockResponses struct {
+	Full []json.RawMessage `json:"Full"`
+}
+
+// ActionResponse is the execution result of a single signed action
+type ActionResponse struct {
+	User string `json:"user"`
+	Res  struct {
+		Status   string `json:"status"` // "ok" or "err"
+		Response struct {
+			Type string `json:"type"`
+			Data struct {
+				Statuses []OrderStatus `json:"statuses"`
+			} `json:"data"`
+		} `json:"response"`
+	} `json:"res"`
+}
+
+// OrderStatus is the outcome of one order in an order action
+type OrderStatus struct {
+	Filled *struct {
+		TotalSz string `json:"totalSz"`
+		AvgPx   string `json:"avgPx"`
+		Oid     int64  `json:"oid"`
+	} `json:"filled,omitempty"`
+	Resting *struct {
+		Oid int64 `json:"oid"`
+	} `json:"resting,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// decodeBlockResponses decodes a block's resps into per-bundle action responses,
+// aligned by index with the block's signed action bundles. Each entry is either
+// a [hash, responses] array or a bare responses array.
+func decodeBlockResponses(raw json.RawMessage) ([][]ActionResponse, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+	
+	var resps BlockResponses
+	if err := json.Unmarshal(trimmed, &resps); err != nil {
+		return nil, fmt.Errorf("failed to decode resps: %w", err)
+	}
+	
+	bundles := make([][]ActionResponse, 0, len(resps.Full))
+	for i, rawEntry := range resps.Full {
+		var responses []ActionResponse
+		if err := json.Unmarshal(rawEntry, &responses); err != nil {
+			// Fall back to the [hash, responses] form
+			var parts []json.RawMessage
+			if err := json.Unmarshal(rawEntry, &parts); err != nil || len(parts) < 2 {
+				return nil, fmt.Errorf("unsupported resps entry %d", i)
+			}
+			if err := json.Unmarshal(parts[1], &responses); err != nil {
+				return nil, fmt.Errorf("failed to decode resps entry %d: %w", i, err)
+			}
+		}
+		bundles = append(bundles, responses)
+	}
+	return bundles, nil
+}
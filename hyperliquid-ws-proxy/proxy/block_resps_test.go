@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeBlockResponses(t *testing.T) {
+	const filled = `{"user":"0xtaker","res":{"status":"ok","response":{"type":"order","data":{"statuses":[{"filled":{"totalSz":"1","avgPx":"100","oid":7}}]}}}}`
+	const rejected = `{"user":"0xother","res":{"status":"err","response":{"type":"error"}}}`
+	
+	for _, raw := range []string{"", "null", " "} {
+		bundles, err := decodeBlockResponses(json.RawMessage(raw))
+		if err != nil || bundles != nil {
+			t.Fatalf("decodeBlockResponses(%q) = %v, %v, want nothing", raw, bundles, err)
+		}
+	}
+	
+	// Entries come as [hash, responses] or as bare responses, aligned with the bundles
+	raw := `{"Full":[["0xbundle",[` + filled + `]],[` + rejected + `]]}`
+	bundles, err := decodeBlockResponses(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 2 || len(bundles[0]) != 1 || len(bundles[1]) != 1 {
+		t.Fatalf("decoded %d bundles, want one response in each of 2", len(bundles))
+	}
+	first := bundles[0][0]
+	if first.User != "0xtaker" || first.Res.Status != "ok" {
+		t.Fatalf("first response = %+v, want ok from 0xtaker", first)
+	}
+	statuses := first.Res.Response.Data.Statuses
+	if len(statuses) != 1 || statuses[0].Filled == nil || statuses[0].Filled.AvgPx != "100" || statuses[0].Filled.Oid != 7 {
+		t.Fatalf("first response statuses = %+v, want a fill at 100", statuses)
+	}
+	if second := bundles[1][0]; second.User != "0xother" || second.Res.Status != "err" {
+		t.Fatalf("second response = %+v, want err from 0xother", second)
+	}
+	
+	for _, raw := range []string{`[]`, `{"Full":["0xbundle"]}`, `{"Full":[["0xbundle"]]}`, `{"Full":[["0xbundle",{}]]}`} {
+		if _, err := decodeBlockResponses(json.RawMessage(raw)); err == nil {
+			t.Fatalf("decodeBlockResponses(%s) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestOrderStatuses(t *testing.T) {
+	if statuses, ok := orderStatuses(nil); !ok || statuses != nil {
+		t.Fatalf("orderStatuses(nil) = %v, %v, want no statuses and ok", statuses, ok)
+	}
+	
+	var response ActionResponse
+	response.Res.Status = "err"
+	if _, ok := orderStatuses(&response); ok {
+		t.Fatal("orderStatuses of a rejected action reported ok")
+	}
+	
+	response.Res.Status = "ok"
+	response.Res.Response.Data.Statuses = decodeStatuses(t, `[{"resting":{"oid":1}},{"error":"Insufficient margin"}]`)
+	statuses, ok := orderStatuses(&response)
+	if !ok || len(statuses) != 2 || statuses[0].Resting == nil || statuses[1].Error == "" {
+		t.Fatalf("orderStatuses = %+v, %v, want the resting and rejected statuses", statuses, ok)
+	}
+}
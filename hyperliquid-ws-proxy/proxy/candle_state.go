@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/types"
+)
+
+// persistedCandle is an open candle saved across restarts
+type persistedCandle struct {
+	Candle        types.Candle `json:"candle"`
+	LastTradeTime int64        `json:"lastTradeTime"` // time of the last trade folded in, unix millis
+}
+
+// SaveCandleState writes the open candles to the candle state file, if configured,
+// so a restart mid-bucket continues them instead of starting over with a wrong open
+func (r *LocalNodeReader) SaveCandleState() error {
+	path := r.options.CandleStateFile
+	if path == "" {
+		return nil
+	}
+	
+	r.dataMu.RLock()
+	state := make([]persistedCandle, 0, len(r.openCandles))
+	for key, candle := range r.openCandles {
+		if r.partialCandles[key] {
+			continue
+		}
+		state = append(state, persistedCandle{
+			Candle:        *candle,
+			LastTradeTime: r.candleLastTrade[key],
+		})
+	}
+	r.dataMu.RUnlock()
+	
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode candle state: %w", err)
+	}
+	
+	// Write then rename so a crash mid-write never leaves a truncated file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write candle state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write candle state: %w", err)
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"path":    path,
+		"candles": len(state),
+	}).Info("Saved open candles")
+	return nil
+}
+
+// loadCandleState restores the open candles saved by SaveCandleState. Trades
+// replayed up to each candle's last trade time are not folded in again.
+func (r *LocalNodeReader) loadCandleState() error {
+	data, err := os.ReadFile(r.options.CandleStateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read candle state: %w", err)
+	}
+	
+	var state []persistedCandle
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode candle state: %w", err)
+	}
+	
+	r.dataMu.Lock()
+	defer r.dataMu.Unlock()
+	
+	for i := range state {
+		candle := state[i].Candle
+		if _, exists := candleIntervals[candle.I]; !exists {
+			continue
+		}
+		key := candleKey{coin: candle.S, interval: candle.I}
+		r.openCandles[key] = &candle
+		r.candleLastTrade[key] = state[i].LastTradeTime
+		r.restoredCandles[key] = true
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"path":    r.options.CandleStateFile,
+		"candles": len(r.openCandles),
+	}).Info("Restored open candles")
+	return nil
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestCandleContinuesAcrossRestartMidBucket(t *testing.T) {
+	const base = int64(1700000040000) // a minute boundary
+	options := LocalNodeOptions{CandleStateFile: filepath.Join(t.TempDir(), "candles.json")}
+	trades := []*types.WsTrade{
+		{Coin: "BTC", Px: "100", Sz: "1", Time: base + 1000},
+		{Coin: "BTC", Px: "110", Sz: "2", Time: base + 2000},
+	}
+	
+	before := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), options)
+	before.firstBlockTime = base
+	before.dataMu.Lock()
+	for _, trade := range trades {
+		before.updateCandles(trade)
+	}
+	before.dataMu.Unlock()
+	if err := before.SaveCandleState(); err != nil {
+		t.Fatal(err)
+	}
+	
+	// The restarted reader starts reading mid-bucket and replays the last trade
+	after := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), options)
+	after.firstBlockTime = base + 2000
+	after.dataMu.Lock()
+	after.updateCandles(trades[1])
+	after.updateCandles(&types.WsTrade{Coin: "BTC", Px: "90", Sz: "1", Time: base + 3000})
+	after.dataMu.Unlock()
+	
+	candle := after.GetCandle("BTC", "1m")
+	if candle == nil {
+		t.Fatal("restored candle not published after the restart")
+	}
+	want := types.Candle{T: base, T2: base + 59999, S: "BTC", I: "1m", O: 100, C: 90, H: 110, L: 90, V: 4, N: 3}
+	if *candle != want {
+		t.Fatalf("candle = %+v, want %+v", *candle, want)
+	}
+}
+
+func TestCandleWithoutSavedStateIsPartialAfterRestart(t *testing.T) {
+	const base = int64(1700000040000)
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	
+	// Reading started mid-way through the minute, so its open is unknown
+	r.firstBlockTime = base + 30000
+	r.dataMu.Lock()
+	r.updateCandles(&types.WsTrade{Coin: "BTC", Px: "90", Sz: "1", Time: base + 31000})
+	r.dataMu.Unlock()
+	
+	if candle := r.GetCandle("BTC", "1m"); candle != nil {
+		t.Fatalf("partial candle published: %+v", *candle)
+	}
+}
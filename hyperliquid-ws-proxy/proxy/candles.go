@@ -0,0 +1,311 @@
+package proxy
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/types"
+)
+
+// candleIntervals are the candle intervals built from local node trades. The
+// calendar month interval ("1M") is not supported as its length varies.
+var candleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// Candle backfill gap policies
+const (
+	CandleGapsFlat = "flat" // buckets without trades become flat candles at the previous close
+	CandleGapsSkip = "skip" // buckets without trades are left out
+)
+
+// Candle volume units
+const (
+	CandleVolumeBase  = "base"  // summed trade sizes, as Hyperliquid reports candle volume
+	CandleVolumeQuote = "quote" // summed trade notionals, price times size
+)
+
+// Decimals of volumes whose asset precision is unknown. Hyperliquid prices carry
+// at most 6 decimals minus szDecimals for perps and 8 minus szDecimals for spot,
+// so a notional never has more than 6 or 8 decimals.
+const (
+	perpNotionalDecimals = 6
+	spotVolumeDecimals   = 8
+)
+
+// candlePartialSlack is how long after a bucket opened the first block may be
+// read for the bucket's candle to still count as complete
+const candlePartialSlack = 5 * time.Second
+
+// candleKey identifies the open candle of a coin for one interval
+type candleKey struct {
+	coin     string
+	interval string
+}
+
+// updateCandles folds a trade into the open candle of every interval, closing
+// candles whose bucket the trade has moved past. Caller must hold dataMu.
+func (r *LocalNodeReader) updateCandles(trade *types.WsTrade) {
+	px, err := strconv.ParseFloat(trade.Px, 64)
+	if err != nil {
+		return
+	}
+	sz, err := strconv.ParseFloat(trade.Sz, 64)
+	if err != nil {
+		return
+	}
+	volume := r.tradeVolume(px, sz)
+	decimals := r.candleVolumeDecimals(trade.Coin)
+	
+	for interval, length := range candleIntervals {
+		key := candleKey{coin: trade.Coin, interval: interval}
+		openTime := trade.Time - trade.Time%length.Milliseconds()
+		
+		// A candle restored after a restart already holds the trades replayed up to its last one
+		if r.restoredCandles[key] {
+			if trade.Time <= r.candleLastTrade[key] {
+				continue
+			}
+			delete(r.restoredCandles, key)
+		}
+		
+		candle, exists := r.openCandles[key]
+		if exists && candle.T != openTime {
+			// Trades arrive in block order, so a different bucket means a later one
+			r.closeCandle(key, candle)
+			exists = false
+		}
+		
+		if !exists {
+			r.openCandles[key] = &types.Candle{
+				T:  openTime,
+				T2: openTime + length.Milliseconds() - 1,
+				S:  trade.Coin,
+				I:  interval,
+				O:  px,
+				C:  px,
+				H:  px,
+				L:  px,
+				V:  roundVolume(volume, decimals),
+				N:  1,
+			}
+			r.candleLastTrade[key] = trade.Time
+			
+			// Trades from before the first block read are missing, so the open would be wrong
+			if openTime < r.firstBlockTime-candlePartialSlack.Milliseconds() {
+				r.partialCandles[key] = true
+			}
+			continue
+		}
+		
+		candle.C = px
+		if px > candle.H {
+			candle.H = px
+		}
+		if px < candle.L {
+			candle.L = px
+		}
+		candle.V = roundVolume(candle.V+volume, decimals)
+		candle.N++
+		r.candleLastTrade[key] = trade.Time
+	}
+}
+
+// closeCandlesBefore closes every open candle whose bucket ended before the given
+// block time, so a candle is finalized even when no further trades arrive for its
+// coin. Caller must hold dataMu.
+func (r *LocalNodeReader) closeCandlesBefore(blockTime int64) {
+	for key, candle := range r.openCandles {
+		if candle.T2 < blockTime {
+			r.closeCandle(key, candle)
+		}
+	}
+}
+
+// closeCandle removes an open candle and queues it on the closed candles stream.
+// Caller must hold dataMu.
+func (r *LocalNodeReader) closeCandle(key candleKey, candle *types.Candle) {
+	delete(r.openCandles, key)
+	delete(r.candleLastTrade, key)
+	delete(r.restoredCandles, key)
+	
+	// An incomplete candle is never published
+	if r.partialCandles[key] {
+		delete(r.partialCandles, key)
+		logrus.WithFields(logrus.Fields{
+			"coin":     candle.S,
+			"interval": candle.I,
+		}).Debug("Discarding candle that started before the first block read")
+		return
+	}
+	
+	select {
+	case r.closedCandles <- candle:
+	default:
+		logrus.WithFields(logrus.Fields{
+			"coin":     candle.S,
+			"interval": candle.I,
+		}).Warn("Closed candles channel full, dropping candle")
+	}
+}
+
+// GetCandle returns a copy of the open candle for a coin and interval, or nil
+// if no trade has been seen in the current bucket or the candle is incomplete
+func (r *LocalNodeReader) GetCandle(coin, interval string) *types.Candle {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	key := candleKey{coin: coin, interval: interval}
+	candle, exists := r.openCandles[key]
+	if !exists || r.partialCandles[key] {
+		return nil
+	}
+	copied := *candle
+	return &copied
+}
+
+// BackfillCandles rebuilds up to count of the most recent closed candles of a coin
+// from its retained trades, oldest first. Buckets without trades are filled with
+// flat candles or skipped per gapPolicy. The oldest bucket is left out when older
+// trades were evicted or it opened before the first block read, since its candle
+// would be incomplete.
+func (r *LocalNodeReader) BackfillCandles(coin, interval string, count int, gapPolicy string) []*types.Candle {
+	length, exists := candleIntervals[interval]
+	if !exists || count <= 0 {
+		return nil
+	}
+	lengthMs := length.Milliseconds()
+	
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	// The open candle's bucket is still in progress
+	openTime := int64(-1)
+	if candle, exists := r.openCandles[candleKey{coin: coin, interval: interval}]; exists {
+		openTime = candle.T
+	}
+	
+	decimals := r.candleVolumeDecimals(coin)
+	var candles []*types.Candle
+	for _, trade := range r.latestTrades[coin] {
+		bucket := trade.Time - trade.Time%lengthMs
+		if bucket == openTime {
+			break
+		}
+		px, err := strconv.ParseFloat(trade.Px, 64)
+		if err != nil {
+			continue
+		}
+		sz, err := strconv.ParseFloat(trade.Sz, 64)
+		if err != nil {
+			continue
+		}
+		
+		var candle *types.Candle
+		if n := len(candles); n > 0 && candles[n-1].T == bucket {
+			candle = candles[n-1]
+		} else {
+			if n > 0 && gapPolicy != CandleGapsSkip {
+				previous := candles[n-1]
+				for t := previous.T + lengthMs; t < bucket; t += lengthMs {
+					candles = append(candles, flatCandle(previous, t, lengthMs))
+				}
+			}
+			candle = &types.Candle{
+				T:  bucket,
+				T2: bucket + lengthMs - 1,
+				S:  coin,
+				I:  interval,
+				O:  px,
+				H:  px,
+				L:  px,
+			}
+			candles = append(candles, candle)
+		}
+		
+		candle.C = px
+		if px > candle.H {
+			candle.H = px
+		}
+		if px < candle.L {
+			candle.L = px
+		}
+		candle.V = roundVolume(candle.V+r.tradeVolume(px, sz), decimals)
+		candle.N++
+	}
+	
+	if len(candles) > 0 && (r.trimmedTrades[coin] || candles[0].T < r.firstBlockTime-candlePartialSlack.Milliseconds()) {
+		candles = candles[1:]
+	}
+	if len(candles) > count {
+		candles = candles[len(candles)-count:]
+	}
+	return candles
+}
+
+// tradeVolume returns what a trade adds to candle volume in the configured unit
+func (r *LocalNodeReader) tradeVolume(px, sz float64) float64 {
+	if r.options.CandleVolumeUnit == CandleVolumeQuote {
+		return px * sz
+	}
+	return sz
+}
+
+// candleVolumeDecimals returns the decimals a coin's candle volume is rounded
+// to, so float sums don't drift past what its trades can express: szDecimals
+// for sizes, 6 for perp notionals and 8 for spot notionals or unknown coins
+func (r *LocalNodeReader) candleVolumeDecimals(coin string) int {
+	if r.assetFetcher == nil {
+		return spotVolumeDecimals
+	}
+	asset, exists := r.assetFetcher.GetAssetByName(coin)
+	if !exists {
+		return spotVolumeDecimals
+	}
+	if r.options.CandleVolumeUnit == CandleVolumeQuote {
+		if asset.IsSpot {
+			return spotVolumeDecimals
+		}
+		return perpNotionalDecimals
+	}
+	return asset.SzDecimals
+}
+
+// roundVolume rounds a volume to the given decimals
+func roundVolume(volume float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(volume*scale) / scale
+}
+
+// flatCandle builds a tradeless candle at the given open time, priced at the previous close
+func flatCandle(previous *types.Candle, openTime, lengthMs int64) *types.Candle {
+	return &types.Candle{
+		T:  openTime,
+		T2: openTime + lengthMs - 1,
+		S:  previous.S,
+		I:  previous.I,
+		O:  previous.C,
+		C:  previous.C,
+		H:  previous.C,
+		L:  previous.C,
+	}
+}
+
+// ClosedCandles streams candles as their interval ends
+func (r *LocalNodeReader) ClosedCandles() <-chan *types.Candle {
+	return r.closedCandles
+}
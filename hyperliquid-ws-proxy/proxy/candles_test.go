@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"testing"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+// newBackfillReader returns a reader holding BTC trades in minutes 0 and 2
+// after base, none in minute 1, and one in the still open minute 3
+func newBackfillReader(t *testing.T, base int64) *LocalNodeReader {
+	t.Helper()
+	
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	minute := int64(60000)
+	r.latestTrades["BTC"] = []*types.WsTrade{
+		{Coin: "BTC", Px: "100", Sz: "1", Time: base + 1000},
+		{Coin: "BTC", Px: "105", Sz: "2", Time: base + 2000},
+		{Coin: "BTC", Px: "98", Sz: "0.5", Time: base + 2*minute + 1000},
+		{Coin: "BTC", Px: "99", Sz: "1", Time: base + 3*minute + 1000},
+	}
+	r.openCandles[candleKey{coin: "BTC", interval: "1m"}] = &types.Candle{T: base + 3*minute}
+	r.firstBlockTime = base
+	return r
+}
+
+func TestBackfillCandlesFromRetainedTrades(t *testing.T) {
+	const base = int64(1700000040000) // a minute boundary
+	minute := int64(60000)
+	first := types.Candle{T: base, T2: base + minute - 1, S: "BTC", I: "1m", O: 100, C: 105, H: 105, L: 100, V: 3, N: 2}
+	flat := types.Candle{T: base + minute, T2: base + 2*minute - 1, S: "BTC", I: "1m", O: 105, C: 105, H: 105, L: 105}
+	third := types.Candle{T: base + 2*minute, T2: base + 3*minute - 1, S: "BTC", I: "1m", O: 98, C: 98, H: 98, L: 98, V: 0.5, N: 1}
+	
+	cases := []struct {
+		name  string
+		count int
+		gaps  string
+		want  []types.Candle
+	}{
+		{"flat gaps", 10, CandleGapsFlat, []types.Candle{first, flat, third}},
+		{"skipped gaps", 10, CandleGapsSkip, []types.Candle{first, third}},
+		{"bounded by count", 2, CandleGapsFlat, []types.Candle{flat, third}},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			candles := newBackfillReader(t, base).BackfillCandles("BTC", "1m", tc.count, tc.gaps)
+			if len(candles) != len(tc.want) {
+				t.Fatalf("got %d candles, want %d", len(candles), len(tc.want))
+			}
+			for i, candle := range candles {
+				if *candle != tc.want[i] {
+					t.Fatalf("candle %d = %+v, want %+v", i, *candle, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillCandlesSkipsIncompleteOldestBucket(t *testing.T) {
+	const base = int64(1700000040000)
+	r := newBackfillReader(t, base)
+	
+	// Reading started mid-way through the first minute
+	r.firstBlockTime = base + 30000
+	candles := r.BackfillCandles("BTC", "1m", 10, CandleGapsFlat)
+	if len(candles) != 2 || candles[0].T != base+60000 {
+		t.Fatalf("backfill = %+v, want the 2 candles after the partial minute", candles)
+	}
+}
+
+func TestCandleVolumeUnits(t *testing.T) {
+	const base = int64(1700000040000)
+	trades := []*types.WsTrade{
+		{Coin: "BTC", Px: "100.5", Sz: "0.1", Time: base + 1000},
+		{Coin: "BTC", Px: "101", Sz: "0.3", Time: base + 2000},
+	}
+	
+	cases := []struct {
+		unit string
+		want float64
+	}{
+		{CandleVolumeBase, 0.4},
+		{CandleVolumeQuote, 40.35},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.unit, func(t *testing.T) {
+			fetcher := NewAssetFetcher("")
+			btc := &AssetInfo{Index: 0, Name: "BTC", SzDecimals: 5}
+			fetcher.perpAssets[0] = btc
+			fetcher.assetsByName["BTC"] = btc
+			
+			r := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{CandleVolumeUnit: tc.unit})
+			r.firstBlockTime = base
+			r.dataMu.Lock()
+			for _, trade := range trades {
+				r.updateCandles(trade)
+			}
+			r.dataMu.Unlock()
+			if candle := r.GetCandle("BTC", "1m"); candle == nil || candle.V != tc.want {
+				t.Fatalf("open candle = %+v, want volume %v", candle, tc.want)
+			}
+			
+			// Backfill sums the same trades the same way
+			r.latestTrades["BTC"] = trades
+			r.openCandles[candleKey{coin: "BTC", interval: "1m"}] = &types.Candle{T: base + 60000}
+			if candles := r.BackfillCandles("BTC", "1m", 1, CandleGapsFlat); len(candles) != 1 || candles[0].V != tc.want {
+				t.Fatalf("backfilled candles = %+v, want one with volume %v", candles, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// runConnectionLifetime periodically closes connections open longer than
+// max_connection_duration_sec
+func (p *Proxy) runConnectionLifetime() {
+	maxDuration := time.Duration(p.config.Proxy.MaxConnectionDurationSec) * time.Second
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		p.closeExpiredConnections(maxDuration)
+	}
+}
+
+// closeExpiredConnections tells clients connected for maxDuration or longer to
+// reconnect and closes them with 1012 (service restart), after the messages
+// already queued for them
+func (p *Proxy) closeExpiredConnections(maxDuration time.Duration) {
+	for _, c := range p.hub.GetClients() {
+		if c.Closing() || time.Since(c.ConnectedAt()) < maxDuration {
+			continue
+		}
+		
+		p.sendNotificationToClient(c, "maximum connection duration reached, reconnect")
+		c.CloseWith(websocket.CloseServiceRestart, "maximum connection duration reached")
+		logrus.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"duration":  time.Since(c.ConnectedAt()).Round(time.Second),
+		}).Info("Closing connection at maximum duration")
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/config"
+)
+
+// dialProxy connects a WebSocket client to p's hub, with query added to the
+// URL, and waits for its registration
+func dialProxy(t *testing.T, p *Proxy, query string) *websocket.Conn {
+	t.Helper()
+	
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client.ServeWS(p.hub, w, r, client.ConnectOptions{})
+	}))
+	t.Cleanup(srv.Close)
+	
+	registered := p.hub.GetClientCount() + 1
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	
+	deadline := time.Now().Add(2 * time.Second)
+	for p.hub.GetClientCount() < registered {
+		if time.Now().After(deadline) {
+			t.Fatal("client never registered with the hub")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return conn
+}
+
+func TestConnectionClosedAfterMaxDuration(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.MaxConnectionDurationSec = 1
+	})
+	conn := dialProxy(t, p, "")
+	connected := time.Now()
+	
+	// A connection younger than the limit stays open
+	p.closeExpiredConnections(time.Hour)
+	for _, c := range p.hub.GetClients() {
+		if c.Closing() {
+			t.Fatal("connection closed before reaching the maximum duration")
+		}
+	}
+	
+	go p.runConnectionLifetime()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var notified bool
+	for {
+		_, message, err := conn.ReadMessage()
+		if err == nil {
+			notified = notified || strings.Contains(string(message), `"channel":"notification"`)
+			continue
+		}
+		
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("connection ended with %v, want a close frame", err)
+		}
+		if closeErr.Code != websocket.CloseServiceRestart {
+			t.Fatalf("close code = %d, want %d (service restart)", closeErr.Code, websocket.CloseServiceRestart)
+		}
+		break
+	}
+	if elapsed := time.Since(connected); elapsed < time.Second {
+		t.Fatalf("connection closed after %s, before the 1s maximum duration", elapsed)
+	}
+	if !notified {
+		t.Fatal("no notification sent before closing")
+	}
+}
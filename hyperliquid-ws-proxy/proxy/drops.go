@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dropCounter counts frames dropped because a client could not take them and
+// logs them sampled, at most once per channel per log interval
+type dropCounter struct {
+	mu          sync.Mutex
+	total       int64
+	byChannel   map[string]int64
+	unlogged    map[string]int64     // channel -> drops since the last log line
+	lastLogged  map[string]time.Time // channel -> time of the last log line
+	logInterval time.Duration        // 0 disables drop logging
+}
+
+// newDropCounter creates a drop counter logging at most once per interval per channel
+func newDropCounter(logIntervalSec int) *dropCounter {
+	return &dropCounter{
+		byChannel:   make(map[string]int64),
+		unlogged:    make(map[string]int64),
+		lastLogged:  make(map[string]time.Time),
+		logInterval: time.Duration(logIntervalSec) * time.Second,
+	}
+}
+
+// record counts a dropped frame. site names the code path that dropped it.
+func (d *dropCounter) record(channel, clientID, site string) {
+	if channel == "" {
+		channel = "unknown"
+	}
+	
+	d.mu.Lock()
+	d.total++
+	d.byChannel[channel]++
+	d.unlogged[channel]++
+	
+	shouldLog := d.logInterval > 0 && time.Since(d.lastLogged[channel]) >= d.logInterval
+	dropped := d.unlogged[channel]
+	if shouldLog {
+		d.lastLogged[channel] = time.Now()
+		d.unlogged[channel] = 0
+	}
+	d.mu.Unlock()
+	
+	if shouldLog {
+		logrus.WithFields(logrus.Fields{
+			"channel":   channel,
+			"client_id": clientID,
+			"site":      site,
+			"dropped":   dropped,
+		}).Warn("Dropped messages for slow or disconnected clients")
+	}
+}
+
+// totals returns the number of dropped frames overall and per channel
+func (d *dropCounter) totals() (int64, map[string]int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	byChannel := make(map[string]int64, len(d.byChannel))
+	for channel, count := range d.byChannel {
+		byChannel[channel] = count
+	}
+	return d.total, byChannel
+}
+
+// frameChannel returns the channel of an outbound frame, empty if it has none
+func frameChannel(data []byte) string {
+	var frame struct {
+		Channel string `json:"channel"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return ""
+	}
+	return frame.Channel
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"sync"
+
+	"hyperliquid-ws-proxy/client"
+)
+
+// Clients each fan-out worker handles at least, so small subscriptions are
+// sent inline rather than paying for goroutines
+const fanOutMinClientsPerWorker = 64
+
+// sendToClients queues data for every client, spreading the sends over up to
+// FanOutWorkers goroutines for large subscriptions so a slow send doesn't hold
+// up every client behind it. Returns the clients the message couldn't be queued
+// for. It returns once every send is done, so each client still receives
+// messages in order.
+func (p *Proxy) sendToClients(clients map[*client.Client]bool, data []byte) []*client.Client {
+	workers := min(p.config.Proxy.FanOutWorkers, len(clients)/fanOutMinClientsPerWorker)
+	if workers <= 1 {
+		var failed []*client.Client
+		for c := range clients {
+			if !p.safelyTryToSendMessage(c, data) {
+				failed = append(failed, c)
+			}
+		}
+		return failed
+	}
+	
+	list := make([]*client.Client, 0, len(clients))
+	for c := range clients {
+		list = append(list, c)
+	}
+	
+	chunkSize := (len(list) + workers - 1) / workers
+	results := make([][]*client.Client, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunkSize
+		end := min(start+chunkSize, len(list))
+		if start >= end {
+			break
+		}
+		
+		wg.Add(1)
+		go func(w int, chunk []*client.Client) {
+			defer wg.Done()
+			for _, c := range chunk {
+				if !p.safelyTryToSendMessage(c, data) {
+					results[w] = append(results[w], c)
+				}
+			}
+		}(w, list[start:end])
+	}
+	wg.Wait()
+	
+	var failed []*client.Client
+	for _, result := range results {
+		failed = append(failed, result...)
+	}
+	return failed
+}
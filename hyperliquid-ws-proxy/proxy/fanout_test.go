@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"fmt"
+	"testing"
+
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/config"
+)
+
+func TestSendToClientsReportsFullClients(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		p := newTestProxy(t, func(cfg *config.Config) {
+			cfg.Proxy.FanOutWorkers = workers
+		})
+		
+		clients := make(map[*client.Client]bool)
+		full := make(map[*client.Client]bool)
+		for i := 0; i < 4*fanOutMinClientsPerWorker; i++ {
+			c := client.NewClient(nil, p.hub)
+			if i%10 == 0 {
+				for len(c.Send) < cap(c.Send) {
+					c.Send <- []byte("filler")
+				}
+				full[c] = true
+			}
+			clients[c] = true
+		}
+		
+		failed := p.sendToClients(clients, []byte("update"))
+		if len(failed) != len(full) {
+			t.Fatalf("%d workers: %d clients failed, want the %d full ones", workers, len(failed), len(full))
+		}
+		for _, c := range failed {
+			if !full[c] {
+				t.Fatalf("%d workers: client with room reported as failed", workers)
+			}
+		}
+		for c := range clients {
+			if !full[c] && len(c.Send) != 1 {
+				t.Fatalf("%d workers: client got %d frames, want 1", workers, len(c.Send))
+			}
+		}
+	}
+}
+
+// BenchmarkSendToClients measures fanning one message out to many subscribers,
+// sequentially and over fan-out workers. Workers only pay off with several CPUs.
+func BenchmarkSendToClients(b *testing.B) {
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg, err := config.LoadConfig("")
+			if err != nil {
+				b.Fatal(err)
+			}
+			cfg.Proxy.FanOutWorkers = workers
+			p := NewProxy(cfg)
+			
+			clients := make(map[*client.Client]bool)
+			for i := 0; i < 10000; i++ {
+				clients[client.NewClient(nil, p.hub)] = true
+			}
+			
+			data := []byte(`{"channel":"l2Book","data":{"coin":"BTC"}}`)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if failed := p.sendToClients(clients, data); len(failed) > 0 {
+					b.Fatalf("%d clients failed", len(failed))
+				}
+				
+				// Empty the buffers outside the measurement, as writePump would
+				b.StopTimer()
+				for c := range clients {
+					<-c.Send
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
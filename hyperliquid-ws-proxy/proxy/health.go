@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"time"
+)
+
+// HealthStatus describes whether the proxy is currently serving fresh data.
+// Reasons make it unhealthy; warnings only degrade it, e.g. while the upstream
+// reconnects before the data it last sent goes stale.
+type HealthStatus struct {
+	Healthy  bool     `json:"healthy"`
+	Status   string   `json:"status"` // "healthy", "degraded" or "unhealthy"
+	Source   string   `json:"source"` // "local_node" or "upstream"
+	Reasons  []string `json:"reasons,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	
+	// Age of the most recent data, in seconds; -1 when nothing was received yet
+	DataAgeSec   float64 `json:"data_age_sec"`
+	MaxDataAgeSec float64 `json:"max_data_age_sec"`
+	LastDataTime int64   `json:"last_data_time,omitempty"` // unix millis of the most recent data
+}
+
+// Ready reports whether the proxy has data to serve: the local node reader has
+// read a block, or the upstream connection has subscribed. Clients connecting
+// earlier would only get empty snapshots.
+func (p *Proxy) Ready() bool {
+	if p.useLocalNode && p.localNodeReader != nil {
+		return !p.localNodeReader.IsReplaying() && !p.localNodeReader.GetLastBlockTime().IsZero()
+	}
+	
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+	return p.upstreamReady
+}
+
+// HealthStatus evaluates data freshness against the configured thresholds
+func (p *Proxy) HealthStatus() HealthStatus {
+	var (
+		status     HealthStatus
+		lastData   time.Time
+		maxDataAge time.Duration
+	)
+	
+	if p.useLocalNode && p.localNodeReader != nil {
+		status.Source = "local_node"
+		lastData = p.localNodeReader.GetLastBlockTime()
+		maxDataAge = p.config.GetMaxBlockAge()
+		if !p.localNodeReader.IsRunning() {
+			status.Reasons = append(status.Reasons, "local node reader not running")
+		}
+		if p.localNodeReader.IsReplaying() {
+			status.Reasons = append(status.Reasons, "replaying historical blocks")
+		}
+		if err := p.localNodeReader.GetAccessError(); err != nil {
+			status.Reasons = append(status.Reasons, "local node data not readable: "+err.Error())
+		}
+		if reason := p.networkMismatch(); reason != "" {
+			status.Reasons = append(status.Reasons, reason)
+		}
+	} else {
+		status.Source = "upstream"
+		if p.hlConnector != nil {
+			lastData = p.hlConnector.LastActivity()
+			if !p.hlConnector.IsConnected() {
+				status.Warnings = append(status.Warnings, "upstream disconnected")
+			}
+		}
+		maxDataAge = p.config.GetMaxUpstreamStale()
+	}
+	
+	status.MaxDataAgeSec = maxDataAge.Seconds()
+	status.DataAgeSec = -1
+	
+	if lastData.IsZero() {
+		status.Reasons = append(status.Reasons, "no data received yet")
+	} else {
+		age := time.Since(lastData)
+		status.LastDataTime = lastData.UnixMilli()
+		status.DataAgeSec = age.Seconds()
+		if age > maxDataAge {
+			status.Reasons = append(status.Reasons, "data older than threshold")
+		}
+	}
+	
+	status.Healthy = len(status.Reasons) == 0
+	switch {
+	case !status.Healthy:
+		status.Status = "unhealthy"
+	case len(status.Warnings) > 0:
+		status.Status = "degraded"
+	default:
+		status.Status = "healthy"
+	}
+	return status
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperliquid-ws-proxy/config"
+)
+
+// setLastBlock marks the reader as running with its last block read age ago
+func setLastBlock(r *LocalNodeReader, age time.Duration) {
+	r.mu.Lock()
+	r.isRunning = true
+	r.mu.Unlock()
+	
+	r.dataMu.Lock()
+	r.lastBlockTime = time.Now().Add(-age).UnixMilli()
+	r.dataMu.Unlock()
+}
+
+func TestHealthFlipsAtConfiguredBlockAge(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.MaxBlockAgeSec = 10
+	})
+	
+	setLastBlock(p.localNodeReader, 8*time.Second)
+	if status := p.HealthStatus(); !status.Healthy || status.Status != "healthy" || status.MaxDataAgeSec != 10 {
+		t.Fatalf("status with an 8s old block = %+v, want healthy under a 10s threshold", status)
+	}
+	
+	setLastBlock(p.localNodeReader, 12*time.Second)
+	if status := p.HealthStatus(); status.Healthy || status.Status != "unhealthy" {
+		t.Fatalf("status with a 12s old block = %+v, want unhealthy past a 10s threshold", status)
+	}
+}
+
+func TestHealthBlockAgeDefaultsPerNetwork(t *testing.T) {
+	tests := []struct {
+		network string
+		healthy bool
+	}{
+		{"mainnet", false},
+		{"testnet", true},
+	}
+	
+	for _, tt := range tests {
+		p := newTestProxy(t, func(cfg *config.Config) {
+			cfg.Hyperliquid.Network = tt.network
+			cfg.Proxy.MaxBlockAgeSec = 0
+		})
+		setLastBlock(p.localNodeReader, 20*time.Second)
+		if status := p.HealthStatus(); status.Healthy != tt.healthy {
+			t.Errorf("%s status with a 20s old block = %+v, want healthy %v", tt.network, status, tt.healthy)
+		}
+	}
+}
+
+func TestHealthWithoutDataIsUnhealthy(t *testing.T) {
+	p := newTestProxy(t, nil)
+	status := p.HealthStatus()
+	if status.Healthy || status.DataAgeSec != -1 {
+		t.Fatalf("status before any block = %+v, want unhealthy with no data age", status)
+	}
+}
+
+func TestPermissionErrorSurfacedUntilReadable(t *testing.T) {
+	p := newTestProxy(t, nil)
+	r := p.localNodeReader
+	setLastBlock(r, 0)
+	
+	// A scan hitting a permission error records it
+	r.scanDenied = false
+	denied := &os.PathError{Op: "open", Path: filepath.Join(r.dataPath, "replica_cmds"), Err: fs.ErrPermission}
+	if !r.checkAccess(denied) {
+		t.Fatal("permission error not recognized")
+	}
+	r.clearAccessError()
+	status := p.HealthStatus()
+	if status.Healthy || !strings.Contains(strings.Join(status.Reasons, "; "), "local node data not readable") {
+		t.Fatalf("status with an unreadable data path = %+v, want it reported", status)
+	}
+	
+	// The next scan getting through clears it
+	r.scanDenied = false
+	r.checkAccess(nil)
+	r.clearAccessError()
+	if err := r.GetAccessError(); err != nil {
+		t.Fatalf("access error %v kept after a clean scan", err)
+	}
+}
+
+func TestUnreadableReplicaCmdsReported(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions don't restrict root")
+	}
+	p := newTestProxy(t, nil)
+	replicaCmds := filepath.Join(p.localNodeReader.dataPath, "replica_cmds")
+	if err := os.MkdirAll(replicaCmds, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(replicaCmds, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(replicaCmds, 0o755) })
+	
+	p.localNodeReader.scanReplicaCmdsDirectory()
+	if err := p.localNodeReader.GetAccessError(); !os.IsPermission(err) {
+		t.Fatalf("access error after scanning an unreadable replica_cmds = %v, want a permission error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// infoPerpCtx holds the parts of a perp's asset context that blocks don't
+// carry, as last reported by the info API
+type infoPerpCtx struct {
+	Funding      float64
+	OpenInterest float64
+	OraclePx     float64
+	DayNtlVlm    float64
+	PrevDayPx    float64
+}
+
+// SetAssetCtxInterval sets how often perp asset contexts are fetched from the
+// info API, 0 disabling the fetch. Takes effect on Start.
+func (af *AssetFetcher) SetAssetCtxInterval(interval time.Duration) {
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	af.ctxInterval = interval
+}
+
+// GetPerpInfoCtx returns the info API context of a perp, if fetched
+func (af *AssetFetcher) GetPerpInfoCtx(coin string) (infoPerpCtx, bool) {
+	af.mu.RLock()
+	defer af.mu.RUnlock()
+	
+	ctx, exists := af.perpCtxs[coin]
+	return ctx, exists
+}
+
+// runAssetCtxUpdates fetches perp asset contexts right away and then every interval
+func (af *AssetFetcher) runAssetCtxUpdates(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		if err := af.fetchPerpContexts(); err != nil {
+			logrus.WithError(err).Warn("Failed to fetch perp asset contexts, keeping the last ones")
+		}
+		
+		select {
+		case <-ticker.C:
+		case <-af.stopChan:
+			return
+		}
+	}
+}
+
+// fetchPerpContexts fetches the funding, open interest, oracle price and day
+// statistics of every perp with a metaAndAssetCtxs request, whose response is
+// [meta, contexts] with contexts in universe order
+func (af *AssetFetcher) fetchPerpContexts() error {
+	var response []json.RawMessage
+	if err := af.postInfo("metaAndAssetCtxs", &response); err != nil {
+		return err
+	}
+	if len(response) < 2 {
+		return fmt.Errorf("unexpected metaAndAssetCtxs response with %d elements", len(response))
+	}
+	
+	var meta HyperliquidMetaResponse
+	if err := json.Unmarshal(response[0], &meta); err != nil {
+		return fmt.Errorf("failed to decode meta: %w", err)
+	}
+	var contexts []struct {
+		Funding      string `json:"funding"`
+		OpenInterest string `json:"openInterest"`
+		OraclePx     string `json:"oraclePx"`
+		DayNtlVlm    string `json:"dayNtlVlm"`
+		PrevDayPx    string `json:"prevDayPx"`
+	}
+	if err := json.Unmarshal(response[1], &contexts); err != nil {
+		return fmt.Errorf("failed to decode asset contexts: %w", err)
+	}
+	
+	perpCtxs := make(map[string]infoPerpCtx, len(contexts))
+	for i, ctx := range contexts {
+		if i >= len(meta.Universe) {
+			break
+		}
+		perpCtxs[meta.Universe[i].Name] = infoPerpCtx{
+			Funding:      parseInfoFloat(ctx.Funding),
+			OpenInterest: parseInfoFloat(ctx.OpenInterest),
+			OraclePx:     parseInfoFloat(ctx.OraclePx),
+			DayNtlVlm:    parseInfoFloat(ctx.DayNtlVlm),
+			PrevDayPx:    parseInfoFloat(ctx.PrevDayPx),
+		}
+	}
+	
+	af.mu.Lock()
+	af.perpCtxs = perpCtxs
+	af.mu.Unlock()
+	
+	logrus.WithField("perps", len(perpCtxs)).Debug("Fetched perp asset contexts")
+	return nil
+}
+
+// parseInfoFloat parses a decimal string of an info response, 0 when absent
+func parseInfoFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
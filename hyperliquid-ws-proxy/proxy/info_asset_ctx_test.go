@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+const (
+	ctxMeta     = `{"universe":[{"name":"BTC","szDecimals":5},{"name":"ETH","szDecimals":4}]}`
+	ctxSpotMeta = `{"universe":[],"tokens":[]}`
+	ctxResponse = `[` + ctxMeta + `,[{"funding":"0.0000125","openInterest":"1234.5","oraclePx":"97000","dayNtlVlm":"5000000","prevDayPx":"95000"},{"funding":"-0.00001","openInterest":"800","oraclePx":"3000","dayNtlVlm":"100","prevDayPx":"2900"}]]`
+)
+
+// startAssetCtxServer serves an info endpoint answering meta, spotMeta and
+// metaAndAssetCtxs requests, failing the latter while failing is set
+func startAssetCtxServer(t *testing.T, failing *atomic.Bool) string {
+	t.Helper()
+	
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Type {
+		case "meta":
+			w.Write([]byte(ctxMeta))
+		case "spotMeta":
+			w.Write([]byte(ctxSpotMeta))
+		case "metaAndAssetCtxs":
+			if failing.Load() {
+				http.Error(w, "unavailable", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(ctxResponse))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(info.Close)
+	return info.URL
+}
+
+// perpCtx returns the activeAssetCtx of a perp built by r
+func perpCtx(t *testing.T, r *LocalNodeReader, coin string) types.PerpsAssetCtx {
+	t.Helper()
+	
+	ctx, ok := r.GetAssetCtx(coin).(*types.WsActiveAssetCtx)
+	if !ok {
+		t.Fatalf("asset context of %s = %#v, want a perp context", coin, r.GetAssetCtx(coin))
+	}
+	return ctx.Ctx
+}
+
+func TestAssetCtxCarriesInfoFundingAndOpenInterest(t *testing.T) {
+	fetcher := NewAssetFetcher(startAssetCtxServer(t, new(atomic.Bool)))
+	fetcher.SetAssetCtxInterval(time.Hour)
+	if err := fetcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fetcher.Stop)
+	
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, fetched := fetcher.GetPerpInfoCtx("BTC"); fetched {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("perp asset contexts never fetched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	
+	r := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{})
+	ctx := perpCtx(t, r, "BTC")
+	if ctx.Funding != 0.0000125 || ctx.OpenInterest != 1234.5 || ctx.DayNtlVlm != 5000000 || ctx.PrevDayPx != 95000 {
+		t.Fatalf("BTC context = %+v, want the info API's funding, open interest and day statistics", ctx)
+	}
+	if ctx.OraclePx != 97000 {
+		t.Fatalf("oracle price = %v, want the info API's 97000 before validators set one", ctx.OraclePx)
+	}
+	if ctx := perpCtx(t, r, "ETH"); ctx.Funding != -0.00001 || ctx.OpenInterest != 800 {
+		t.Fatalf("ETH context = %+v, want its own funding and open interest", ctx)
+	}
+	
+	// Validator prices take precedence over the polled oracle price
+	r.dataMu.Lock()
+	r.perpPrices["BTC"] = perpPrices{oraclePx: 97100, markPx: 97050}
+	r.dataMu.Unlock()
+	ctx = perpCtx(t, r, "BTC")
+	if ctx.OraclePx != 97100 || ctx.MarkPx != 97050 {
+		t.Fatalf("BTC context = %+v, want the validators' oracle and mark prices", ctx)
+	}
+	if ctx.Funding != 0.0000125 || ctx.OpenInterest != 1234.5 {
+		t.Fatalf("BTC context = %+v, lost the info API's funding and open interest", ctx)
+	}
+}
+
+func TestAssetCtxKeepsLastInfoAfterFailedPoll(t *testing.T) {
+	failing := new(atomic.Bool)
+	fetcher := NewAssetFetcher(startAssetCtxServer(t, failing))
+	if err := fetcher.fetchPerpContexts(); err != nil {
+		t.Fatal(err)
+	}
+	
+	failing.Store(true)
+	if err := fetcher.fetchPerpContexts(); err == nil {
+		t.Fatal("failed poll reported no error")
+	}
+	if info, fetched := fetcher.GetPerpInfoCtx("BTC"); !fetched || info.OpenInterest != 1234.5 {
+		t.Fatalf("BTC info after a failed poll = %+v, %v, want the previous values", info, fetched)
+	}
+}
+
+func TestAssetCtxWithoutInfoPolling(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	if ctx := r.GetAssetCtx("BTC"); ctx != nil {
+		t.Fatalf("asset context = %#v for a coin nothing is known about, want nil", ctx)
+	}
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+// infoCache caches responses to info POST requests keyed by a hash of the payload
+type infoCache struct {
+	mu         sync.Mutex
+	entries    map[string]*infoCacheEntry
+	ttls       map[string]time.Duration // info request type -> TTL
+	defaultTTL time.Duration
+}
+
+// infoCacheEntry holds a cached response and its expiry
+type infoCacheEntry struct {
+	response *types.PostResponse
+	expires  time.Time
+}
+
+// newInfoCache creates an info cache from per-type TTLs in milliseconds
+func newInfoCache(defaultTTLMs int, ttlsMs map[string]int) *infoCache {
+	ttls := make(map[string]time.Duration, len(ttlsMs))
+	for infoType, ms := range ttlsMs {
+		ttls[infoType] = time.Duration(ms) * time.Millisecond
+	}
+	
+	return &infoCache{
+		entries:    make(map[string]*infoCacheEntry),
+		ttls:       ttls,
+		defaultTTL: time.Duration(defaultTTLMs) * time.Millisecond,
+	}
+}
+
+// ttlFor returns the TTL for an info payload based on its "type" field
+func (ic *infoCache) ttlFor(payload json.RawMessage) time.Duration {
+	var req struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return 0
+	}
+	
+	if ttl, exists := ic.ttls[req.Type]; exists {
+		return ttl
+	}
+	return ic.defaultTTL
+}
+
+// cacheKey hashes a payload into a cache key
+func (ic *infoCache) cacheKey(payload json.RawMessage) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a cached response for a payload if it hasn't expired
+func (ic *infoCache) get(payload json.RawMessage) (*types.PostResponse, bool) {
+	key := ic.cacheKey(payload)
+	
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	
+	entry, exists := ic.entries[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(ic.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// set caches a response for a payload, skipping payload types without a TTL
+func (ic *infoCache) set(payload json.RawMessage, response *types.PostResponse) {
+	ttl := ic.ttlFor(payload)
+	if ttl <= 0 {
+		return
+	}
+	
+	now := time.Now()
+	
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	
+	// Drop expired entries so the cache doesn't grow unbounded
+	for key, entry := range ic.entries {
+		if now.After(entry.expires) {
+			delete(ic.entries, key)
+		}
+	}
+	
+	ic.entries[ic.cacheKey(payload)] = &infoCacheEntry{
+		response: response,
+		expires:  now.Add(ttl),
+	}
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/types"
+)
+
+// startPostUpstream serves an upstream stub answering every POST
+// request with an empty info response, counting them in posts
+func startPostUpstream(t *testing.T, posts *int64) string {
+	return startUpstream(t, func(conn *websocket.Conn, msg types.WSMessage) {
+		if msg.Method != "post" || msg.ID == nil {
+			return
+		}
+		atomic.AddInt64(posts, 1)
+		reply := types.PostResponse{ID: *msg.ID, Response: types.PostResponseInner{Type: "info", Payload: json.RawMessage(`{}`)}}
+		data, _ := json.Marshal(reply)
+		conn.WriteJSON(types.WSMessage{Channel: "post", Data: data})
+	})
+}
+
+// postInfo sends an info POST request for c and waits for its reply
+func postInfo(t *testing.T, p *Proxy, c *client.Client, id int64, payload string) {
+	t.Helper()
+	
+	p.handlePostRequest(c, &types.WSMessage{Method: "post", ID: &id, Request: &types.PostRequest{Type: "info", Payload: json.RawMessage(payload)}})
+	select {
+	case frame := <-c.Send:
+		if !strings.Contains(string(frame), `"type":"info"`) {
+			t.Fatalf("reply to request %d = %s, want an info response", id, frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no reply to request %d", id)
+	}
+}
+
+func TestInfoCacheServesRepeatedQueriesWithinTTL(t *testing.T) {
+	var posts int64
+	url := startPostUpstream(t, &posts)
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.MainnetURL = url
+		cfg.Proxy.EnableLocalNode = false
+		cfg.Proxy.InfoCacheDefaultTTLMs = 0
+		cfg.Proxy.InfoCacheTTLMs = map[string]int{"meta": 60000}
+	})
+	if err := p.hlConnector.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.hlConnector.Disconnect)
+	c := client.NewClient(nil, p.hub)
+	
+	postInfo(t, p, c, 1, `{"type":"meta"}`)
+	postInfo(t, p, c, 2, `{"type":"meta"}`)
+	if upstream := atomic.LoadInt64(&posts); upstream != 1 {
+		t.Fatalf("upstream POST requests for two identical meta queries = %d, want 1", upstream)
+	}
+	
+	// Types without a TTL always go upstream
+	postInfo(t, p, c, 3, `{"type":"l2Book","coin":"BTC"}`)
+	postInfo(t, p, c, 4, `{"type":"l2Book","coin":"BTC"}`)
+	if upstream := atomic.LoadInt64(&posts); upstream != 3 {
+		t.Fatalf("upstream POST requests = %d, want uncached l2Book queries forwarded", upstream)
+	}
+}
+
+func TestInfoCacheEntriesExpire(t *testing.T) {
+	cache := newInfoCache(20, nil)
+	payload := json.RawMessage(`{"type":"meta"}`)
+	cache.set(payload, &types.PostResponse{ID: 1})
+	if _, ok := cache.get(payload); !ok {
+		t.Fatal("fresh entry not served")
+	}
+	
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := cache.get(payload); ok {
+		t.Fatal("entry served past its TTL")
+	}
+}
@@ -1,16 +1,21 @@
 package proxy
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
 	"hyperliquid-ws-proxy/types"
 )
@@ -19,13 +24,22 @@ import (
 type HyperliquidNodeBlock struct {
 	ABCIBlock struct {
 		Time                string                    `json:"time"`
-		SignedActionBundles [][]interface{}           `json:"signed_action_bundles"`
+		SignedActionBundles []json.RawMessage         `json:"signed_action_bundles"`
 		Round               int64                     `json:"round"`
 		ParentRound         int64                     `json:"parent_round"`
 		Hardfork            map[string]interface{}    `json:"hardfork"`
 		Proposer            string                    `json:"proposer"`
 	} `json:"abci_block"`
-	Resps interface{} `json:"resps"`
+	Resps json.RawMessage `json:"resps"`
+}
+
+// isEmpty reports whether the block carries neither action bundles nor responses
+func (b *HyperliquidNodeBlock) isEmpty() bool {
+	if len(b.ABCIBlock.SignedActionBundles) > 0 {
+		return false
+	}
+	responses, err := decodeBlockResponses(b.Resps)
+	return err == nil && len(responses) == 0
 }
 
 // SignedActionBundle represents a bundle of signed actions
@@ -53,10 +67,19 @@ type ActionData struct {
 	Type     string      `json:"type"`
 	Orders   []Order     `json:"orders,omitempty"`
 	Cancels  []Cancel    `json:"cancels,omitempty"`
+	Modifies []Modify    `json:"modifies,omitempty"`
 	Grouping string      `json:"grouping,omitempty"`
+	Builder  *Builder    `json:"builder,omitempty"`
+	Pxs      []json.RawMessage `json:"pxs,omitempty"` // SetGlobalAction: [oraclePx, markPx] per perp
 	Time     int64       `json:"time,omitempty"` 
 }
 
+// Builder is the builder an order action was routed through and the fee it charges
+type Builder struct {
+	Address string `json:"b"` // builder address
+	Fee     int    `json:"f"` // fee in tenths of a basis point
+}
+
 // Order represents a trading order
 type Order struct {
 	Asset    int    `json:"a"`          // asset ID
@@ -64,18 +87,175 @@ type Order struct {
 	Price    string `json:"p"`          // price
 	Size     string `json:"s"`          // size
 	ReduceOnly bool `json:"r"`          // reduce only
-	OrderType struct {
-		Limit struct {
-			TIF string `json:"tif"`    // time in force
-		} `json:"limit"`
-	} `json:"t"`
+	OrderType OrderType `json:"t"`      // limit or trigger
 	ClientOrderID string `json:"c"`      // client order ID
 }
 
-// Cancel represents an order cancellation
+// UnmarshalJSON decodes an order whose price and size may be encoded as JSON
+// strings or numbers, normalizing numbers to canonical decimal strings
+func (o *Order) UnmarshalJSON(data []byte) error {
+	type orderAlias Order
+	aux := struct {
+		*orderAlias
+		Price json.RawMessage `json:"p"`
+		Size  json.RawMessage `json:"s"`
+	}{orderAlias: (*orderAlias)(o)}
+	
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	
+	var err error
+	if o.Price, err = decimalString(aux.Price); err != nil {
+		return fmt.Errorf("invalid order price: %w", err)
+	}
+	if o.Size, err = decimalString(aux.Size); err != nil {
+		return fmt.Errorf("invalid order size: %w", err)
+	}
+	return nil
+}
+
+// decimalString returns a JSON string as is and a JSON number as its shortest
+// decimal string, e.g. 50000.0 -> "50000". Missing or null values are empty.
+func decimalString(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	
+	if raw[0] == '"' {
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+	
+	number, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return "", fmt.Errorf("%s is neither a string nor a number", raw)
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64), nil
+}
+
+// Limit order time in force values
+const (
+	TIFGtc            = "Gtc"            // good til canceled
+	TIFAlo            = "Alo"            // add liquidity only (post-only)
+	TIFIoc            = "Ioc"            // immediate or cancel
+	TIFFrontendMarket = "FrontendMarket" // market order placed from the UI, executes as Ioc
+)
+
+// OrderType is the order kind, exactly one of Limit or Trigger is set
+type OrderType struct {
+	Limit   *LimitOrderType   `json:"limit,omitempty"`
+	Trigger *TriggerOrderType `json:"trigger,omitempty"`
+}
+
+// LimitOrderType holds the settings of a limit order
+type LimitOrderType struct {
+	TIF string `json:"tif"` // time in force
+}
+
+// TriggerOrderType holds the settings of a take profit or stop loss order
+type TriggerOrderType struct {
+	IsMarket  bool        `json:"isMarket"`  // execute as market once triggered
+	TriggerPx json.Number `json:"triggerPx"` // sent as a string or a number
+	Tpsl      string      `json:"tpsl"`      // "tp" or "sl"
+}
+
+// RestsOnBook reports whether an unfilled remainder of the order rests on the
+// book. Trigger orders wait off-book until triggered; Ioc and market orders never rest.
+func (t OrderType) RestsOnBook() bool {
+	if t.Limit == nil {
+		return false
+	}
+	switch t.Limit.TIF {
+	case TIFIoc, TIFFrontendMarket:
+		return false
+	}
+	return true
+}
+
+// Cancel represents an order cancellation, by client order ID for cancelByCloid
+// actions or by order ID for cancel actions
 type Cancel struct {
 	Asset int    `json:"asset"`
 	Cloid string `json:"cloid"`      // client order ID to cancel
+	Oid   int64  `json:"o"`          // order ID to cancel
+}
+
+// UnmarshalJSON decodes both cancel shapes, {"asset":..,"cloid":..} and {"a":..,"o":..}
+func (c *Cancel) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Asset *int   `json:"asset"`
+		A     *int   `json:"a"`
+		Cloid string `json:"cloid"`
+		Oid   int64  `json:"o"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	
+	*c = Cancel{Cloid: raw.Cloid, Oid: raw.Oid}
+	if raw.Asset != nil {
+		c.Asset = *raw.Asset
+	} else if raw.A != nil {
+		c.Asset = *raw.A
+	}
+	return nil
+}
+
+// Modify replaces a resting order with a new one, as carried by batchModify actions
+type Modify struct {
+	Oid   json.RawMessage `json:"oid"`   // order ID, or client order ID as a hex string
+	Order Order           `json:"order"` // the replacement order
+}
+
+// Target returns the order a modify refers to, by order ID or client order ID
+func (m *Modify) Target() (oid int64, cloid string) {
+	if err := json.Unmarshal(m.Oid, &oid); err == nil {
+		return oid, ""
+	}
+	json.Unmarshal(m.Oid, &cloid)
+	return 0, cloid
+}
+
+// Trade eviction policies applied when the global trade cap is exceeded
+const (
+	EvictLeastRecentCoin = "least_recent" // trim the coin whose last trade is oldest
+	EvictLargestCoin     = "largest"      // trim the coin retaining the most trades
+)
+
+// Policies for trades repeating a TID recently seen for the same coin
+const (
+	DuplicateTIDKeep    = "keep"    // store and emit the trade unchanged
+	DuplicateTIDDrop    = "drop"    // drop the trade
+	DuplicateTIDRestamp = "restamp" // give the trade the next unused TID
+)
+
+// Cold start policies deciding where reading begins when the reader starts
+const (
+	ColdStartCatchUp    = "catch_up"     // replay the current date directory from the start
+	ColdStartSkipToLive = "skip_to_live" // read only the tail of the latest file
+)
+
+// LocalNodeOptions tunes the local node reader's retention
+type LocalNodeOptions struct {
+	MaxTotalTrades      int    // cap on trades retained across all coins, 0 means unlimited
+	MaxTradesPerCoin    int    // trades retained per coin, 0 means unlimited
+	MaxBlocksInMemory   int    // recent blocks retained, 0 means unlimited
+	TradeEvictionPolicy string // EvictLeastRecentCoin or EvictLargestCoin
+	AssetMapFile        string // optional static asset ID -> symbol mapping
+	AssetMapOverride    bool   // consult the asset map before the AssetFetcher
+	ColdStartPolicy     string // ColdStartCatchUp or ColdStartSkipToLive
+	ColdStartTailBytes  int64  // bytes of the latest file read on skip_to_live
+	DuplicateTIDPolicy  string        // DuplicateTIDKeep, DuplicateTIDDrop or DuplicateTIDRestamp
+	DuplicateTIDWindow  time.Duration // how long a coin's TIDs are remembered
+	CandleStateFile     string        // open candles saved on stop and restored on start
+	IncludeBuilderFees  bool          // fill in builderFee and feeToken on fills of builder-routed orders
+	CandleVolumeUnit    string        // CandleVolumeBase or CandleVolumeQuote
+	ReadPositionsFile   string        // block file read positions checkpointed here and resumed from on start
+	ReadPositionsInterval time.Duration // how often read positions are checkpointed
+	ReplayFrom          time.Time     // replay block files from this time before tailing live blocks, zero disables
 }
 
 // LocalNodeReader reads data from the local Hyperliquid node
@@ -92,20 +272,63 @@ type LocalNodeReader struct {
 	// File watching
 	lastReadFiles   map[string]int64  // filename -> last read position
 	watchedDirs     []string
+	coldStartDone   bool              // the cold start policy has been applied
 	
 	// Data cache
 	latestBlocks    []*HyperliquidNodeBlock
 	latestTrades    map[string][]*types.WsTrade
+	totalTrades     int
+	trimmedTrades   map[string]bool // coins whose oldest retained trades were evicted
 	latestPrices    map[string]string
+	lastBlockTime   int64 // block time of the most recent block, unix millis
+	firstBlockTime  int64 // block time of the first block read since startup, unix millis
+	lastRound       int64 // highest block round processed
+	rereadRounds    map[string]int64 // shrunk file -> last round processed before it is re-read
+	orderBooks      map[string]*orderBook    // coin -> resting orders by price level
+	restingOrders   map[string]*restingOrder // asset:cloid -> resting order
+	restingOids     map[int64]*restingOrder  // oid -> resting order, known when the block carries responses
+	openCandles     map[candleKey]*types.Candle
+	candleLastTrade map[candleKey]int64 // time of the last trade folded into each open candle
+	restoredCandles map[candleKey]bool  // open candles loaded from the candle state file
+	partialCandles  map[candleKey]bool  // open candles missing trades from before the first block read
+	rawDecimals     map[string]bool     // coins stored before the AssetFetcher had their decimals
+	perpPrices      map[string]perpPrices // coin -> oracle and mark prices last set by validators
+	userFills       map[string][]types.WsFill // lowercased user address -> fills
+	recentTIDs      map[string]map[int64]int64 // coin -> TID -> trade time, for duplicate detection
+	closedCandles   chan *types.Candle
 	dataMu          sync.RWMutex
 	
 	// Asset fetcher for dynamic asset metadata
 	assetFetcher    *AssetFetcher
+	
+	options         LocalNodeOptions
+	assetMap        *AssetMap
+	
+	// Replay of historical block files at startup
+	replay          ReplayProgress
+	replayMu        sync.RWMutex
+	
+	// Asset IDs looked up in the AssetFetcher and how many it didn't know
+	assetLookups    int64
+	assetMisses     int64
+	
+	// Bumped whenever a block may have changed the data served to clients
+	dataVersion     int64
+	onUpdate        func() // called after each bump of dataVersion
+	
+	// Ensures an unexpected bundle or resps shape is only logged once
+	bundleShapeOnce sync.Once
+	respsShapeOnce  sync.Once
+	
+	// Last permission error hit while scanning the data path, nil once readable
+	accessErr       error
+	accessMu        sync.RWMutex
+	scanDenied      bool // set by checkAccess during the current scan
 }
 
 // NewLocalNodeReader creates a new local node reader
-func NewLocalNodeReader(dataPath string, assetFetcher *AssetFetcher) *LocalNodeReader {
-	return &LocalNodeReader{
+func NewLocalNodeReader(dataPath string, assetFetcher *AssetFetcher, options LocalNodeOptions) *LocalNodeReader {
+	r := &LocalNodeReader{
 		dataPath:      dataPath,
 		blocksChan:    make(chan *HyperliquidNodeBlock, 1000),
 		tradesChan:    make(chan []byte, 1000),
@@ -113,9 +336,47 @@ func NewLocalNodeReader(dataPath string, assetFetcher *AssetFetcher) *LocalNodeR
 		lastReadFiles: make(map[string]int64),
 		latestBlocks:  make([]*HyperliquidNodeBlock, 0),
 		latestTrades:  make(map[string][]*types.WsTrade),
+		trimmedTrades: make(map[string]bool),
 		latestPrices:  make(map[string]string),
+		orderBooks:    make(map[string]*orderBook),
+		restingOrders: make(map[string]*restingOrder),
+		restingOids:   make(map[int64]*restingOrder),
+		openCandles:   make(map[candleKey]*types.Candle),
+		candleLastTrade: make(map[candleKey]int64),
+		restoredCandles: make(map[candleKey]bool),
+		partialCandles:  make(map[candleKey]bool),
+		rawDecimals:     make(map[string]bool),
+		rereadRounds:    make(map[string]int64),
+		perpPrices:      make(map[string]perpPrices),
+		userFills:     make(map[string][]types.WsFill),
+		recentTIDs:    make(map[string]map[int64]int64),
+		closedCandles: make(chan *types.Candle, 10000),
 		assetFetcher:  assetFetcher,
+		options:       options,
+	}
+	
+	if options.AssetMapFile != "" {
+		r.assetMap = NewAssetMap(options.AssetMapFile)
+		if err := r.assetMap.Load(); err != nil {
+			logrus.WithError(err).Warn("Asset map file not loaded, continuing without it")
+		}
+	}
+	
+	if options.CandleStateFile != "" {
+		if err := r.loadCandleState(); err != nil {
+			logrus.WithError(err).Warn("Candle state not restored, open candles start over")
+		}
+	}
+	
+	return r
+}
+
+// ReloadAssetMap reloads the asset map file, if configured
+func (r *LocalNodeReader) ReloadAssetMap() error {
+	if r.assetMap == nil {
+		return nil
 	}
+	return r.assetMap.Load()
 }
 
 // Start starts the local node reader
@@ -128,8 +389,29 @@ func (r *LocalNodeReader) Start() {
 	
 	// AssetFetcher is expected to be already initialized and started by the caller
 	
-	// Start file watchers
-	go r.watchReplicaCmdsDirectory()
+	// Resume reading where the previous run stopped
+	if r.options.ReadPositionsFile != "" {
+		if err := r.loadReadPositions(); err != nil {
+			logrus.WithError(err).Warn("Read positions not restored, applying the cold start policy")
+		}
+		if r.options.ReadPositionsInterval > 0 {
+			go r.runReadPositionsCheckpoint(r.options.ReadPositionsInterval)
+		}
+	}
+	
+	// Start file watchers, after replaying history if requested
+	if !r.options.ReplayFrom.IsZero() {
+		r.replayMu.Lock()
+		r.replay = ReplayProgress{Active: true, From: r.options.ReplayFrom.UnixMilli()}
+		r.replayMu.Unlock()
+		
+		go func() {
+			r.replayHistory()
+			r.watchReplicaCmdsDirectory()
+		}()
+	} else {
+		go r.watchReplicaCmdsDirectory()
+	}
 	go r.processBlocks()
 	
 	logrus.Info("Local node reader started")
@@ -141,6 +423,13 @@ func (r *LocalNodeReader) Stop() {
 	r.isRunning = false
 	r.mu.Unlock()
 	
+	if err := r.SaveCandleState(); err != nil {
+		logrus.WithError(err).Error("Failed to save open candles")
+	}
+	if err := r.SaveReadPositions(); err != nil {
+		logrus.WithError(err).Error("Failed to save block file read positions")
+	}
+	
 	logrus.Info("Local node reader stopped")
 }
 
@@ -153,41 +442,193 @@ func (r *LocalNodeReader) IsRunning() bool {
 
 
 
-// getAssetSymbol returns the symbol for an asset ID using the AssetFetcher
+// getAssetSymbol returns the symbol for an asset ID using the AssetFetcher.
+// The asset ID as found in the action decides the market: IDs at or above
+// spotAssetIDOffset target spot pair (ID - 10000), anything below targets the
+// perpetual with that index. Lookups never fall back across markets, so an
+// unknown perp is not mislabeled as the spot pair sharing its index. The
+// optional asset map file is consulted before the @N/ASSET_N fallbacks.
 func (r *LocalNodeReader) getAssetSymbol(assetID int) string {
-	if r.assetFetcher == nil {
-		logrus.WithField("asset_id", assetID).Warn("AssetFetcher not initialized")
-		return "ASSET_" + strconv.Itoa(assetID)
+	isSpot := assetID >= spotAssetIDOffset
+	
+	// The asset map file takes precedence when configured as an override
+	if r.assetMap != nil && r.options.AssetMapOverride {
+		if symbol, exists := r.assetMap.Lookup(assetID); exists {
+			return symbol
+		}
 	}
 	
-	// First try direct asset ID lookup (for perpetuals)
-	if asset, exists := r.assetFetcher.GetAssetByID(assetID); exists {
-		return asset.Name
+	if r.assetFetcher != nil {
+		atomic.AddInt64(&r.assetLookups, 1)
 	}
 	
-	// Then try spot asset lookup (spot assets use 10000 + index)
-	if asset, exists := r.assetFetcher.GetAssetByID(10000 + assetID); exists {
+	if r.assetFetcher == nil {
+		logrus.WithField("asset_id", assetID).Warn("AssetFetcher not initialized")
+	} else if isSpot {
+		if asset, exists := r.assetFetcher.GetSpotAsset(assetID - spotAssetIDOffset); exists {
+			return asset.Name
+		}
+	} else if asset, exists := r.assetFetcher.GetPerpAsset(assetID); exists {
 		return asset.Name
 	}
 	
-	// For spot assets that don't have names in the fetcher, use @X format
-	// This matches Hyperliquid's convention for spot assets
-	if assetID > 0 && assetID < 1000 { // Reasonable range for spot asset indices
-		spotName := fmt.Sprintf("@%d", assetID)
-		logrus.WithFields(logrus.Fields{
-			"asset_id": assetID,
-			"spot_name": spotName,
-		}).Debug("Using spot asset name format")
-		return spotName
+	// Otherwise it fills in assets the fetcher doesn't know
+	if r.assetMap != nil {
+		if symbol, exists := r.assetMap.Lookup(assetID); exists {
+			return symbol
+		}
+	}
+	
+	if r.assetFetcher != nil {
+		atomic.AddInt64(&r.assetMisses, 1)
 	}
 	
-	// Return asset ID as string if not found
-	logrus.WithField("asset_id", assetID).Debug("Asset not found in fetcher, using fallback name")
+	logrus.WithFields(logrus.Fields{
+		"asset_id": assetID,
+		"is_spot":  isSpot,
+	}).Debug("Asset not found in fetcher, using fallback name")
+	return r.fallbackAssetSymbol(assetID, isSpot)
+}
+
+// DataVersion returns a counter that changes whenever processed blocks may have
+// changed prices, trades, books, fills or candles, so consumers can skip work
+// while only empty blocks arrive
+func (r *LocalNodeReader) DataVersion() int64 {
+	return atomic.LoadInt64(&r.dataVersion)
+}
+
+// SetOnUpdate sets a callback invoked whenever DataVersion changes, for
+// consumers that would rather be notified than poll. It runs on the reader's
+// goroutine and must not block.
+func (r *LocalNodeReader) SetOnUpdate(onUpdate func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onUpdate = onUpdate
+}
+
+// bumpDataVersion marks the data served to clients as changed
+func (r *LocalNodeReader) bumpDataVersion() {
+	atomic.AddInt64(&r.dataVersion, 1)
+	
+	r.mu.RLock()
+	onUpdate := r.onUpdate
+	r.mu.RUnlock()
+	
+	if onUpdate != nil {
+		onUpdate()
+	}
+}
+
+// GetAssetResolution returns how many asset IDs were looked up in the
+// AssetFetcher and how many of them were unknown to it
+func (r *LocalNodeReader) GetAssetResolution() (lookups, misses int64) {
+	return atomic.LoadInt64(&r.assetLookups), atomic.LoadInt64(&r.assetMisses)
+}
+
+// fallbackAssetSymbol names an asset missing from the metadata, using
+// Hyperliquid's @N convention for spot pairs
+func (r *LocalNodeReader) fallbackAssetSymbol(assetID int, isSpot bool) string {
+	if isSpot {
+		return "@" + strconv.Itoa(assetID-spotAssetIDOffset)
+	}
 	return "ASSET_" + strconv.Itoa(assetID)
 }
 
-// watchReplicaCmdsDirectory watches the replica_cmds directory for new files
+// watchReplicaCmdsDirectory watches the replica_cmds directory for new files,
+// reading new blocks as soon as the node writes them. Falls back to polling
+// when a file watcher can't be created.
 func (r *LocalNodeReader) watchReplicaCmdsDirectory() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create file watcher, polling replica_cmds every second")
+		r.pollReplicaCmdsDirectory()
+		return
+	}
+	defer watcher.Close()
+	
+	// Also checks for shutdown, and retries the watch while replica_cmds isn't there yet
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	
+	datePath := r.rewatchReplicaCmds(watcher, "")
+	for {
+		select {
+		case <-ticker.C:
+			if !r.IsRunning() {
+				return
+			}
+			if datePath == "" {
+				datePath = r.rewatchReplicaCmds(watcher, datePath)
+			}
+			
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			
+			if filepath.Dir(event.Name) == datePath {
+				if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+					r.readBlockFile(event.Name, r.lastReadFiles[event.Name])
+				}
+			} else if event.Has(fsnotify.Create) {
+				// A new timestamp or date directory: the node rotated, follow it
+				datePath = r.rewatchReplicaCmds(watcher, datePath)
+			}
+			
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost (e.g. queue overflow), catch up with a full scan
+			logrus.WithError(err).Warn("File watcher error, rescanning replica_cmds")
+			datePath = r.rewatchReplicaCmds(watcher, datePath)
+		}
+	}
+}
+
+// rewatchReplicaCmds reads any new data and points the watcher at the most recent
+// date directory, along with replica_cmds and the most recent timestamp directory
+// so rotations are noticed even before the new date directory exists. Returns the
+// watched date directory, empty if there is none yet or the watch failed.
+func (r *LocalNodeReader) rewatchReplicaCmds(watcher *fsnotify.Watcher, current string) string {
+	datePath := r.scanReplicaCmdsDirectory()
+	if datePath != "" && datePath == current {
+		return datePath
+	}
+	
+	replicaCmdsPath := filepath.Join(r.dataPath, "replica_cmds")
+	paths := []string{replicaCmdsPath}
+	if timestampDir, err := r.getMostRecentDirectory(replicaCmdsPath); err == nil && timestampDir != "" {
+		paths = append(paths, filepath.Join(replicaCmdsPath, timestampDir))
+	}
+	if datePath != "" {
+		paths = append(paths, datePath)
+	}
+	
+	for _, path := range watcher.WatchList() {
+		watcher.Remove(path)
+	}
+	for _, path := range paths {
+		if err := watcher.Add(path); err != nil {
+			if !r.checkAccess(err) && !os.IsNotExist(err) {
+				logrus.WithError(err).WithField("path", path).Warn("Failed to watch local node directory, retrying")
+			}
+			return ""
+		}
+	}
+	if datePath == "" {
+		return ""
+	}
+	
+	// Pick up anything written between the scan and the watch
+	r.scanBlockFiles(datePath)
+	
+	logrus.WithField("path", datePath).Info("Watching local node block directory")
+	return datePath
+}
+
+// pollReplicaCmdsDirectory scans the replica_cmds directory every second
+func (r *LocalNodeReader) pollReplicaCmdsDirectory() {
 	ticker := time.NewTicker(1 * time.Second) // Check every second
 	defer ticker.Stop()
 	
@@ -203,39 +644,92 @@ func (r *LocalNodeReader) watchReplicaCmdsDirectory() {
 	}
 }
 
-// scanReplicaCmdsDirectory scans the replica_cmds directory for new files
-func (r *LocalNodeReader) scanReplicaCmdsDirectory() {
+// scanReplicaCmdsDirectory scans the replica_cmds directory for new files,
+// returning the date directory it read, empty if none was found
+func (r *LocalNodeReader) scanReplicaCmdsDirectory() string {
+	// Permission errors hit during this scan are recorded by checkAccess
+	r.scanDenied = false
+	defer r.clearAccessError()
+	
 	// Look for replica_cmds directory
 	replicaCmdsPath := filepath.Join(r.dataPath, "replica_cmds")
 	
 	if _, err := os.Stat(replicaCmdsPath); os.IsNotExist(err) {
 		logrus.WithField("path", replicaCmdsPath).Debug("replica_cmds directory not found")
-		return
+		return ""
 	}
 	
 	// Get the most recent timestamp directory
-	recentTimestampDir := r.getMostRecentDirectory(replicaCmdsPath)
-	if recentTimestampDir == "" {
-		return
+	recentTimestampDir, err := r.getMostRecentDirectory(replicaCmdsPath)
+	if r.checkAccess(err) || recentTimestampDir == "" {
+		return ""
 	}
 	
 	timestampPath := filepath.Join(replicaCmdsPath, recentTimestampDir)
 	
 	// Get the most recent date directory within the timestamp
-	recentDateDir := r.getMostRecentDirectory(timestampPath)
-	if recentDateDir == "" {
-		return
+	recentDateDir, err := r.getMostRecentDirectory(timestampPath)
+	if r.checkAccess(err) || recentDateDir == "" {
+		return ""
 	}
 	
 	datePath := filepath.Join(timestampPath, recentDateDir)
 	
 	// Get all files in the date directory
 	r.scanBlockFiles(datePath)
+	return datePath
+}
+
+// checkAccess reports whether err is a permission error, recording it so health
+// checks see the data path as unreadable. The error is logged when it first appears.
+func (r *LocalNodeReader) checkAccess(err error) bool {
+	if err == nil || !os.IsPermission(err) {
+		return false
+	}
+	r.scanDenied = true
+	
+	r.accessMu.Lock()
+	first := r.accessErr == nil
+	r.accessErr = err
+	r.accessMu.Unlock()
+	
+	if first {
+		logrus.WithError(err).WithField("data_path", r.dataPath).Error("Permission denied reading local node data: the proxy cannot see new blocks until it can read replica_cmds. Run the proxy as the node's user, or grant read access (e.g. add the proxy user to the node's group and chmod -R g+rX the data directory)")
+	}
+	return true
+}
+
+// clearAccessError forgets a recorded permission error once a full scan got
+// through without one, so fixing permissions needs no restart
+func (r *LocalNodeReader) clearAccessError() {
+	if r.scanDenied {
+		return
+	}
+	
+	r.accessMu.Lock()
+	hadErr := r.accessErr != nil
+	r.accessErr = nil
+	r.accessMu.Unlock()
+	
+	if hadErr {
+		logrus.WithField("data_path", r.dataPath).Info("Local node data is readable again")
+	}
+}
+
+// GetAccessError returns the permission error currently preventing the data path
+// from being read, or nil
+func (r *LocalNodeReader) GetAccessError() error {
+	r.accessMu.RLock()
+	defer r.accessMu.RUnlock()
+	return r.accessErr
 }
 
 // scanBlockFiles scans for block files and reads new data
 func (r *LocalNodeReader) scanBlockFiles(dirPath string) {
 	entries, err := os.ReadDir(dirPath)
+	if r.checkAccess(err) {
+		return
+	}
 	if err != nil {
 		logrus.WithError(err).Debug("Failed to read directory")
 		return
@@ -250,6 +744,11 @@ func (r *LocalNodeReader) scanBlockFiles(dirPath string) {
 	}
 	sort.Strings(fileNames)
 	
+	if !r.coldStartDone && len(fileNames) > 0 {
+		r.applyColdStartPolicy(dirPath, fileNames)
+		r.coldStartDone = true
+	}
+	
 	// Process files in order
 	for _, fileName := range fileNames {
 		filePath := filepath.Join(dirPath, fileName)
@@ -260,21 +759,147 @@ func (r *LocalNodeReader) scanBlockFiles(dirPath string) {
 			continue
 		}
 		
+		// A file smaller than the read position was truncated or replaced and is re-read
 		lastReadPos, exists := r.lastReadFiles[filePath]
-		if !exists || stat.Size() > lastReadPos {
+		if !exists || stat.Size() != lastReadPos {
 			r.readBlockFile(filePath, lastReadPos)
 		}
 	}
 }
 
+// applyColdStartPolicy positions the reader on the first scan. With skip_to_live,
+// every file but the latest is marked as read and reading of the latest starts at
+// the first block within its last ColdStartTailBytes, so a long backlog neither
+// floods clients with stale trades nor spikes memory. Order books then only hold
+// orders placed since that point.
+func (r *LocalNodeReader) applyColdStartPolicy(dirPath string, fileNames []string) {
+	if r.options.ColdStartPolicy != ColdStartSkipToLive {
+		return
+	}
+	
+	skippedBytes := int64(0)
+	for _, fileName := range fileNames[:len(fileNames)-1] {
+		filePath := filepath.Join(dirPath, fileName)
+		if stat, err := os.Stat(filePath); err == nil {
+			r.setReadPosition(filePath, stat.Size())
+			skippedBytes += stat.Size()
+		}
+	}
+	
+	latestPath := filepath.Join(dirPath, fileNames[len(fileNames)-1])
+	start, err := r.findTailStart(latestPath, r.options.ColdStartTailBytes)
+	if err != nil {
+		logrus.WithError(err).WithField("file", latestPath).Warn("Failed to locate tail of latest block file, reading it from the start")
+		start = 0
+	}
+	r.setReadPosition(latestPath, start)
+	skippedBytes += start
+	
+	logrus.WithFields(logrus.Fields{
+		"skipped_files": len(fileNames) - 1,
+		"skipped_bytes": skippedBytes,
+		"file":          latestPath,
+		"start_pos":     start,
+	}).Info("Cold start: skipping block backlog to live data")
+}
+
+// findTailStart returns the offset of the first complete line within the last
+// tailBytes of a file, or 0 when the whole file fits in the tail
+func (r *LocalNodeReader) findTailStart(filePath string, tailBytes int64) (int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if tailBytes <= 0 || stat.Size() <= tailBytes {
+		return 0, nil
+	}
+	
+	// Scan forward from the cut point to the end of the partial line
+	pos := stat.Size() - tailBytes
+	buffer := make([]byte, 64*1024)
+	for pos < stat.Size() {
+		n, err := file.ReadAt(buffer, pos)
+		if i := bytes.IndexByte(buffer[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		if err != nil {
+			break
+		}
+		pos += int64(n)
+	}
+	
+	// No line ends in the tail: only the partial last block remains
+	return stat.Size(), nil
+}
+
+// setReadPosition records how far a block file has been read. Only the file
+// watcher reads positions back, but stats count the files from other goroutines.
+func (r *LocalNodeReader) setReadPosition(filePath string, pos int64) {
+	r.dataMu.Lock()
+	r.lastReadFiles[filePath] = pos
+	r.dataMu.Unlock()
+}
+
+// restartShrunkFile rewinds the read position of a block file that shrank below
+// it, remembering the last round processed so the blocks read again aren't
+// emitted twice. Returns the new read position.
+func (r *LocalNodeReader) restartShrunkFile(filePath string, fromPos, size int64) int64 {
+	r.dataMu.Lock()
+	r.lastReadFiles[filePath] = 0
+	r.rereadRounds[filePath] = r.lastRound
+	lastRound := r.lastRound
+	r.dataMu.Unlock()
+	
+	logrus.WithFields(logrus.Fields{
+		"file":       filePath,
+		"read_pos":   fromPos,
+		"size":       size,
+		"last_round": lastRound,
+	}).Warn("Block file shrank below the read position, re-reading it from the start")
+	return 0
+}
+
+// skipRereadBlock reports whether a block read from a re-read file was already
+// processed, ending the re-read once a newer round shows up
+func (r *LocalNodeReader) skipRereadBlock(filePath string, round int64) bool {
+	r.dataMu.Lock()
+	defer r.dataMu.Unlock()
+	
+	lastRound, rereading := r.rereadRounds[filePath]
+	if !rereading {
+		return false
+	}
+	if round > lastRound {
+		delete(r.rereadRounds, filePath)
+		return false
+	}
+	return true
+}
+
+// GetMonitoredFiles returns how many block files the reader is tracking
+func (r *LocalNodeReader) GetMonitoredFiles() int {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	return len(r.lastReadFiles)
+}
+
 // readBlockFile reads a block file from a given position
 func (r *LocalNodeReader) readBlockFile(filePath string, fromPos int64) {
 	logrus.WithFields(logrus.Fields{
 		"file":     filePath,
 		"from_pos": fromPos,
-	}).Info("NEW VERSION - Reading block file with chunk method")
+	}).Debug("Reading block file")
 	
 	file, err := os.Open(filePath)
+	if r.checkAccess(err) {
+		return
+	}
 	if err != nil {
 		logrus.WithError(err).Error("Failed to open block file")
 		return
@@ -288,7 +913,11 @@ func (r *LocalNodeReader) readBlockFile(filePath string, fromPos int64) {
 		return
 	}
 	
-	// If file is smaller than our last position, reset
+	// A file smaller than our last position was truncated or replaced by the
+	// node: read it again from the start, skipping the blocks already processed
+	if stat.Size() < fromPos {
+		fromPos = r.restartShrunkFile(filePath, fromPos, stat.Size())
+	}
 	if stat.Size() <= fromPos {
 		return
 	}
@@ -315,53 +944,77 @@ func (r *LocalNodeReader) readBlockFile(filePath string, fromPos int64) {
 		return
 	}
 	
-	content := string(buffer[:bytesRead])
-	lines := strings.Split(content, "\n")
+	// Decode the blocks, however many share a line or however many lines one spans
+	readToEnd := fromPos+int64(bytesRead) >= stat.Size()
+	consumed, blocksDecoded := r.decodeBlocks(filePath, buffer[:bytesRead], readToEnd)
+	newPos := fromPos + consumed
 	
-	newPos := fromPos
+	// Update last read position
+	r.setReadPosition(filePath, newPos)
 	
-	// Process each line
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	logrus.WithFields(logrus.Fields{
+		"file":        filePath,
+		"bytes_read":  bytesRead,
+		"blocks_decoded": blocksDecoded,
+		"new_pos":     newPos,
+	}).Debug("Block file read completed")
+}
+
+// decodeBlocks processes the blocks in data, a chunk of a block file, with a
+// streaming decoder so blocks need not be one per line: concatenated or
+// pretty-printed blocks decode the same. A value that fails to parse is skipped
+// up to the next newline. Returns how many bytes were consumed, which excludes a
+// trailing incomplete block unless readToEnd says the node is done with it, and
+// how many blocks were decoded.
+func (r *LocalNodeReader) decodeBlocks(filePath string, data []byte, readToEnd bool) (int64, int) {
+	var consumed int64
+	blocks := 0
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	base := int64(0) // offset in data where decoder starts
+	
+	for {
+		var block HyperliquidNodeBlock
+		err := decoder.Decode(&block)
+		if err == io.EOF {
+			// Only whitespace left
+			return int64(len(data)), blocks
 		}
-		
-		// Update position (except for the last line which might be incomplete)
-		if i < len(lines)-1 {
-			newPos += int64(len(line) + 1) // +1 for newline
+		if err == io.ErrUnexpectedEOF {
+			// The node is still writing this block, read it again once complete
+			return consumed, blocks
 		}
-		
-		// Skip incomplete last line if we didn't read the entire file
-		if i == len(lines)-1 && bytesRead == int(remainingSize) && fromPos+int64(bytesRead) < stat.Size() {
+		if err != nil {
+			// Resume after the line holding the bad value
+			start := consumed
+			for start < int64(len(data)) && isJSONSpace(data[start]) {
+				start++
+			}
+			next := bytes.IndexByte(data[start:], '\n')
+			logrus.WithError(err).WithField("offset", start).Debug("Failed to parse block")
+			if next < 0 {
+				if readToEnd {
+					return int64(len(data)), blocks
+				}
+				return consumed, blocks
+			}
+			base = start + int64(next) + 1
+			consumed = base
+			decoder = json.NewDecoder(bytes.NewReader(data[base:]))
 			continue
 		}
+		consumed = base + decoder.InputOffset()
+		blocks++
 		
-		// Parse the NDJSON line as a block
-		var block HyperliquidNodeBlock
-		if err := json.Unmarshal([]byte(line), &block); err != nil {
-			logrus.WithError(err).WithField("line_length", len(line)).Debug("Failed to parse block line")
-			continue
-		}
-		
-		// Process the block
-		r.processBlock(&block)
-		
-		// Update position for complete lines
-		if i == len(lines)-1 && (bytesRead < int(remainingSize) || fromPos+int64(bytesRead) >= stat.Size()) {
-			newPos += int64(len(line))
+		// Process the block, unless a re-read file already delivered it
+		if !r.skipRereadBlock(filePath, block.ABCIBlock.Round) {
+			r.processBlock(&block)
 		}
 	}
-	
-	// Update last read position
-	r.lastReadFiles[filePath] = newPos
-	
-	logrus.WithFields(logrus.Fields{
-		"file":        filePath,
-		"bytes_read":  bytesRead,
-		"lines_processed": len(lines),
-		"new_pos":     newPos,
-	}).Debug("Block file read completed")
+}
+
+// isJSONSpace reports whether b is whitespace between JSON values
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
 }
 
 // processBlock processes a single block
@@ -372,23 +1025,62 @@ func (r *LocalNodeReader) processBlock(block *HyperliquidNodeBlock) {
 		"bundles_count": len(block.ABCIBlock.SignedActionBundles),
 	}).Debug("Processing block")
 	
-	// Store the block
+	blockTime := r.parseBlockTime(block.ABCIBlock.Time)
+	if r.skipReplayedBlock(blockTime) {
+		return
+	}
+	
 	r.dataMu.Lock()
+	r.lastBlockTime = blockTime
+	if block.ABCIBlock.Round > r.lastRound {
+		r.lastRound = block.ABCIBlock.Round
+	}
+	if r.firstBlockTime == 0 {
+		r.firstBlockTime = r.lastBlockTime
+	}
+	openCandles := len(r.openCandles)
+	r.closeCandlesBefore(r.lastBlockTime)
+	candlesClosed := len(r.openCandles) < openCandles
+	
+	// An empty block only advances time, there is nothing to store or apply
+	if block.isEmpty() {
+		r.dataMu.Unlock()
+		if candlesClosed {
+			r.bumpDataVersion()
+		}
+		logrus.WithField("round", block.ABCIBlock.Round).Debug("Skipping empty block")
+		return
+	}
+	
+	// Store the block
 	r.latestBlocks = append(r.latestBlocks, block)
 	
-	// Keep only last 100 blocks in memory
-	if len(r.latestBlocks) > 100 {
-		r.latestBlocks = r.latestBlocks[len(r.latestBlocks)-100:]
+	// Keep only the most recent blocks in memory
+	if maxBlocks := r.options.MaxBlocksInMemory; maxBlocks > 0 && len(r.latestBlocks) > maxBlocks {
+		r.latestBlocks = r.latestBlocks[len(r.latestBlocks)-maxBlocks:]
 	}
 	r.dataMu.Unlock()
 	
+	// Execution results, aligned by index with the bundles
+	responses, err := decodeBlockResponses(block.Resps)
+	if err != nil {
+		r.respsShapeOnce.Do(func() {
+			logrus.WithError(err).Warn("Unexpected block resps shape, fills cannot be extracted from such blocks")
+		})
+	}
+	
 	// Process each signed action bundle
 	bundleProcessed := 0
-	for i, bundleInterface := range block.ABCIBlock.SignedActionBundles {
+	for i, rawBundle := range block.ABCIBlock.SignedActionBundles {
 		logrus.WithField("bundle_index", i).Debug("Processing signed action bundle")
-		r.processSignedActionBundle(bundleInterface, block.ABCIBlock.Time)
+		var bundleResponses []ActionResponse
+		if i < len(responses) {
+			bundleResponses = responses[i]
+		}
+		r.processSignedActionBundle(rawBundle, bundleResponses, block.ABCIBlock.Time)
 		bundleProcessed++
 	}
+	r.bumpDataVersion()
 	
 	logrus.WithFields(logrus.Fields{
 		"round": block.ABCIBlock.Round,
@@ -404,32 +1096,12 @@ func (r *LocalNodeReader) processBlock(block *HyperliquidNodeBlock) {
 }
 
 // processSignedActionBundle processes a signed action bundle
-func (r *LocalNodeReader) processSignedActionBundle(bundleInterface interface{}, blockTime string) {
-	// SignedActionBundles are arrays of [hash, bundle_data]
-	bundleArray, ok := bundleInterface.([]interface{})
-	if !ok {
-		logrus.WithField("type", fmt.Sprintf("%T", bundleInterface)).Debug("Bundle is not an array")
-		return
-	}
-	
-	if len(bundleArray) < 2 {
-		logrus.WithField("length", len(bundleArray)).Debug("Bundle array too short")
-		return
-	}
-	
-	// Extract the bundle data (second element)
-	bundleDataInterface := bundleArray[1]
-	bundleDataBytes, err := json.Marshal(bundleDataInterface)
+func (r *LocalNodeReader) processSignedActionBundle(rawBundle json.RawMessage, responses []ActionResponse, blockTime string) {
+	bundle, err := r.decodeSignedActionBundle(rawBundle)
 	if err != nil {
-		logrus.WithError(err).Debug("Failed to marshal bundle data")
-		return
-	}
-	
-	logrus.WithField("bundle_data_size", len(bundleDataBytes)).Debug("Marshaled bundle data")
-	
-	var bundle SignedActionBundle
-	if err := json.Unmarshal(bundleDataBytes, &bundle); err != nil {
-		logrus.WithError(err).WithField("bundle_json", string(bundleDataBytes[:min(200, len(bundleDataBytes))])).Debug("Failed to unmarshal signed action bundle")
+		r.bundleShapeOnce.Do(func() {
+			logrus.WithError(err).WithField("bundle_json", string(rawBundle[:min(200, len(rawBundle))])).Warn("Unexpected signed action bundle shape, skipping such bundles")
+		})
 		return
 	}
 	
@@ -444,10 +1116,51 @@ func (r *LocalNodeReader) processSignedActionBundle(bundleInterface interface{},
 			"action_index": i,
 			"action_type": signedAction.Action.Type,
 		}).Debug("Processing signed action")
-		r.processSignedAction(&signedAction, blockTime)
+		var response *ActionResponse
+		if i < len(responses) {
+			response = &responses[i]
+		}
+		r.processSignedAction(&signedAction, response, blockTime)
 	}
 }
 
+// decodeSignedActionBundle decodes a bundle in either the [hash, data] array
+// form or a plain object form, dispatching on the leading JSON token
+func (r *LocalNodeReader) decodeSignedActionBundle(rawBundle json.RawMessage) (*SignedActionBundle, error) {
+	trimmed := bytes.TrimLeft(rawBundle, " \t\r\n")
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty bundle")
+	}
+	
+	var bundle SignedActionBundle
+	switch trimmed[0] {
+	case '[':
+		var parts []json.RawMessage
+		if err := json.Unmarshal(trimmed, &parts); err != nil {
+			return nil, fmt.Errorf("failed to decode bundle array: %w", err)
+		}
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("bundle array too short: %d elements", len(parts))
+		}
+		if err := json.Unmarshal(parts[1], &bundle); err != nil {
+			return nil, fmt.Errorf("failed to decode bundle data: %w", err)
+		}
+		// The first element carries the bundle hash
+		var hash string
+		if err := json.Unmarshal(parts[0], &hash); err == nil && bundle.Hash == "" {
+			bundle.Hash = hash
+		}
+	case '{':
+		if err := json.Unmarshal(trimmed, &bundle); err != nil {
+			return nil, fmt.Errorf("failed to decode bundle object: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported bundle JSON token %q", trimmed[0])
+	}
+	
+	return &bundle, nil
+}
+
 // Helper function
 func min(a, b int) int {
 	if a < b {
@@ -456,13 +1169,30 @@ func min(a, b int) int {
 	return b
 }
 
-// processSignedAction processes a single signed action
-func (r *LocalNodeReader) processSignedAction(action *SignedAction, blockTime string) {
+// processSignedAction processes a single signed action with its execution result, if known
+func (r *LocalNodeReader) processSignedAction(action *SignedAction, response *ActionResponse, blockTime string) {
+	user := action.VaultAddress
+	if response != nil && user == "" {
+		user = response.User
+	}
+	
 	switch action.Action.Type {
 	case "order":
-		r.processOrders(action.Action.Orders, blockTime, action.VaultAddress)
-	case "cancelByCloid":
-		r.processCancellations(action.Action.Cancels, blockTime, action.VaultAddress)
+		statuses, ok := orderStatuses(response)
+		if !ok {
+			return
+		}
+		r.processOrders(action.Action.Orders, action.Action.Builder, statuses, blockTime, user)
+	case "cancel", "cancelByCloid":
+		r.processCancellations(action.Action.Cancels, blockTime, user)
+	case "batchModify":
+		statuses, ok := orderStatuses(response)
+		if !ok {
+			return
+		}
+		r.processModifies(action.Action.Modifies, statuses, blockTime, user)
+	case "SetGlobalAction":
+		r.processGlobalPrices(action.Action.Pxs)
 	case "scheduleCancel":
 		// Handle scheduled cancellations
 		logrus.Debug("Scheduled cancel action")
@@ -473,101 +1203,432 @@ func (r *LocalNodeReader) processSignedAction(action *SignedAction, blockTime st
 	}
 }
 
-// processOrders processes order actions and generates trade-like data
-func (r *LocalNodeReader) processOrders(orders []Order, blockTime string, userAddress string) {
+// orderStatuses returns the per-order statuses of an order or batchModify
+// response, nil without a response. ok is false when the action was rejected as
+// a whole, so its orders neither fill nor rest.
+func orderStatuses(response *ActionResponse) (statuses []OrderStatus, ok bool) {
+	if response == nil {
+		return nil, true
+	}
+	if response.Res.Status != "ok" {
+		return nil, false
+	}
+	return response.Res.Response.Data.Statuses, true
+}
+
+// processOrders processes order actions. Only orders the block's responses report
+// as filled are recorded as trades, taking the resting orders they traded against
+// off the book; orders reported as resting go on the book. When the block carries
+// no responses, orders are assumed to rest unless they cross the book, and no
+// trades are recorded.
+// builder is the builder the orders were routed through, nil if none.
+func (r *LocalNodeReader) processOrders(orders []Order, builder *Builder, statuses []OrderStatus, blockTime string, userAddress string) {
 	if len(orders) == 0 {
 		logrus.Debug("No orders to process")
 		return
 	}
 	
-	logrus.WithField("orders_count", len(orders)).Debug("Processing orders")
+	logrus.WithFields(logrus.Fields{
+		"orders_count":   len(orders),
+		"statuses_count": len(statuses),
+	}).Debug("Processing orders")
 	
-	ordersProcessed := 0
-	for _, order := range orders {
+	fills := 0
+	for i, order := range orders {
 		symbol := r.getAssetSymbol(order.Asset)
 		
-		// Log asset mapping for debugging
-		logrus.WithFields(logrus.Fields{
-			"asset_id": order.Asset,
-			"symbol": symbol,
-			"price": order.Price,
-			"size": order.Size,
-		}).Debug("Processing order - asset mapping")
+		// Store prices and sizes as the official API formats them
+		order.Price = r.normalizePrice(symbol, order.Price)
+		order.Size = r.normalizeSize(symbol, order.Size)
+		_, normalized := r.decimalsMetadata(symbol)
 		
-		// Skip if we couldn't map the asset
-		if strings.HasPrefix(symbol, "ASSET_") {
-			logrus.WithFields(logrus.Fields{
-				"asset_id": order.Asset,
-				"symbol": symbol,
-			}).Debug("Unknown asset ID, using fallback name")
+		var status *OrderStatus
+		if i < len(statuses) {
+			status = &statuses[i]
 		}
 		
-		// Convert to WsTrade format for compatibility
-		trade := &types.WsTrade{
-			Coin: symbol,
-			Side: "buy",
-			Px:   order.Price,
-			Sz:   order.Size,
-			Time: r.parseBlockTime(blockTime),
-			Hash: order.ClientOrderID,
-			TID:  time.Now().UnixNano(), // Generate a TID
-			Users: [2]string{userAddress, ""}, // User placing the order
+		r.dataMu.Lock()
+		if !normalized {
+			r.rawDecimals[symbol] = true
 		}
-		
-		if !order.IsBuy {
-			trade.Side = "sell"
+		switch {
+		case status == nil:
+			// An order crossing the book would have traded rather than rested
+			if !r.crossesBook(symbol, &order) {
+				r.addRestingOrder(symbol, &order, 0)
+			}
+		case status.Filled != nil:
+			r.takeRestingOrders(symbol, &order, status.Filled.TotalSz)
+			trade := &types.WsTrade{
+				Coin:  symbol,
+				Side:  "buy",
+				Px:    r.normalizePrice(symbol, status.Filled.AvgPx),
+				Sz:    r.normalizeSize(symbol, status.Filled.TotalSz),
+				Time:  r.parseBlockTime(blockTime),
+				Hash:  order.ClientOrderID,
+				TID:   status.Filled.Oid,
+				Users: [2]string{userAddress, ""}, // Taker of the fill
+			}
+			if !order.IsBuy {
+				trade.Side = "sell"
+			}
+			// Only traded prices move the latest price, never resting or rejected orders
+			r.latestPrices[symbol] = trade.Px
+			r.storeTrade(symbol, trade)
+			fill := types.WsFill{
+				Coin:    symbol,
+				Px:      trade.Px,
+				Sz:      trade.Sz,
+				Side:    fillSide(order.IsBuy),
+				Time:    trade.Time,
+				Hash:    trade.Hash,
+				OID:     status.Filled.Oid,
+				Crossed: true, // the order filled on submission, so it took liquidity
+				TID:     trade.TID,
+			}
+			r.applyBuilderFee(&fill, order.Asset, builder)
+			r.storeUserFill(userAddress, fill)
+			fills++
+		case status.Resting != nil:
+			// Whatever the order could have traded against is gone from the book
+			r.takeRestingOrders(symbol, &order, "")
+			r.addRestingOrder(symbol, &order, status.Resting.Oid)
 		}
+		r.dataMu.Unlock()
 		
-		// Store the trade
-		r.dataMu.Lock()
-		if r.latestTrades[symbol] == nil {
-			r.latestTrades[symbol] = make([]*types.WsTrade, 0)
+		logrus.WithFields(logrus.Fields{
+			"symbol":   symbol,
+			"asset_id": order.Asset,
+			"is_buy":   order.IsBuy,
+			"price":    order.Price,
+			"size":     order.Size,
+			"user":     userAddress,
+			"filled":   status != nil && status.Filled != nil,
+		}).Debug("Processed order")
+	}
+	
+	logrus.WithField("fills", fills).Debug("Completed processing orders")
+}
+
+// applyBuilderFee sets the builder fee and fee token of a fill when builder fees
+// are enabled and the order was routed through a builder charging a fee
+func (r *LocalNodeReader) applyBuilderFee(fill *types.WsFill, assetID int, builder *Builder) {
+	if !r.options.IncludeBuilderFees || builder == nil || builder.Fee <= 0 {
+		return
+	}
+	
+	fee, ok := computeBuilderFee(fill.Px, fill.Sz, builder.Fee)
+	if !ok {
+		return
+	}
+	fill.BuilderFee = &fee
+	fill.FeeToken = feeToken(assetID, fill.Coin)
+}
+
+// feeToken returns the token fees are charged in: the quote token of a spot
+// pair named BASE/QUOTE, USDC otherwise
+func feeToken(assetID int, symbol string) string {
+	if assetID >= spotAssetIDOffset {
+		if i := strings.LastIndexByte(symbol, '/'); i >= 0 {
+			return symbol[i+1:]
 		}
+	}
+	return "USDC"
+}
+
+// fillSide returns the API side code of a fill, "B" for buys and "A" for sells
+func fillSide(isBuy bool) string {
+	if isBuy {
+		return "B"
+	}
+	return "A"
+}
+
+// storeTrade appends a trade to a coin's history and enforces the per-coin and
+// global retention caps. Caller must hold dataMu.
+func (r *LocalNodeReader) storeTrade(symbol string, trade *types.WsTrade) {
+	if !r.checkTradeTID(symbol, trade) {
+		return
+	}
+	
+	r.latestTrades[symbol] = append(r.latestTrades[symbol], trade)
+	r.totalTrades++
+	r.updateCandles(trade)
+	
+	// Keep only the most recent trades per symbol
+	if excess := len(r.latestTrades[symbol]) - r.options.MaxTradesPerCoin; r.options.MaxTradesPerCoin > 0 && excess > 0 {
+		r.latestTrades[symbol] = r.latestTrades[symbol][excess:]
+		r.totalTrades -= excess
+		r.trimmedTrades[symbol] = true
+	}
+	
+	r.enforceTradeCap()
+}
+
+// checkTradeTID applies the duplicate TID policy to a trade, returning false if
+// the trade must be dropped. Caller must hold dataMu.
+func (r *LocalNodeReader) checkTradeTID(symbol string, trade *types.WsTrade) bool {
+	policy := r.options.DuplicateTIDPolicy
+	if policy == "" || policy == DuplicateTIDKeep {
+		return true
+	}
+	
+	tids, exists := r.recentTIDs[symbol]
+	if !exists {
+		tids = make(map[int64]int64)
+		r.recentTIDs[symbol] = tids
+	}
+	
+	// Forget TIDs that fell out of the window
+	cutoff := trade.Time - r.options.DuplicateTIDWindow.Milliseconds()
+	for tid, seen := range tids {
+		if seen < cutoff {
+			delete(tids, tid)
+		}
+	}
+	
+	if _, duplicate := tids[trade.TID]; duplicate {
+		logrus.WithFields(logrus.Fields{
+			"coin":   symbol,
+			"tid":    trade.TID,
+			"policy": policy,
+		}).Debug("Trade with duplicate TID")
 		
-		r.latestTrades[symbol] = append(r.latestTrades[symbol], trade)
-		
-		// Keep only last 1000 trades per symbol
-		if len(r.latestTrades[symbol]) > 1000 {
-			r.latestTrades[symbol] = r.latestTrades[symbol][len(r.latestTrades[symbol])-1000:]
+		if policy == DuplicateTIDDrop {
+			return false
+		}
+		for {
+			trade.TID++
+			if _, taken := tids[trade.TID]; !taken {
+				break
+			}
+		}
+	}
+	
+	tids[trade.TID] = trade.Time
+	return true
+}
+
+// enforceTradeCap evicts the oldest trades of coins picked by the eviction
+// policy until the global trade cap holds. Caller must hold dataMu.
+func (r *LocalNodeReader) enforceTradeCap() {
+	maxTotal := r.options.MaxTotalTrades
+	if maxTotal <= 0 {
+		return
+	}
+	
+	for r.totalTrades > maxTotal {
+		victim := r.pickEvictionVictim()
+		if victim == "" {
+			return
 		}
 		
-		// Update latest price
-		oldPrice, hadPrice := r.latestPrices[symbol]
-		r.latestPrices[symbol] = order.Price
-		totalPrices := len(r.latestPrices)
-		r.dataMu.Unlock()
+		trades := r.latestTrades[victim]
+		evict := r.totalTrades - maxTotal
+		if evict > len(trades) {
+			evict = len(trades)
+		}
 		
-		logrus.WithFields(logrus.Fields{
-			"symbol":    symbol,
-			"asset_id":  order.Asset,
-			"side":      trade.Side,
-			"price":     order.Price,
-			"old_price": oldPrice,
-			"had_price": hadPrice,
-			"size":      order.Size,
-			"user":      userAddress,
-			"total_prices": totalPrices,
-		}).Debug("Processed order as trade")
+		if evict == len(trades) {
+			delete(r.latestTrades, victim)
+			delete(r.trimmedTrades, victim)
+		} else {
+			r.latestTrades[victim] = trades[evict:]
+			r.trimmedTrades[victim] = true
+		}
+		r.totalTrades -= evict
+	}
+}
+
+// pickEvictionVictim returns the coin to evict trades from. Caller must hold dataMu.
+func (r *LocalNodeReader) pickEvictionVictim() string {
+	victim := ""
+	for symbol, trades := range r.latestTrades {
+		if len(trades) == 0 {
+			continue
+		}
+		if victim == "" {
+			victim = symbol
+			continue
+		}
 		
-		ordersProcessed++
+		current := r.latestTrades[victim]
+		switch r.options.TradeEvictionPolicy {
+		case EvictLargestCoin:
+			if len(trades) > len(current) {
+				victim = symbol
+			}
+		default: // EvictLeastRecentCoin
+			if trades[len(trades)-1].Time < current[len(current)-1].Time {
+				victim = symbol
+			}
+		}
+	}
+	return victim
+}
+
+// GetTotalTrades returns the number of trades retained across all coins
+func (r *LocalNodeReader) GetTotalTrades() int {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	return r.totalTrades
+}
+
+// restingOrderKey identifies a resting order by asset and client order ID
+func restingOrderKey(asset int, cloid string) string {
+	return strconv.Itoa(asset) + ":" + cloid
+}
+
+// addRestingOrder inserts a limit order into its coin's order book. Orders that
+// never rest, such as Ioc, market and trigger orders, are skipped. Orders with a
+// client order ID are tracked so a later cancelByCloid removes them. Caller must hold dataMu.
+func (r *LocalNodeReader) addRestingOrder(symbol string, order *Order, oid int64) {
+	if !order.OrderType.RestsOnBook() {
+		return
+	}
+	
+	px, err := strconv.ParseFloat(order.Price, 64)
+	if err != nil || px <= 0 {
+		return
+	}
+	sz, err := strconv.ParseFloat(order.Size, 64)
+	if err != nil || sz <= 0 {
+		return
+	}
+	
+	book, exists := r.orderBooks[symbol]
+	if !exists {
+		book = newOrderBook()
+		r.orderBooks[symbol] = book
 	}
 	
-	logrus.WithField("orders_processed", ordersProcessed).Debug("Completed processing orders")
+	resting := &restingOrder{coin: symbol, isBuy: order.IsBuy, px: px, sz: sz, oid: oid}
+	if order.ClientOrderID != "" {
+		resting.cloidKey = restingOrderKey(order.Asset, order.ClientOrderID)
+		// A reused cloid replaces the order it previously referred to
+		if previous, exists := r.restingOrders[resting.cloidKey]; exists {
+			r.removeRestingOrder(previous)
+		}
+		r.restingOrders[resting.cloidKey] = resting
+	}
+	if oid != 0 {
+		r.restingOids[oid] = resting
+	}
+	book.add(resting)
+}
+
+// crossesBook reports whether a limit order would trade against the other side
+// of its coin's book. Caller must hold dataMu.
+func (r *LocalNodeReader) crossesBook(symbol string, order *Order) bool {
+	book, exists := r.orderBooks[symbol]
+	if !exists {
+		return false
+	}
+	px, err := strconv.ParseFloat(order.Price, 64)
+	if err != nil {
+		return false
+	}
+	return book.crosses(order.IsBuy, px)
+}
+
+// takeRestingOrders removes the liquidity a taker order traded against: resting
+// orders on the other side at prices its limit price reaches, up to filledSz, or
+// all of them when filledSz is empty. Caller must hold dataMu.
+func (r *LocalNodeReader) takeRestingOrders(symbol string, order *Order, filledSz string) {
+	book, exists := r.orderBooks[symbol]
+	if !exists {
+		return
+	}
+	px, err := strconv.ParseFloat(order.Price, 64)
+	if err != nil {
+		return
+	}
+	sz := math.Inf(1)
+	if filledSz != "" {
+		if sz, err = strconv.ParseFloat(filledSz, 64); err != nil {
+			return
+		}
+	}
+	
+	for _, taken := range book.take(order.IsBuy, px, sz) {
+		r.removeRestingOrder(taken)
+	}
+}
+
+// findRestingOrder looks up a tracked order by order ID, or by client order ID
+// when oid is 0. Caller must hold dataMu.
+func (r *LocalNodeReader) findRestingOrder(asset int, oid int64, cloid string) (*restingOrder, bool) {
+	if oid != 0 {
+		resting, found := r.restingOids[oid]
+		return resting, found
+	}
+	resting, found := r.restingOrders[restingOrderKey(asset, cloid)]
+	return resting, found
+}
+
+// removeRestingOrder takes a tracked order off its coin's order book. Caller must hold dataMu.
+func (r *LocalNodeReader) removeRestingOrder(resting *restingOrder) {
+	if resting.cloidKey != "" && r.restingOrders[resting.cloidKey] == resting {
+		delete(r.restingOrders, resting.cloidKey)
+	}
+	if resting.oid != 0 && r.restingOids[resting.oid] == resting {
+		delete(r.restingOids, resting.oid)
+	}
+	if book, exists := r.orderBooks[resting.coin]; exists {
+		book.remove(resting)
+	}
 }
 
-// processCancellations processes cancellation actions
+// processCancellations processes cancellation actions, by order ID or client order ID
 func (r *LocalNodeReader) processCancellations(cancels []Cancel, blockTime string, userAddress string) {
 	for _, cancel := range cancels {
 		symbol := r.getAssetSymbol(cancel.Asset)
 		
+		r.dataMu.Lock()
+		resting, found := r.findRestingOrder(cancel.Asset, cancel.Oid, cancel.Cloid)
+		if found {
+			r.removeRestingOrder(resting)
+		}
+		r.dataMu.Unlock()
+		
 		logrus.WithFields(logrus.Fields{
 			"symbol":  symbol,
+			"oid":     cancel.Oid,
 			"cloid":   cancel.Cloid,
 			"user":    userAddress,
+			"found":   found,
 		}).Debug("Processed cancellation")
 	}
 }
 
+// processModifies processes batchModify actions: each targeted order is taken off
+// the book and its replacement is handled like a new order, filling or resting
+// according to its status. batchModify carries no builder, so no builder fee applies.
+func (r *LocalNodeReader) processModifies(modifies []Modify, statuses []OrderStatus, blockTime string, userAddress string) {
+	orders := make([]Order, 0, len(modifies))
+	for _, modify := range modifies {
+		oid, cloid := modify.Target()
+		
+		r.dataMu.Lock()
+		resting, found := r.findRestingOrder(modify.Order.Asset, oid, cloid)
+		if found {
+			r.removeRestingOrder(resting)
+		}
+		r.dataMu.Unlock()
+		
+		logrus.WithFields(logrus.Fields{
+			"symbol": r.getAssetSymbol(modify.Order.Asset),
+			"oid":    oid,
+			"cloid":  cloid,
+			"user":   userAddress,
+			"found":  found,
+		}).Debug("Processed modify")
+		
+		orders = append(orders, modify.Order)
+	}
+	r.processOrders(orders, nil, statuses, blockTime, userAddress)
+}
+
 // processBlocks processes blocks from the channel
 func (r *LocalNodeReader) processBlocks() {
 	for {
@@ -584,11 +1645,15 @@ func (r *LocalNodeReader) processBlocks() {
 
 
 
-// parseBlockTime parses block time to Unix timestamp
+// parseBlockTime parses block time to Unix timestamp. Node blocks carry UTC
+// times without a zone, such as 2025-06-01T12:00:00.123456789.
 func (r *LocalNodeReader) parseBlockTime(timeStr string) int64 {
 	t, err := time.Parse(time.RFC3339, timeStr)
 	if err != nil {
-		return time.Now().UnixMilli()
+		t, err = time.Parse("2006-01-02T15:04:05.999999999", timeStr)
+		if err != nil {
+			return time.Now().UnixMilli()
+		}
 	}
 	return t.UnixMilli()
 }
@@ -602,8 +1667,20 @@ func (r *LocalNodeReader) GetLatestPrice(coin string) (string, bool) {
 	return price, exists
 }
 
-// GetLatestTrades returns the latest trades for a coin
-func (r *LocalNodeReader) GetLatestTrades(coin string, limit int) []*types.WsTrade {
+// GetTradeCoins returns the coins with retained fills
+func (r *LocalNodeReader) GetTradeCoins() []string {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	coins := make([]string, 0, len(r.latestTrades))
+	for coin := range r.latestTrades {
+		coins = append(coins, coin)
+	}
+	return coins
+}
+
+// GetRealTrades returns the latest fills for a coin, oldest first
+func (r *LocalNodeReader) GetRealTrades(coin string, limit int) []*types.WsTrade {
 	r.dataMu.RLock()
 	defer r.dataMu.RUnlock()
 	
@@ -613,10 +1690,68 @@ func (r *LocalNodeReader) GetLatestTrades(coin string, limit int) []*types.WsTra
 	}
 	
 	if limit > 0 && len(trades) > limit {
-		return trades[len(trades)-limit:]
+		trades = trades[len(trades)-limit:]
 	}
 	
-	return trades
+	// Copy so callers can iterate while new fills are stored
+	return append([]*types.WsTrade(nil), trades...)
+}
+
+// GetAllMidsBbo returns every known coin's latest price with the best bid and ask of its book
+func (r *LocalNodeReader) GetAllMidsBbo() map[string]types.WsMidBbo {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	result := make(map[string]types.WsMidBbo, len(r.latestPrices))
+	for symbol, price := range r.latestPrices {
+		entry := types.WsMidBbo{Mid: price}
+		if book, exists := r.orderBooks[symbol]; exists {
+			if bid, ok := book.best(true); ok {
+				entry.Bid = formatBookNumber(bid)
+			}
+			if ask, ok := book.best(false); ok {
+				entry.Ask = formatBookNumber(ask)
+			}
+		}
+		result[symbol] = entry
+	}
+	return result
+}
+
+// GetBBO returns the best bid and ask of a coin's book, with nil for an empty
+// side, or nil if no orders have been seen for the coin
+func (r *LocalNodeReader) GetBBO(coin string) *types.WsBbo {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	book, exists := r.orderBooks[coin]
+	if !exists {
+		return nil
+	}
+	
+	return &types.WsBbo{
+		Coin: coin,
+		Time: r.lastBlockTime,
+		BBO:  [2]*types.WsLevel{book.bestLevel(true), book.bestLevel(false)},
+	}
+}
+
+// GetL2Book returns the order book for a coin aggregated to nSigFigs significant
+// figures (0 for full precision), or nil if no orders have been seen for the coin
+func (r *LocalNodeReader) GetL2Book(coin string, nSigFigs int) *types.WsBook {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	book, exists := r.orderBooks[coin]
+	if !exists {
+		return nil
+	}
+	
+	return &types.WsBook{
+		Coin:   coin,
+		Levels: book.snapshot(nSigFigs),
+		Time:   r.lastBlockTime,
+	}
 }
 
 // GetAllLatestPrices returns all available prices
@@ -632,11 +1767,22 @@ func (r *LocalNodeReader) GetAllLatestPrices() map[string]string {
 	return allPrices
 }
 
+// GetLastBlockTime returns the time of the most recent block read, zero if none yet
+func (r *LocalNodeReader) GetLastBlockTime() time.Time {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	if r.lastBlockTime == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(r.lastBlockTime)
+}
+
 // getMostRecentDirectory returns the most recent directory in a path
-func (r *LocalNodeReader) getMostRecentDirectory(basePath string) string {
+func (r *LocalNodeReader) getMostRecentDirectory(basePath string) (string, error) {
 	entries, err := os.ReadDir(basePath)
 	if err != nil {
-		return ""
+		return "", err
 	}
 	
 	var dirs []string
@@ -647,11 +1793,11 @@ func (r *LocalNodeReader) getMostRecentDirectory(basePath string) string {
 	}
 	
 	if len(dirs) == 0 {
-		return ""
+		return "", nil
 	}
 	
 	sort.Strings(dirs)
-	return dirs[len(dirs)-1] // Return the last (most recent) directory
+	return dirs[len(dirs)-1], nil // Return the last (most recent) directory
 }
 
 // GetNodeStats returns statistics about the local node data
@@ -668,11 +1814,7 @@ func (r *LocalNodeReader) GetNodeStats() map[string]interface{} {
 		"running":           r.IsRunning(),
 	}
 	
-	totalTrades := 0
-	for _, trades := range r.latestTrades {
-		totalTrades += len(trades)
-	}
-	stats["total_trades"] = totalTrades
+	stats["total_trades"] = r.totalTrades
 	
 	return stats
 } 
\ No newline at end of file
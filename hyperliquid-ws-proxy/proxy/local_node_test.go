@@ -0,0 +1,553 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestLatestPriceOnlyFollowsFills(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	const blockTime = "2025-01-01T00:00:00.000"
+	coin := r.getAssetSymbol(0)
+	
+	r.processOrders([]Order{limitOrder(false, "150", "1")}, nil, decodeStatuses(t, `[{"resting":{"oid":1}}]`), blockTime, "0xmaker")
+	r.processOrders([]Order{limitOrder(true, "90", "1")}, nil, decodeStatuses(t, `[{"error":"Insufficient margin"}]`), blockTime, "0xrejected")
+	r.processOrders([]Order{limitOrder(true, "95", "1")}, nil, nil, blockTime, "0xnoresps")
+	if price, exists := r.GetLatestPrice(coin); exists {
+		t.Fatalf("latest price = %s after resting and rejected orders only, want none", price)
+	}
+	
+	r.processOrders([]Order{limitOrder(true, "160", "1")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"1","avgPx":"150","oid":2}}]`), blockTime, "0xtaker")
+	if price, _ := r.GetLatestPrice(coin); price != "150" {
+		t.Fatalf("latest price = %q, want the fill's average price 150", price)
+	}
+	
+	r.processOrders([]Order{limitOrder(false, "200", "1")}, nil, decodeStatuses(t, `[{"resting":{"oid":3}}]`), blockTime, "0xmaker")
+	if price, _ := r.GetLatestPrice(coin); price != "150" {
+		t.Fatalf("latest price = %q after a resting order, want it left at 150", price)
+	}
+}
+
+// filledBlock returns a block line holding one buy on asset 0 that filled at px
+func filledBlock(round int, px string) string {
+	return fmt.Sprintf(`{"abci_block":{"time":"2025-01-01T00:00:00.000","round":%[1]d,"signed_action_bundles":[["0xb%[1]d",{"signed_actions":[{"signature":{"r":"0x0","s":"0x0","v":27},"action":{"type":"order","orders":[{"a":0,"b":true,"p":"%[2]s","s":"1","r":false,"t":{"limit":{"tif":"Ioc"}}}],"grouping":"na"},"nonce":%[1]d}],"broadcaster":"0x0","broadcaster_nonce":%[1]d}]]},"resps":{"Full":[["0xb%[1]d",[{"user":"0xtaker","res":{"status":"ok","response":{"type":"order","data":{"statuses":[{"filled":{"totalSz":"1","avgPx":"%[2]s","oid":%[1]d}}]}}}}]]]}}`, round, px) + "\n"
+}
+
+// writeBlocks writes the blocks of rounds from to to, filled at 100 + round, as a block file
+func writeBlocks(t *testing.T, path string, from, to int) {
+	t.Helper()
+	
+	var data string
+	for round := from; round <= to; round++ {
+		data += filledBlock(round, fmt.Sprint(100+round))
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColdStartPolicies(t *testing.T) {
+	cases := []struct {
+		policy     string
+		wantTrades int
+	}{
+		{ColdStartCatchUp, 8},
+		// Only the two complete blocks within the tail of the latest file
+		{ColdStartSkipToLive, 2},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.policy, func(t *testing.T) {
+			dir := t.TempDir()
+			writeBlocks(t, filepath.Join(dir, "0"), 1, 3)
+			writeBlocks(t, filepath.Join(dir, "1"), 4, 8)
+			tail := int64(len(filledBlock(7, "107")) + len(filledBlock(8, "108")) + 10)
+			
+			r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{ColdStartPolicy: tc.policy, ColdStartTailBytes: tail})
+			r.scanBlockFiles(dir)
+			if trades := r.GetTotalTrades(); trades != tc.wantTrades {
+				t.Fatalf("%d trades recorded, want %d", trades, tc.wantTrades)
+			}
+			if price, _ := r.GetLatestPrice(r.getAssetSymbol(0)); price != "108" {
+				t.Fatalf("latest price = %q, want the last block's 108", price)
+			}
+		})
+	}
+}
+
+func TestDecodeOrderTypes(t *testing.T) {
+	cases := []struct {
+		name      string
+		orderType string
+		want      OrderType
+		rests     bool
+	}{
+		{"limit gtc", `{"limit":{"tif":"Gtc"}}`, OrderType{Limit: &LimitOrderType{TIF: TIFGtc}}, true},
+		{"alo", `{"limit":{"tif":"Alo"}}`, OrderType{Limit: &LimitOrderType{TIF: TIFAlo}}, true},
+		{"ioc", `{"limit":{"tif":"Ioc"}}`, OrderType{Limit: &LimitOrderType{TIF: TIFIoc}}, false},
+		{"trigger", `{"trigger":{"isMarket":true,"triggerPx":"95000","tpsl":"sl"}}`, OrderType{Trigger: &TriggerOrderType{IsMarket: true, TriggerPx: "95000", Tpsl: "sl"}}, false},
+		{"trigger with a numeric price", `{"trigger":{"isMarket":false,"triggerPx":105000.5,"tpsl":"tp"}}`, OrderType{Trigger: &TriggerOrderType{TriggerPx: "105000.5", Tpsl: "tp"}}, false},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var order Order
+			if err := json.Unmarshal([]byte(`{"a":0,"b":true,"p":"100","s":"1","r":false,"t":`+tc.orderType+`}`), &order); err != nil {
+				t.Fatal(err)
+			}
+			got := order.OrderType
+			if (got.Limit == nil) != (tc.want.Limit == nil) || (got.Limit != nil && *got.Limit != *tc.want.Limit) {
+				t.Fatalf("limit = %+v, want %+v", got.Limit, tc.want.Limit)
+			}
+			if (got.Trigger == nil) != (tc.want.Trigger == nil) || (got.Trigger != nil && *got.Trigger != *tc.want.Trigger) {
+				t.Fatalf("trigger = %+v, want %+v", got.Trigger, tc.want.Trigger)
+			}
+			if got.RestsOnBook() != tc.rests {
+				t.Fatalf("RestsOnBook() = %v, want %v", got.RestsOnBook(), tc.rests)
+			}
+		})
+	}
+}
+
+func TestDecodeOrderNumericPriceAndSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		p, s      string
+		wantPrice string
+		wantSize  string
+	}{
+		{"strings kept as is", `"50000.0"`, `"0.10"`, "50000.0", "0.10"},
+		{"numbers", `50000.0`, `0.1`, "50000", "0.1"},
+		{"exponent", `1e-5`, `2E3`, "0.00001", "2000"},
+		{"null", `null`, `"1"`, "", "1"},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var order Order
+			if err := json.Unmarshal([]byte(`{"a":0,"b":true,"p":`+tc.p+`,"s":`+tc.s+`,"r":false,"t":{"limit":{"tif":"Gtc"}}}`), &order); err != nil {
+				t.Fatal(err)
+			}
+			if order.Price != tc.wantPrice || order.Size != tc.wantSize {
+				t.Fatalf("price, size = %q, %q, want %q, %q", order.Price, order.Size, tc.wantPrice, tc.wantSize)
+			}
+		})
+	}
+	
+	var order Order
+	if err := json.Unmarshal([]byte(`{"a":0,"b":true,"p":true,"s":"1","r":false,"t":{"limit":{"tif":"Gtc"}}}`), &order); err == nil {
+		t.Fatal("order with a boolean price decoded without error")
+	}
+}
+
+func TestDecodeBlocks(t *testing.T) {
+	block := func(round int) string {
+		return fmt.Sprintf(`{"abci_block":{"time":"2025-01-01T00:00:0%d.000","round":%d,"signed_action_bundles":[]}}`, round, round)
+	}
+	first, second := block(1), block(2)
+	
+	cases := []struct {
+		name         string
+		data         string
+		readToEnd    bool
+		wantConsumed int
+		wantBlocks   int
+	}{
+		{"one per line", first + "\n" + second + "\n", false, len(first + "\n" + second + "\n"), 2},
+		{"concatenated", first + second, false, len(first + second), 2},
+		{"pretty-printed", "{\n  \"abci_block\": {\n    \"round\": 1\n  }\n}\n", false, len("{\n  \"abci_block\": {\n    \"round\": 1\n  }\n}\n"), 1},
+		{"incomplete trailing block", first + "\n" + second[:20], false, len(first), 1},
+		{"incomplete trailing block at end", first + "\n" + second[:20], true, len(first), 1},
+		{"bad line skipped", first + "\nnot json\n" + second + "\n", false, len(first + "\nnot json\n" + second + "\n"), 2},
+		{"bad tail kept until complete", first + "\nnot json", false, len(first), 1},
+		{"bad tail dropped at end", first + "\nnot json", true, len(first + "\nnot json"), 1},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+			consumed, blocks := r.decodeBlocks("block", []byte(tc.data), tc.readToEnd)
+			if consumed != int64(tc.wantConsumed) || blocks != tc.wantBlocks {
+				t.Fatalf("decodeBlocks = %d bytes, %d blocks, want %d bytes, %d blocks", consumed, blocks, tc.wantConsumed, tc.wantBlocks)
+			}
+			if r.GetLastBlockTime().IsZero() {
+				t.Fatal("no block was processed")
+			}
+		})
+	}
+}
+
+func TestCheckTradeTID(t *testing.T) {
+	trade := func(tid, time int64) *types.WsTrade {
+		return &types.WsTrade{Coin: "BTC", TID: tid, Time: time}
+	}
+	
+	keep := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{DuplicateTIDPolicy: DuplicateTIDKeep})
+	for i := 0; i < 2; i++ {
+		if !keep.checkTradeTID("BTC", trade(1, 1000)) {
+			t.Fatal("keep policy dropped a trade")
+		}
+	}
+	
+	drop := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{DuplicateTIDPolicy: DuplicateTIDDrop, DuplicateTIDWindow: time.Minute})
+	if !drop.checkTradeTID("BTC", trade(1, 1000)) {
+		t.Fatal("drop policy dropped the first trade with a TID")
+	}
+	if drop.checkTradeTID("BTC", trade(1, 2000)) {
+		t.Fatal("drop policy kept a duplicate TID")
+	}
+	if !drop.checkTradeTID("ETH", trade(1, 2000)) {
+		t.Fatal("drop policy dropped a TID seen on another coin")
+	}
+	if !drop.checkTradeTID("BTC", trade(1, 1000+time.Minute.Milliseconds()+1)) {
+		t.Fatal("drop policy dropped a TID that fell out of the window")
+	}
+	
+	restamp := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{DuplicateTIDPolicy: DuplicateTIDRestamp, DuplicateTIDWindow: time.Minute})
+	restamp.checkTradeTID("BTC", trade(1, 1000))
+	restamp.checkTradeTID("BTC", trade(2, 1000))
+	duplicate := trade(1, 1000)
+	if !restamp.checkTradeTID("BTC", duplicate) || duplicate.TID != 3 {
+		t.Fatalf("restamp policy gave the duplicate TID %d, want the next unused TID 3", duplicate.TID)
+	}
+}
+
+func TestDuplicateTIDFillsFollowPolicy(t *testing.T) {
+	cases := []struct {
+		policy string
+		want   []int64
+	}{
+		{DuplicateTIDKeep, []int64{7, 7}},
+		{DuplicateTIDDrop, []int64{7}},
+		{DuplicateTIDRestamp, []int64{7, 8}},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.policy, func(t *testing.T) {
+			r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{DuplicateTIDPolicy: tc.policy, DuplicateTIDWindow: time.Minute})
+			// Two fills of the same order carry the same TID
+			for _, px := range []string{"100", "101"} {
+				r.processOrders([]Order{limitOrder(true, px, "1")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"1","avgPx":"`+px+`","oid":7}}]`), "2025-01-01T00:00:00.000", "0xtaker")
+			}
+			
+			trades := r.GetRealTrades(r.getAssetSymbol(0), 0)
+			tids := make([]int64, len(trades))
+			for i, trade := range trades {
+				tids[i] = trade.TID
+			}
+			if fmt.Sprint(tids) != fmt.Sprint(tc.want) {
+				t.Fatalf("trade TIDs = %v, want %v", tids, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnforceTradeCap(t *testing.T) {
+	trades := func(coin string, count int, lastTime int64) []*types.WsTrade {
+		list := make([]*types.WsTrade, count)
+		for i := range list {
+			list[i] = &types.WsTrade{Coin: coin, TID: int64(i), Time: lastTime - int64(count-1-i)}
+		}
+		return list
+	}
+	
+	cases := []struct {
+		policy  string
+		wantBTC int
+		wantETH int
+	}{
+		// ETH traded last longest ago
+		{EvictLeastRecentCoin, 4, 0},
+		// BTC retains the most trades
+		{EvictLargestCoin, 2, 2},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.policy, func(t *testing.T) {
+			r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{MaxTotalTrades: 4, TradeEvictionPolicy: tc.policy})
+			r.latestTrades["BTC"] = trades("BTC", 4, 2000)
+			r.latestTrades["ETH"] = trades("ETH", 2, 1000)
+			r.totalTrades = 6
+			
+			r.enforceTradeCap()
+			if got := len(r.latestTrades["BTC"]); got != tc.wantBTC {
+				t.Fatalf("BTC retains %d trades, want %d", got, tc.wantBTC)
+			}
+			if got := len(r.latestTrades["ETH"]); got != tc.wantETH {
+				t.Fatalf("ETH retains %d trades, want %d", got, tc.wantETH)
+			}
+			if r.totalTrades != 4 {
+				t.Fatalf("total trades = %d, want the cap 4", r.totalTrades)
+			}
+			if btc := r.latestTrades["BTC"]; len(btc) > 0 && btc[len(btc)-1].TID != 3 {
+				t.Fatal("eviction dropped BTC's newest trades instead of its oldest")
+			}
+		})
+	}
+}
+
+func TestTotalTradeCapHoldsAcrossManyCoins(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{MaxTotalTrades: 20})
+	const blockTime = "2025-01-01T00:00:00.000"
+	
+	oid := 0
+	for asset := 0; asset < 50; asset++ {
+		for i := 0; i < 3; i++ {
+			oid++
+			order := limitOrder(true, "100", "1")
+			order.Asset = asset
+			r.processOrders([]Order{order}, nil, decodeStatuses(t, fmt.Sprintf(`[{"filled":{"totalSz":"1","avgPx":"100","oid":%d}}]`, oid)), blockTime, "0xtaker")
+		}
+	}
+	
+	retained := 0
+	for _, coin := range r.GetTradeCoins() {
+		retained += len(r.GetRealTrades(coin, 0))
+	}
+	if retained != 20 || r.GetTotalTrades() != retained {
+		t.Fatalf("%d trades retained, %d counted, want the cap of 20", retained, r.GetTotalTrades())
+	}
+}
+
+func TestGetAssetSymbol(t *testing.T) {
+	fetcher := NewAssetFetcher("")
+	fetcher.perpAssets = map[int]*AssetInfo{
+		0: {Index: 0, Name: "BTC"},
+		1: {Index: 1, Name: "ETH"},
+	}
+	fetcher.spotAssets = map[int]*AssetInfo{
+		10000: {Index: 10000, Name: "PURR/USDC", IsSpot: true},
+		10107: {Index: 10107, Name: "HYPE/USDC", IsSpot: true},
+	}
+	
+	cases := []struct {
+		name    string
+		fetcher *AssetFetcher
+		assetID int
+		want    string
+	}{
+		{"perp", fetcher, 1, "ETH"},
+		{"perp sharing an index with a spot pair", fetcher, 0, "BTC"},
+		{"spot pair 0", fetcher, 10000, "PURR/USDC"},
+		{"spot above 10000", fetcher, 10107, "HYPE/USDC"},
+		{"unknown perp is not looked up as spot", fetcher, 107, "ASSET_107"},
+		{"unknown spot pair", fetcher, 10001, "@1"},
+		{"perp without metadata", nil, 3, "ASSET_3"},
+		{"spot without metadata", nil, 10042, "@42"},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewLocalNodeReader(t.TempDir(), tc.fetcher, LocalNodeOptions{})
+			if got := r.getAssetSymbol(tc.assetID); got != tc.want {
+				t.Fatalf("getAssetSymbol(%d) = %q, want %q", tc.assetID, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFallbackAssetSymbol(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	cases := []struct {
+		assetID int
+		isSpot  bool
+		want    string
+	}{
+		{0, false, "ASSET_0"},
+		{9999, false, "ASSET_9999"},
+		{10000, true, "@0"},
+		{10250, true, "@250"},
+	}
+	for _, tc := range cases {
+		if got := r.fallbackAssetSymbol(tc.assetID, tc.isSpot); got != tc.want {
+			t.Errorf("fallbackAssetSymbol(%d, %v) = %q, want %q", tc.assetID, tc.isSpot, got, tc.want)
+		}
+	}
+}
+
+func BenchmarkDecodeSignedActionBundle(b *testing.B) {
+	const bundle = `{"signed_actions":[{"signature":{"r":"0x1","s":"0x2","v":27},"action":{"type":"order","orders":[{"a":0,"b":true,"p":"100.5","s":"0.25","r":false,"t":{"limit":{"tif":"Gtc"}},"c":"0x00000000000000000000000000000001"}],"grouping":"na"},"nonce":1700000000000}],"broadcaster":"0xbroadcaster","broadcaster_nonce":1700000000000}`
+	forms := map[string]string{
+		"object": bundle,
+		"array":  `["0xhash",` + bundle + `]`,
+	}
+	
+	r := NewLocalNodeReader(b.TempDir(), nil, LocalNodeOptions{})
+	for name, raw := range forms {
+		b.Run(name, func(b *testing.B) {
+			data := json.RawMessage(raw)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.decodeSignedActionBundle(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSignedActionBundleForms(t *testing.T) {
+	const data = `{"signed_actions":[{"action":{"type":"noop"},"nonce":7}],"broadcaster":"0xb"}`
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	
+	forms := map[string]struct {
+		raw      string
+		wantHash string
+	}{
+		"array":               {`["0xhash",` + data + `]`, "0xhash"},
+		"object":              {`{"hash":"0xobj",` + data[1:], "0xobj"},
+		"object without hash": {" \n" + data, ""},
+	}
+	for name, form := range forms {
+		bundle, err := r.decodeSignedActionBundle(json.RawMessage(form.raw))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if bundle.Hash != form.wantHash || bundle.Broadcaster != "0xb" {
+			t.Fatalf("%s: hash %q broadcaster %q, want %q and 0xb", name, bundle.Hash, bundle.Broadcaster, form.wantHash)
+		}
+		if len(bundle.SignedActions) != 1 || bundle.SignedActions[0].Action.Type != "noop" || bundle.SignedActions[0].Nonce != 7 {
+			t.Fatalf("%s: signed actions %+v, want the noop", name, bundle.SignedActions)
+		}
+	}
+	
+	for _, raw := range []string{``, `  `, `"0xhash"`, `42`, `["0xhash"]`, `["0xhash","data"]`, `{"signed_actions":"none"}`} {
+		if _, err := r.decodeSignedActionBundle(json.RawMessage(raw)); err == nil {
+			t.Fatalf("decodeSignedActionBundle(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestUnexpectedBundleShapeLoggedOnce(t *testing.T) {
+	hook := captureLogs(t, logrus.WarnLevel)
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	
+	for i := 0; i < 3; i++ {
+		r.processSignedActionBundle(json.RawMessage(`"unexpected"`), nil, "2025-01-01T00:00:00.000")
+	}
+	if got := countLogs(hook, "Unexpected signed action bundle shape, skipping such bundles"); got != 1 {
+		t.Fatalf("unexpected shape logged %d times, want once", got)
+	}
+}
+
+func TestEmptyBlockOnlyAdvancesTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "0")
+	empty := `{"abci_block":{"time":"2025-01-01T00:00:05.000","round":2,"signed_action_bundles":[]},"resps":{"Full":[]}}` + "\n"
+	data := filledBlock(1, "100") + empty
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	r.readBlockFile(path, 0)
+	
+	r.dataMu.RLock()
+	pos, stored := r.lastReadFiles[path], len(r.latestBlocks)
+	r.dataMu.RUnlock()
+	if pos != int64(len(data)) {
+		t.Fatalf("read position = %d, want the end of the file at %d", pos, len(data))
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC); !r.GetLastBlockTime().Equal(want) {
+		t.Fatalf("last block time = %s, want the empty block's %s", r.GetLastBlockTime(), want)
+	}
+	if stored != 1 {
+		t.Fatalf("%d blocks stored, want only the filled one", stored)
+	}
+	if version := r.DataVersion(); version != 1 {
+		t.Fatalf("data version = %d, want 1 so no messages are generated for the empty block", version)
+	}
+}
+
+func TestShrunkBlockFileReReadWithoutDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "0")
+	writeBlocks(t, path, 1, 5)
+	
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	r.readBlockFile(path, 0)
+	
+	// The node replaces the file with a shorter one repeating rounds 4 and 5
+	hook := captureLogs(t, logrus.WarnLevel)
+	writeBlocks(t, path, 4, 7)
+	r.dataMu.RLock()
+	pos := r.lastReadFiles[path]
+	r.dataMu.RUnlock()
+	if stat, err := os.Stat(path); err != nil || stat.Size() >= pos {
+		t.Fatalf("replaced file is not smaller than the read position %d: %v", pos, err)
+	}
+	r.readBlockFile(path, pos)
+	
+	r.dataMu.RLock()
+	var rounds []int64
+	for _, block := range r.latestBlocks {
+		rounds = append(rounds, block.ABCIBlock.Round)
+	}
+	pos = r.lastReadFiles[path]
+	_, rereading := r.rereadRounds[path]
+	r.dataMu.RUnlock()
+	if fmt.Sprint(rounds) != "[1 2 3 4 5 6 7]" {
+		t.Fatalf("processed rounds %v, want each of 1 to 7 once", rounds)
+	}
+	if stat, _ := os.Stat(path); pos != stat.Size() {
+		t.Fatalf("read position = %d after the re-read, want the end of the file at %d", pos, stat.Size())
+	}
+	if rereading {
+		t.Fatal("file still marked as re-read after newer rounds were processed")
+	}
+	if got := countLogs(hook, "Block file shrank below the read position, re-reading it from the start"); got != 1 {
+		t.Fatalf("shrink logged %d times, want once", got)
+	}
+}
+
+func TestReadBlockFileWithConcatenatedBlocksAndPartialTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "0")
+	line := strings.TrimSuffix(filledBlock(1, "101"), "\n") + filledBlock(2, "102")
+	third := filledBlock(3, "103")
+	if err := os.WriteFile(path, []byte(line+third[:len(third)/2]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	rounds := func() (string, int64) {
+		r.dataMu.RLock()
+		defer r.dataMu.RUnlock()
+		var rounds []int64
+		for _, block := range r.latestBlocks {
+			rounds = append(rounds, block.ABCIBlock.Round)
+		}
+		return fmt.Sprint(rounds), r.lastReadFiles[path]
+	}
+	
+	// Both blocks on the line parse, the block still being written waits and
+	// the position stays right after the second block
+	r.readBlockFile(path, 0)
+	got, pos := rounds()
+	if end := int64(len(line) - 1); got != "[1 2]" || pos != end {
+		t.Fatalf("after the first read: rounds %s at position %d, want [1 2] at %d", got, pos, end)
+	}
+	if price, _ := r.GetLatestPrice(r.getAssetSymbol(0)); price != "102" {
+		t.Fatalf("latest price = %q, want the second block's 102", price)
+	}
+	
+	// The node finishes writing the third block
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(third[len(third)/2:]); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	r.readBlockFile(path, pos)
+	got, pos = rounds()
+	if got != "[1 2 3]" || pos != int64(len(line+third)) {
+		t.Fatalf("after the second read: rounds %s at position %d, want [1 2 3] at %d", got, pos, len(line+third))
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Asset lookups needed before the unresolved share is considered meaningful
+	minNetworkCheckLookups = 500
+	// Share of unresolved asset IDs above which the data is assumed to come from another network
+	maxUnresolvedAssetRatio = 0.5
+)
+
+// pathTokens splits a path into lowercase alphanumeric words
+var pathTokens = regexp.MustCompile(`[a-z0-9]+`)
+
+// dataPathNetwork infers the network a local node data path belongs to from
+// its name (e.g. "node_hl-data-mainnet"), empty if the path doesn't tell
+func dataPathNetwork(dataPath string) string {
+	network := ""
+	for _, token := range pathTokens.FindAllString(strings.ToLower(dataPath), -1) {
+		if token != "mainnet" && token != "testnet" {
+			continue
+		}
+		if network != "" && network != token {
+			return ""
+		}
+		network = token
+	}
+	return network
+}
+
+// networkMismatch returns why the local node data appears to come from another
+// network than the configured one, empty if nothing points to a mismatch. The
+// first detection is logged as a warning.
+func (p *Proxy) networkMismatch() string {
+	if !p.useLocalNode || p.localNodeReader == nil {
+		return ""
+	}
+	
+	configured := p.config.Hyperliquid.Network
+	if configured == "" {
+		configured = "mainnet"
+	}
+	
+	reason := ""
+	if inferred := dataPathNetwork(p.config.Proxy.LocalNodeDataPath); inferred != "" && inferred != configured {
+		reason = fmt.Sprintf("network mismatch: configured %s but local node data path looks like %s", configured, inferred)
+	} else if lookups, misses := p.localNodeReader.GetAssetResolution(); lookups >= minNetworkCheckLookups && float64(misses)/float64(lookups) > maxUnresolvedAssetRatio {
+		reason = fmt.Sprintf("network mismatch: %d of %d local node asset IDs are unknown to the %s asset metadata", misses, lookups, configured)
+	}
+	
+	if reason != "" {
+		p.networkWarnOnce.Do(func() {
+			logrus.WithFields(logrus.Fields{
+				"network":   configured,
+				"data_path": p.config.Proxy.LocalNodeDataPath,
+				"reason":    reason,
+			}).Warn("Local node data appears to belong to another network, symbols may resolve against the wrong universe")
+		})
+	}
+	return reason
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"hyperliquid-ws-proxy/config"
+)
+
+func TestDataPathNetwork(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/home/hl/hl/data", ""},
+		{"/home/hl/node_hl-data-mainnet", "mainnet"},
+		{"/srv/Testnet/data", "testnet"},
+		{"/srv/testnet-to-mainnet/data", ""}, // names both
+		{"/srv/mainnetwork/data", ""},        // only whole words count
+	}
+	
+	for _, tc := range cases {
+		if got := dataPathNetwork(tc.path); got != tc.want {
+			t.Errorf("dataPathNetwork(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestNetworkMismatchFromDataPath(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.Network = "testnet"
+		cfg.Proxy.LocalNodeDataPath = "/data/node-mainnet"
+	})
+	if reason := p.networkMismatch(); !strings.Contains(reason, "configured testnet but local node data path looks like mainnet") {
+		t.Fatalf("networkMismatch() = %q, want the path mismatch", reason)
+	}
+	setLastBlock(p.localNodeReader, 0)
+	if status := p.HealthStatus(); status.Healthy {
+		t.Fatalf("status = %+v, want the mismatch to make it unhealthy", status)
+	}
+}
+
+func TestNetworkMismatchFromUnresolvedAssets(t *testing.T) {
+	cases := []struct {
+		name     string
+		lookups  int64
+		misses   int64
+		mismatch bool
+	}{
+		{"too few lookups", minNetworkCheckLookups - 1, minNetworkCheckLookups - 1, false},
+		{"mostly resolved", 1000, 500, false},
+		{"mostly unresolved", 1000, 501, true},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestProxy(t, nil)
+			atomic.StoreInt64(&p.localNodeReader.assetLookups, tc.lookups)
+			atomic.StoreInt64(&p.localNodeReader.assetMisses, tc.misses)
+			if reason := p.networkMismatch(); (reason != "") != tc.mismatch {
+				t.Fatalf("networkMismatch() = %q, want a mismatch %v", reason, tc.mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"math/big"
+	
+	"github.com/sirupsen/logrus"
+)
+
+// Most decimals a price may have before szDecimals are taken off, as on Hyperliquid
+const (
+	perpMaxPriceDecimals = 6
+	spotMaxPriceDecimals = 8
+)
+
+// normalizePrice returns the canonical form of a price of coin, as the
+// official API writes it: rounded to the coin's price decimals (6 for perps
+// and 8 for spot, minus szDecimals) without trailing zeros. Prices of coins
+// the AssetFetcher doesn't know, or that don't parse, are returned unchanged.
+func (r *LocalNodeReader) normalizePrice(coin, raw string) string {
+	asset, ok := r.decimalsMetadata(coin)
+	if !ok {
+		return raw
+	}
+	
+	maxDecimals := perpMaxPriceDecimals
+	if asset.IsSpot {
+		maxDecimals = spotMaxPriceDecimals
+	}
+	return roundDecimalString(raw, maxDecimals-asset.SzDecimals)
+}
+
+// normalizeSize returns the canonical form of a size of coin: rounded to its
+// szDecimals without trailing zeros. Sizes of coins the AssetFetcher doesn't
+// know, or that don't parse, are returned unchanged.
+func (r *LocalNodeReader) normalizeSize(coin, raw string) string {
+	asset, ok := r.decimalsMetadata(coin)
+	if !ok {
+		return raw
+	}
+	return roundDecimalString(raw, asset.SzDecimals)
+}
+
+// ApplyLoadedDecimals normalizes the latest price and open candle volumes of
+// coins that traded before the AssetFetcher had their decimals, once it has
+// them, so the next updates carry the corrected values instead of jumping
+// between formats. Trade and fill history keeps the form it was delivered in.
+// Returns the number of coins corrected.
+func (r *LocalNodeReader) ApplyLoadedDecimals() int {
+	r.dataMu.RLock()
+	coins := make([]string, 0, len(r.rawDecimals))
+	for coin := range r.rawDecimals {
+		coins = append(coins, coin)
+	}
+	r.dataMu.RUnlock()
+	
+	loaded := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		if _, ok := r.decimalsMetadata(coin); ok {
+			loaded[coin] = true
+		}
+	}
+	if len(loaded) == 0 {
+		return 0
+	}
+	
+	prices := make(map[string]string, len(loaded))
+	volumeDecimals := make(map[string]int, len(loaded))
+	r.dataMu.RLock()
+	for coin := range loaded {
+		if price, exists := r.latestPrices[coin]; exists {
+			prices[coin] = price
+		}
+	}
+	r.dataMu.RUnlock()
+	for coin := range loaded {
+		if price, exists := prices[coin]; exists {
+			prices[coin] = r.normalizePrice(coin, price)
+		}
+		volumeDecimals[coin] = r.candleVolumeDecimals(coin)
+	}
+	
+	r.dataMu.Lock()
+	for coin := range loaded {
+		delete(r.rawDecimals, coin)
+		if price, exists := prices[coin]; exists {
+			r.latestPrices[coin] = price
+		}
+	}
+	for key, candle := range r.openCandles {
+		if decimals, exists := volumeDecimals[key.coin]; exists {
+			candle.V = roundVolume(candle.V, decimals)
+		}
+	}
+	r.dataMu.Unlock()
+	
+	r.bumpDataVersion()
+	logrus.WithField("coins", len(loaded)).Info("Applied loaded asset decimals to coins seen before their metadata")
+	return len(loaded)
+}
+
+// decimalsMetadata returns the AssetFetcher metadata of a coin
+func (r *LocalNodeReader) decimalsMetadata(coin string) (*AssetInfo, bool) {
+	if r.assetFetcher == nil {
+		return nil, false
+	}
+	return r.assetFetcher.GetAssetByName(coin)
+}
+
+// roundDecimalString rounds a decimal string to at most decimals fractional
+// digits, half away from zero, and trims trailing zeros. Strings that aren't
+// decimals are returned unchanged.
+func roundDecimalString(raw string, decimals int) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+	value, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return raw
+	}
+	return trimDecimalZeros(value.FloatString(decimals))
+}
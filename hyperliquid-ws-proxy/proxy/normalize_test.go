@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newDecimalsReader returns a reader whose AssetFetcher knows BTC as a perp
+// with 5 size decimals and PURR/USDC as a spot pair with 0
+func newDecimalsReader(t *testing.T) *LocalNodeReader {
+	t.Helper()
+	
+	fetcher := NewAssetFetcher("")
+	btc := &AssetInfo{Index: 0, Name: "BTC", SzDecimals: 5}
+	purr := &AssetInfo{Index: 10000, Name: "PURR/USDC", SzDecimals: 0, IsSpot: true}
+	fetcher.perpAssets[0] = btc
+	fetcher.assetsByName[btc.Name] = btc
+	fetcher.assetsByName[purr.Name] = purr
+	return NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{})
+}
+
+func TestRoundDecimalString(t *testing.T) {
+	cases := []struct {
+		raw      string
+		decimals int
+		want     string
+	}{
+		{"100.50", 2, "100.5"},
+		{"100.000", 3, "100"},
+		{"100", 2, "100"},
+		{"0.10", 4, "0.1"},
+		{"1.25", 1, "1.3"},
+		{"1.24", 1, "1.2"},
+		{"-1.25", 1, "-1.3"},
+		{"99.96", 1, "100"},
+		{"12.5", 0, "13"},
+		{"12.5", -2, "13"},
+		{"not a number", 2, "not a number"},
+	}
+	
+	for _, tc := range cases {
+		if got := roundDecimalString(tc.raw, tc.decimals); got != tc.want {
+			t.Errorf("roundDecimalString(%q, %d) = %q, want %q", tc.raw, tc.decimals, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizePriceAndSize(t *testing.T) {
+	r := newDecimalsReader(t)
+	
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		// Perp prices keep 6 - 5 = 1 decimal
+		{"perp price trailing zeros", r.normalizePrice("BTC", "97000.0"), "97000"},
+		{"perp price rounded", r.normalizePrice("BTC", "97000.25"), "97000.3"},
+		{"perp size trailing zeros", r.normalizeSize("BTC", "0.00100"), "0.001"},
+		{"perp size rounded", r.normalizeSize("BTC", "0.123456"), "0.12346"},
+		
+		// Spot prices keep 8 - 0 = 8 decimals
+		{"spot price trailing zeros", r.normalizePrice("PURR/USDC", "0.20000000"), "0.2"},
+		{"spot price rounded", r.normalizePrice("PURR/USDC", "0.123456785"), "0.12345679"},
+		{"spot size rounded", r.normalizeSize("PURR/USDC", "10.5"), "11"},
+		
+		// Coins without metadata pass through
+		{"unknown price", r.normalizePrice("ETH", "3000.10"), "3000.10"},
+		{"unknown size", r.normalizeSize("ETH", "1.500"), "1.500"},
+	}
+	
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestProcessOrdersStoresNormalizedValues(t *testing.T) {
+	r := newDecimalsReader(t)
+	
+	r.processOrders([]Order{limitOrder(true, "97000.50", "0.500")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"0.500","avgPx":"97000.40","oid":1}}]`), "2025-01-01T00:00:00.000", "0xtaker")
+	if price, _ := r.GetLatestPrice("BTC"); price != "97000.4" {
+		t.Fatalf("latest price = %q, want 97000.4", price)
+	}
+	trades := r.GetRealTrades("BTC", 1)
+	if len(trades) != 1 || trades[0].Px != "97000.4" || trades[0].Sz != "0.5" {
+		t.Fatalf("stored trades = %+v, want px 97000.4 and sz 0.5", trades)
+	}
+}
+
+func TestLateDecimalsCorrectEarlierPriceAndCandle(t *testing.T) {
+	// The asset map names asset 0 before the fetcher has its metadata
+	fetcher := NewAssetFetcher("")
+	path := writeAssetMap(t, filepath.Join(t.TempDir(), "assets.json"), `{"0": "BTC"}`)
+	r := NewLocalNodeReader(t.TempDir(), fetcher, LocalNodeOptions{AssetMapFile: path})
+	const blockTime = "2025-01-01T00:00:00.000"
+	
+	r.processOrders([]Order{limitOrder(true, "97000.50", "0.123456")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"0.123456","avgPx":"97000.40","oid":1}}]`), blockTime, "0xtaker")
+	if price, _ := r.GetLatestPrice("BTC"); price != "97000.40" {
+		t.Fatalf("latest price before metadata = %q, want the raw 97000.40", price)
+	}
+	if n := r.ApplyLoadedDecimals(); n != 0 {
+		t.Fatalf("ApplyLoadedDecimals corrected %d coins without metadata, want 0", n)
+	}
+	
+	btc := &AssetInfo{Index: 0, Name: "BTC", SzDecimals: 5}
+	fetcher.mu.Lock()
+	fetcher.perpAssets[0] = btc
+	fetcher.assetsByName[btc.Name] = btc
+	fetcher.mu.Unlock()
+	version := r.DataVersion()
+	if n := r.ApplyLoadedDecimals(); n != 1 {
+		t.Fatalf("ApplyLoadedDecimals corrected %d coins, want 1", n)
+	}
+	if r.DataVersion() == version {
+		t.Fatal("data version not bumped, so generators won't re-emit the corrected values")
+	}
+	if price, _ := r.GetLatestPrice("BTC"); price != "97000.4" {
+		t.Fatalf("latest price after metadata = %q, want 97000.4", price)
+	}
+	if candle := r.GetCandle("BTC", "1m"); candle == nil || candle.V != 0.12346 {
+		t.Fatalf("open candle after metadata = %+v, want volume 0.12346", candle)
+	}
+	
+	// Trades after the load come out in the same form
+	r.processOrders([]Order{limitOrder(true, "97000.70", "0.100000")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"0.100000","avgPx":"97000.60","oid":2}}]`), blockTime, "0xtaker")
+	if price, _ := r.GetLatestPrice("BTC"); price != "97000.6" {
+		t.Fatalf("latest price of a later trade = %q, want 97000.6", price)
+	}
+	if candle := r.GetCandle("BTC", "1m"); candle == nil || candle.V != 0.22346 {
+		t.Fatalf("open candle after a later trade = %+v, want volume 0.22346", candle)
+	}
+	if n := r.ApplyLoadedDecimals(); n != 0 {
+		t.Fatalf("ApplyLoadedDecimals corrected %d coins again, want 0", n)
+	}
+}
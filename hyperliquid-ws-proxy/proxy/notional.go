@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"math/big"
+	"strings"
+)
+
+// computeNotional multiplies a decimal price and size string exactly,
+// keeping every significant decimal and trimming trailing zeros
+func computeNotional(px, sz string) (string, bool) {
+	price, ok := new(big.Rat).SetString(px)
+	if !ok {
+		return "", false
+	}
+	size, ok := new(big.Rat).SetString(sz)
+	if !ok {
+		return "", false
+	}
+	
+	notional := new(big.Rat).Mul(price, size)
+	return trimDecimalZeros(notional.FloatString(decimalPlaces(px) + decimalPlaces(sz))), true
+}
+
+// computeBuilderFee returns the builder fee owed on a fill, the notional
+// times a fee expressed in tenths of a basis point as in an order's builder field
+func computeBuilderFee(px, sz string, tenthsBps int) (string, bool) {
+	notional, ok := computeNotional(px, sz)
+	if !ok {
+		return "", false
+	}
+	
+	value, _ := new(big.Rat).SetString(notional)
+	fee := new(big.Rat).Mul(value, big.NewRat(int64(tenthsBps), 100000))
+	return trimDecimalZeros(fee.FloatString(decimalPlaces(notional) + 5)), true
+}
+
+// decimalPlaces returns the number of digits after the decimal point
+func decimalPlaces(s string) int {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// trimDecimalZeros removes trailing fractional zeros and a dangling decimal point
+func trimDecimalZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestComputeNotional(t *testing.T) {
+	tests := []struct {
+		px, sz string
+		want   string
+	}{
+		{"43250.5", "0.0123", "531.98115"},
+		{"0.1", "0.2", "0.02"},
+		{"100", "2.50", "250"},
+		{"1.5", "2", "3"},
+	}
+	
+	for _, tt := range tests {
+		got, ok := computeNotional(tt.px, tt.sz)
+		if !ok || got != tt.want {
+			t.Errorf("computeNotional(%s, %s) = %q, %v, want %q", tt.px, tt.sz, got, ok, tt.want)
+		}
+	}
+	
+	if _, ok := computeNotional("abc", "1"); ok {
+		t.Error("computeNotional accepted a price that is not a number")
+	}
+}
+
+func TestTradeNotionalOnlyWhenEnabled(t *testing.T) {
+	trade := &types.WsTrade{Coin: "BTC", Side: "B", Px: "43250.5", Sz: "0.0123", TID: 1}
+	
+	p := newTestProxy(t, nil)
+	message, err := p.buildTradeMessage(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(message), "notional") {
+		t.Fatalf("default trade message = %s, want no notional", message)
+	}
+	
+	p = newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.EnrichTradesNotional = true
+	})
+	message, err = p.buildTradeMessage(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(message), `"notional":"531.98115"`) {
+		t.Fatalf("enriched trade message = %s, want notional 531.98115", message)
+	}
+}
+
+func TestBuilderFeeOnlyOnBuilderFills(t *testing.T) {
+	const blockTime = "2025-01-01T00:00:00.000"
+	filled := func(oid int) []OrderStatus {
+		return decodeStatuses(t, fmt.Sprintf(`[{"filled":{"totalSz":"2","avgPx":"100","oid":%d}}]`, oid))
+	}
+	
+	r := NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{IncludeBuilderFees: true})
+	// 10 tenths of a basis point on a 200 notional
+	r.processOrders([]Order{limitOrder(true, "100", "2")}, &Builder{Address: "0xbuilder", Fee: 10}, filled(1), blockTime, "0xrouted")
+	r.processOrders([]Order{limitOrder(true, "100", "2")}, nil, filled(2), blockTime, "0xdirect")
+	
+	routed := r.GetUserFills("0xrouted", 0)
+	if routed == nil || len(routed.Fills) != 1 {
+		t.Fatalf("fills of the builder-routed user = %+v, want one", routed)
+	}
+	if fill := routed.Fills[0]; fill.BuilderFee == nil || *fill.BuilderFee != "0.02" || fill.FeeToken != "USDC" {
+		t.Fatalf("builder fill = %+v, want builderFee 0.02 in USDC", fill)
+	}
+	
+	direct := r.GetUserFills("0xdirect", 0)
+	if direct == nil || len(direct.Fills) != 1 {
+		t.Fatalf("fills of the direct user = %+v, want one", direct)
+	}
+	if fill := direct.Fills[0]; fill.BuilderFee != nil || fill.FeeToken != "" {
+		t.Fatalf("fill without a builder = %+v, want no builder fee or fee token", fill)
+	}
+	
+	// Disabled, builder-routed fills carry no fee either
+	r = NewLocalNodeReader(t.TempDir(), NewAssetFetcher(""), LocalNodeOptions{})
+	r.processOrders([]Order{limitOrder(true, "100", "2")}, &Builder{Address: "0xbuilder", Fee: 10}, filled(3), blockTime, "0xrouted")
+	if fill := r.GetUserFills("0xrouted", 0).Fills[0]; fill.BuilderFee != nil {
+		t.Fatalf("builder fee %s set with include_builder_fees off", *fill.BuilderFee)
+	}
+}
+
+func TestFeeToken(t *testing.T) {
+	cases := []struct {
+		assetID int
+		symbol  string
+		want    string
+	}{
+		{0, "BTC", "USDC"},
+		{spotAssetIDOffset + 1, "PURR/USDH", "USDH"},
+		{spotAssetIDOffset, "@0", "USDC"},
+	}
+	for _, tc := range cases {
+		if got := feeToken(tc.assetID, tc.symbol); got != tc.want {
+			t.Errorf("feeToken(%d, %s) = %s, want %s", tc.assetID, tc.symbol, got, tc.want)
+		}
+	}
+}
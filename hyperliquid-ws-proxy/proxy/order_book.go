@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+// maxBookLevels is the number of levels per side returned in an l2Book snapshot, matching the real API
+const maxBookLevels = 20
+
+// restingOrder is a limit order resting on a local order book
+type restingOrder struct {
+	coin  string
+	isBuy bool
+	px    float64
+	sz    float64
+	
+	// How cancels and modifies refer to the order, empty or 0 when unknown
+	cloidKey string
+	oid      int64
+}
+
+// bookSizeEpsilon absorbs float error when comparing sizes
+const bookSizeEpsilon = 1e-9
+
+// bookLevel aggregates the resting orders at a single price
+type bookLevel struct {
+	sz     float64
+	n      int
+	orders []*restingOrder // in time priority, nil for aggregated buckets
+}
+
+// orderBook is a per-coin book of resting orders keyed by price level
+type orderBook struct {
+	bids map[float64]*bookLevel
+	asks map[float64]*bookLevel
+}
+
+// newOrderBook creates an empty order book
+func newOrderBook() *orderBook {
+	return &orderBook{
+		bids: make(map[float64]*bookLevel),
+		asks: make(map[float64]*bookLevel),
+	}
+}
+
+// side returns the levels for one side of the book
+func (b *orderBook) side(isBuy bool) map[float64]*bookLevel {
+	if isBuy {
+		return b.bids
+	}
+	return b.asks
+}
+
+// add inserts a resting order into its price level
+func (b *orderBook) add(o *restingOrder) {
+	levels := b.side(o.isBuy)
+	level, exists := levels[o.px]
+	if !exists {
+		level = &bookLevel{}
+		levels[o.px] = level
+	}
+	level.sz += o.sz
+	level.n++
+	level.orders = append(level.orders, o)
+}
+
+// remove takes a resting order out of its price level, dropping the level once
+// empty. Orders no longer on the book, such as those already taken, are ignored.
+func (b *orderBook) remove(o *restingOrder) {
+	levels := b.side(o.isBuy)
+	level, exists := levels[o.px]
+	if !exists {
+		return
+	}
+	for i, resting := range level.orders {
+		if resting != o {
+			continue
+		}
+		level.orders = append(level.orders[:i], level.orders[i+1:]...)
+		level.sz -= o.sz
+		level.n--
+		if len(level.orders) == 0 {
+			delete(levels, o.px)
+		}
+		return
+	}
+}
+
+// crosses reports whether a limit order at px would trade against the other
+// side of the book rather than rest
+func (b *orderBook) crosses(isBuy bool, px float64) bool {
+	best, ok := b.best(!isBuy)
+	if !ok {
+		return false
+	}
+	if isBuy {
+		return px >= best
+	}
+	return px <= best
+}
+
+// take consumes the resting orders a taker trades against: those on the other
+// side at prices its limit px reaches, best price first and oldest first within
+// a level, until sz is used up. Returns the orders taken in full; an order taken
+// in part stays on the book with its size reduced.
+func (b *orderBook) take(takerIsBuy bool, px, sz float64) []*restingOrder {
+	levels := b.side(!takerIsBuy)
+	var taken []*restingOrder
+	for sz > bookSizeEpsilon && b.crosses(takerIsBuy, px) {
+		best, _ := b.best(!takerIsBuy)
+		level := levels[best]
+		for len(level.orders) > 0 && sz > bookSizeEpsilon {
+			o := level.orders[0]
+			if o.sz-sz > bookSizeEpsilon {
+				o.sz -= sz
+				level.sz -= sz
+				sz = 0
+				break
+			}
+			sz -= o.sz
+			level.sz -= o.sz
+			level.n--
+			level.orders = level.orders[1:]
+			taken = append(taken, o)
+		}
+		if len(level.orders) == 0 {
+			delete(levels, best)
+		}
+	}
+	return taken
+}
+
+// best returns the best price on one side of the book
+func (b *orderBook) best(isBuy bool) (float64, bool) {
+	var (
+		best  float64
+		found bool
+	)
+	for px := range b.side(isBuy) {
+		if !found || (isBuy && px > best) || (!isBuy && px < best) {
+			best = px
+			found = true
+		}
+	}
+	return best, found
+}
+
+// bestLevel returns the best level of a side at full precision, nil if the side is empty
+func (b *orderBook) bestLevel(isBuy bool) *types.WsLevel {
+	px, ok := b.best(isBuy)
+	if !ok {
+		return nil
+	}
+	level := b.side(isBuy)[px]
+	return &types.WsLevel{
+		Px: formatBookNumber(px),
+		Sz: formatBookNumber(level.sz),
+		N:  level.n,
+	}
+}
+
+// snapshot aggregates the book to nSigFigs significant figures (0 for full
+// precision) and returns up to maxBookLevels levels per side, best first
+func (b *orderBook) snapshot(nSigFigs int) [2][]types.WsLevel {
+	return [2][]types.WsLevel{
+		aggregateLevels(b.bids, true, nSigFigs),
+		aggregateLevels(b.asks, false, nSigFigs),
+	}
+}
+
+// aggregateLevels buckets price levels the way the API does: bids are rounded
+// down and asks rounded up to nSigFigs significant figures
+func aggregateLevels(levels map[float64]*bookLevel, isBid bool, nSigFigs int) []types.WsLevel {
+	buckets := make(map[float64]*bookLevel)
+	for px, level := range levels {
+		bucketPx := roundToSigFigs(px, nSigFigs, !isBid)
+		bucket, exists := buckets[bucketPx]
+		if !exists {
+			bucket = &bookLevel{}
+			buckets[bucketPx] = bucket
+		}
+		bucket.sz += level.sz
+		bucket.n += level.n
+	}
+	
+	prices := make([]float64, 0, len(buckets))
+	for px := range buckets {
+		prices = append(prices, px)
+	}
+	if isBid {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	} else {
+		sort.Float64s(prices)
+	}
+	if len(prices) > maxBookLevels {
+		prices = prices[:maxBookLevels]
+	}
+	
+	result := make([]types.WsLevel, 0, len(prices))
+	for _, px := range prices {
+		bucket := buckets[px]
+		result = append(result, types.WsLevel{
+			Px: formatBookNumber(px),
+			Sz: formatBookNumber(bucket.sz),
+			N:  bucket.n,
+		})
+	}
+	return result
+}
+
+// roundToSigFigs rounds a price to nSigFigs significant figures, up or down
+func roundToSigFigs(px float64, nSigFigs int, up bool) float64 {
+	if nSigFigs <= 0 || px <= 0 {
+		return px
+	}
+	
+	exponent := int(math.Floor(math.Log10(px)))
+	decimals := nSigFigs - 1 - exponent
+	scale := math.Pow(10, float64(decimals))
+	// Nudge by a small epsilon so prices already on the grid stay put
+	scaled := px * scale
+	if up {
+		scaled = math.Ceil(scaled - 1e-9)
+	} else {
+		scaled = math.Floor(scaled + 1e-9)
+	}
+	return scaled / scale
+}
+
+// formatBookNumber formats a price or size without trailing zeros
+func formatBookNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 8, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
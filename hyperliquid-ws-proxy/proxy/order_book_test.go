@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOrderBookTakeConsumesBestLevelsFirst(t *testing.T) {
+	book := newOrderBook()
+	first := &restingOrder{px: 101, sz: 1}
+	second := &restingOrder{px: 101, sz: 2}
+	far := &restingOrder{px: 103, sz: 5}
+	for _, o := range []*restingOrder{first, second, far} {
+		book.add(o)
+	}
+	
+	// A buy up to 102 for 2 lifts the oldest order at 101 and half the next
+	taken := book.take(true, 102, 2)
+	if len(taken) != 1 || taken[0] != first {
+		t.Fatalf("taken = %v, want only the oldest order at 101", taken)
+	}
+	level := book.asks[101]
+	if level == nil || level.n != 1 || level.sz != 1 || second.sz != 1 {
+		t.Fatalf("level 101 = %+v, want 1 order of size 1 left", level)
+	}
+	
+	// The limit price bounds the levels taken
+	taken = book.take(true, 102, 10)
+	if len(taken) != 1 || taken[0] != second {
+		t.Fatalf("taken = %v, want the rest of level 101", taken)
+	}
+	if _, exists := book.asks[101]; exists {
+		t.Fatal("level 101 still on the book after being taken in full")
+	}
+	if level := book.asks[103]; level == nil || level.sz != 5 {
+		t.Fatalf("level 103 = %+v, want it untouched", level)
+	}
+}
+
+func TestOrderBookRemoveIgnoresTakenOrders(t *testing.T) {
+	book := newOrderBook()
+	taken := &restingOrder{isBuy: true, px: 100, sz: 1}
+	book.add(taken)
+	book.take(false, 99, 1)
+	other := &restingOrder{isBuy: true, px: 100, sz: 1}
+	book.add(other)
+	
+	// A late cancel of the taken order must not eat into the new one
+	book.remove(taken)
+	if level := book.bids[100]; level == nil || level.n != 1 || level.sz != 1 {
+		t.Fatalf("level 100 = %+v, want the new order alone", level)
+	}
+}
+
+func TestOrderBookCrosses(t *testing.T) {
+	book := newOrderBook()
+	book.add(&restingOrder{isBuy: true, px: 99, sz: 1})
+	book.add(&restingOrder{px: 101, sz: 1})
+	
+	cases := []struct {
+		isBuy bool
+		px    float64
+		want  bool
+	}{
+		{true, 100, false},
+		{true, 101, true},
+		{false, 100, false},
+		{false, 99, true},
+	}
+	for _, c := range cases {
+		if got := book.crosses(c.isBuy, c.px); got != c.want {
+			t.Errorf("crosses(isBuy=%v, %v) = %v, want %v", c.isBuy, c.px, got, c.want)
+		}
+	}
+}
+
+// decodeStatuses decodes the statuses of an order action response
+func decodeStatuses(t *testing.T, data string) []OrderStatus {
+	t.Helper()
+	var statuses []OrderStatus
+	if err := json.Unmarshal([]byte(data), &statuses); err != nil {
+		t.Fatalf("decoding statuses: %v", err)
+	}
+	return statuses
+}
+
+// limitOrder builds a good-til-cancel limit order on asset 0
+func limitOrder(isBuy bool, px, sz string) Order {
+	return Order{Asset: 0, IsBuy: isBuy, Price: px, Size: sz, OrderType: OrderType{Limit: &LimitOrderType{TIF: "Gtc"}}}
+}
+
+func TestFillLiftsRestingAsk(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	const blockTime = "2025-01-01T00:00:00.000"
+	coin := r.getAssetSymbol(0)
+	
+	r.processOrders([]Order{limitOrder(false, "101", "1")}, nil, decodeStatuses(t, `[{"resting":{"oid":1}}]`), blockTime, "0xmaker")
+	r.processOrders([]Order{limitOrder(true, "100", "1")}, nil, decodeStatuses(t, `[{"resting":{"oid":2}}]`), blockTime, "0xbidder")
+	if book := r.GetL2Book(coin, 0); book == nil || len(book.Levels[1]) != 1 {
+		t.Fatalf("book before the fill = %+v, want one ask", book)
+	}
+	
+	r.processOrders([]Order{limitOrder(true, "101", "1")}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"1","avgPx":"101","oid":3}}]`), blockTime, "0xtaker")
+	
+	book := r.GetL2Book(coin, 0)
+	if book == nil {
+		t.Fatal("book gone after the fill, want the bid left")
+	}
+	if len(book.Levels[1]) != 0 {
+		t.Fatalf("asks after the fill = %+v, want the lifted ask gone", book.Levels[1])
+	}
+	if len(book.Levels[0]) != 1 || book.Levels[0][0].Px != "100" {
+		t.Fatalf("bids after the fill = %+v, want the bid at 100 untouched", book.Levels[0])
+	}
+	if _, tracked := r.restingOids[1]; tracked {
+		t.Fatal("lifted ask still tracked by oid")
+	}
+}
+
+func TestCrossingOrderWithoutResponseDoesNotRest(t *testing.T) {
+	r := NewLocalNodeReader(t.TempDir(), nil, LocalNodeOptions{})
+	const blockTime = "2025-01-01T00:00:00.000"
+	coin := r.getAssetSymbol(0)
+	
+	r.processOrders([]Order{limitOrder(false, "101", "1")}, nil, nil, blockTime, "0xmaker")
+	r.processOrders([]Order{limitOrder(true, "102", "1")}, nil, nil, blockTime, "0xtaker")
+	
+	book := r.GetL2Book(coin, 0)
+	if book == nil || len(book.Levels[0]) != 0 {
+		t.Fatalf("book = %+v, want the crossing bid kept off it", book)
+	}
+}
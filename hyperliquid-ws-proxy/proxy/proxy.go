@@ -1,8 +1,12 @@
 package proxy
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,6 +23,18 @@ type Proxy struct {
 	hub           *client.Hub
 	hlConnector   *hyperliquid.Connector
 	
+	// Upstream status, paused between a disconnect and the resubscription that follows,
+	// ready once subscriptions were first restored after connecting
+	upstreamPaused bool
+	upstreamReady  bool
+	statusMu       sync.Mutex
+	
+	// Logs the first local node / configured network mismatch
+	networkWarnOnce sync.Once
+	
+	// Key of the trade user hashes sent to clients without an API key
+	tradeUsersKey []byte
+	
 	// Subscription management
 	globalSubscriptions map[string]*SubscriptionInfo
 	subMu              sync.RWMutex
@@ -30,6 +46,51 @@ type Proxy struct {
 	localNodeReader *LocalNodeReader
 	assetFetcher    *AssetFetcher
 	useLocalNode    bool
+	
+	// Last mid price sent per midPx subscription key (local node generator only)
+	lastMidPx map[string]string
+	
+	// Last allMids frame sent, to suppress identical snapshots
+	lastAllMids []byte
+	
+	// Local node data version the last messages were generated from
+	lastDataVersion int64
+	
+	// Last mid and top of book sent per coin on the midsBbo channel
+	lastMidsBbo map[string]types.WsMidBbo
+	
+	// Last fill forwarded per userFills subscription key, by order ID
+	lastUserFill map[string]int64
+	
+	// Last trade forwarded per coin, so each fill is sent once
+	lastTrade map[string]*types.WsTrade
+	
+	// Whether the trades firehose had subscribers on the last tick
+	tradesFirehose bool
+	
+	// Last l2Book levels sent per subscription key, to skip unchanged books
+	lastL2Book map[string]string
+	
+	// Last top of book sent per bbo subscription key, to skip unchanged ones
+	lastBbo map[string]string
+	
+	// Last asset context sent per activeAssetCtx subscription key, to skip unchanged ones
+	lastAssetCtx map[string]string
+	
+	// Last open candle sent per subscription key, to skip unchanged candles
+	lastCandle map[string]types.Candle
+	
+	// Limits concurrent in-flight POST requests (nil means unlimited)
+	postSem chan struct{}
+	
+	// Short-lived cache of info POST responses
+	infoCache *infoCache
+	
+	// Frames dropped because a client couldn't take them
+	drops *dropCounter
+	
+	// Last memory statistics read for /stats
+	memStats memStatsCache
 }
 
 // SubscriptionInfo tracks subscription details
@@ -38,6 +99,11 @@ type SubscriptionInfo struct {
 	Clients      map[*client.Client]bool
 	LastMessage  []byte
 	LastUpdate   time.Time
+	LastKeepalive time.Time
+	Pending      bool // coin not yet known to the AssetFetcher
+	UpstreamPending bool // upstream subscribe still in flight
+	AwaitingAck  map[*client.Client]int64 // clients waiting on Hyperliquid's acknowledgment, by subscription id (0 if already answered); nil once settled
+	ThrottledMessage []byte // latest message held back by the minimum update interval
 }
 
 // ProxyStats holds proxy statistics
@@ -47,34 +113,113 @@ type ProxyStats struct {
 	MessagesProcessed    int64
 	MessagesForwarded    int64
 	PostRequestsHandled  int64
+	PostRequestsInFlight int64
+	MessagesSuppressed   int64 // unchanged snapshots and throttled updates not sent
+	MessagesDropped      int64 // frames clients couldn't take
+	DroppedByChannel     map[string]int64
+	RetainedTrades       int
 	LastActivity         time.Time
 	StartTime            time.Time
+	SinceReset           StatsCounters // counts since LastReset
+	LastReset            time.Time     // start of the current stats window, StartTime until the first reset
+	resetBaseline        StatsCounters // cumulative counters at LastReset
 	mu                   sync.RWMutex
 }
 
+// SubscriptionSnapshot describes one active subscription for the /subscriptions endpoint
+type SubscriptionSnapshot struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Coin        string `json:"coin,omitempty"`
+	User        string `json:"user,omitempty"`
+	Interval    string `json:"interval,omitempty"`
+	ClientCount int    `json:"client_count"`
+	LastUpdate  int64  `json:"last_update"` // unix seconds, 0 if no data was received yet
+}
+
 // NewProxy creates a new proxy instance
 func NewProxy(cfg *config.Config) *Proxy {
+	startTime := time.Now()
 	p := &Proxy{
 		config:              cfg,
 		hub:                 client.NewHub(),
 		globalSubscriptions: make(map[string]*SubscriptionInfo),
 		useLocalNode:        cfg.Proxy.EnableLocalNode,
+		lastMidPx:           make(map[string]string),
+		lastL2Book:          make(map[string]string),
+		lastBbo:             make(map[string]string),
+		lastAssetCtx:        make(map[string]string),
+		lastTrade:           make(map[string]*types.WsTrade),
+		lastMidsBbo:         make(map[string]types.WsMidBbo),
+		lastUserFill:        make(map[string]int64),
+		lastCandle:          make(map[string]types.Candle),
+		infoCache:           newInfoCache(cfg.Proxy.InfoCacheDefaultTTLMs, cfg.Proxy.InfoCacheTTLMs),
+		drops:               newDropCounter(cfg.Logging.DropLogIntervalSec),
+		tradeUsersKey:       newTradeUsersKey(),
 		stats: ProxyStats{
-			StartTime: time.Now(),
+			StartTime: startTime,
+			LastReset: startTime,
 		},
 	}
 	
+	// Detach unregistering clients from all subscriptions before the hub closes their Send channel
+	p.hub.SetOnUnregister(p.removeClient)
+	
+	// Updates held behind an initial snapshot can still be dropped after it
+	p.hub.SetOnHeldDrop(func(c *client.Client, frame []byte) {
+		p.drops.record(frameChannel(frame), c.ID, "held")
+	})
+	
+	if cfg.Proxy.MaxConcurrentPosts > 0 {
+		p.postSem = make(chan struct{}, cfg.Proxy.MaxConcurrentPosts)
+	}
+	
 	// Initialize asset fetcher
-	p.assetFetcher = NewAssetFetcher()
+	p.assetFetcher = NewAssetFetcher(cfg.GetInfoURL())
+	p.assetFetcher.SetTimeouts(time.Duration(cfg.Hyperliquid.InfoTimeoutSec)*time.Second, time.Duration(cfg.Hyperliquid.InfoInitialTimeoutSec)*time.Second)
+	if cfg.Proxy.EnableLocalNode {
+		// Hyperliquid streams full contexts in remote mode
+		p.assetFetcher.SetAssetCtxInterval(time.Duration(cfg.Proxy.AssetCtxIntervalSec) * time.Second)
+	}
 	
 	// Initialize local node reader if enabled
 	if cfg.Proxy.EnableLocalNode {
 		logrus.Info("Local node mode enabled - will read data from local node instead of WebSocket API")
-		p.localNodeReader = NewLocalNodeReader(cfg.Proxy.LocalNodeDataPath, p.assetFetcher)
+		p.localNodeReader = NewLocalNodeReader(cfg.Proxy.LocalNodeDataPath, p.assetFetcher, LocalNodeOptions{
+			MaxTotalTrades:      cfg.Proxy.MaxTotalTrades,
+			MaxTradesPerCoin:    cfg.Proxy.MaxTradesPerCoin,
+			MaxBlocksInMemory:   cfg.Proxy.MaxBlocksInMemory,
+			TradeEvictionPolicy: cfg.Proxy.TradeEvictionPolicy,
+			AssetMapFile:        cfg.Proxy.AssetMapFile,
+			AssetMapOverride:    cfg.Proxy.AssetMapOverride,
+			ColdStartPolicy:     cfg.Proxy.ColdStartPolicy,
+			ColdStartTailBytes:  cfg.Proxy.ColdStartTailBytes,
+			DuplicateTIDPolicy:  cfg.Proxy.DuplicateTIDPolicy,
+			DuplicateTIDWindow:  time.Duration(cfg.Proxy.DuplicateTIDWindowSec) * time.Second,
+			CandleStateFile:     cfg.Proxy.CandleStateFile,
+			IncludeBuilderFees:  cfg.Proxy.IncludeBuilderFees,
+			CandleVolumeUnit:    cfg.Proxy.CandleVolumeUnit,
+			ReadPositionsFile:   cfg.Proxy.ReadPositionsFile,
+			ReadPositionsInterval: time.Duration(cfg.Proxy.ReadPositionsIntervalSec) * time.Second,
+			ReplayFrom:          cfg.GetReplayFrom(),
+		})
+		p.assetFetcher.SetOnUpdate(func() {
+			p.localNodeReader.ApplyLoadedDecimals()
+			p.activatePendingSubscriptions()
+		})
 	} else {
 		// Initialize Hyperliquid connector for remote API
 		logrus.Info("Remote API mode - will connect to Hyperliquid WebSocket API")
+		hyperliquid.SetDialRate(cfg.Proxy.UpstreamDialRate)
 		p.hlConnector = hyperliquid.NewConnector(cfg.GetHyperliquidURL())
+		p.hlConnector.SetCompression(cfg.Proxy.EnableCompression)
+		p.hlConnector.SetSubscribeBatch(cfg.Proxy.UpstreamSubscribeBatch)
+		p.hlConnector.SetReconnectPolicy(
+			cfg.Proxy.ReconnectMaxRetries,
+			time.Duration(cfg.Proxy.ReconnectInterval)*time.Second,
+			time.Duration(cfg.Proxy.ReconnectMaxDelay)*time.Second,
+		)
+		p.hlConnector.SetOnResubscribed(p.handleHyperliquidResubscribed)
 		p.hlConnector.SetEventHandlers(
 			p.handleHyperliquidMessage,
 			p.handleHyperliquidConnect,
@@ -109,11 +254,17 @@ func (p *Proxy) Start() error {
 		// Start local data processor
 		go p.processLocalNodeData()
 		
+		// Make sure the node is actually producing data before accepting clients
+		if err := p.verifyLocalNodeSource(); err != nil {
+			p.localNodeReader.Stop()
+			return err
+		}
+		
 		logrus.Info("Local node reader started successfully")
 	} else if p.hlConnector != nil {
 		// Connect to Hyperliquid WebSocket API
 		if err := p.hlConnector.Connect(); err != nil {
-			return fmt.Errorf("failed to connect to Hyperliquid: %v", err)
+			return fmt.Errorf("failed to connect to Hyperliquid at %s: %v (check network access and hyperliquid.network, or set proxy.enable_local_node with a valid local_node_data_path)", p.config.GetHyperliquidURL(), err)
 		}
 		
 		logrus.Info("Connected to Hyperliquid WebSocket API")
@@ -124,10 +275,68 @@ func (p *Proxy) Start() error {
 	// Start statistics updater
 	go p.updateStats()
 	
+	// Start since-reset stats windows if enabled
+	if p.config.Logging.StatsResetIntervalSec > 0 {
+		go p.runStatsReset(time.Duration(p.config.Logging.StatsResetIntervalSec) * time.Second)
+	}
+	
+	// Start per-subscription keepalives if enabled
+	if p.config.Proxy.SubscriptionKeepaliveSec > 0 {
+		go p.runSubscriptionKeepalive()
+	}
+	
+	// Start recycling long-lived connections if enabled
+	if p.config.Proxy.MaxConnectionDurationSec > 0 {
+		go p.runConnectionLifetime()
+	}
+	
+	// Start warning clients that fall behind if enabled
+	if p.config.Proxy.BackpressureAdvisorySec > 0 {
+		go p.runBackpressureAdvisory()
+	}
+	
 	logrus.Info("Proxy started successfully")
 	return nil
 }
 
+// verifyLocalNodeSource checks the local node data path is usable and, unless
+// the grace period is disabled, waits for the first block to be read
+func (p *Proxy) verifyLocalNodeSource() error {
+	dataPath := p.config.Proxy.LocalNodeDataPath
+	replicaCmdsPath := filepath.Join(dataPath, "replica_cmds")
+	if _, err := os.Stat(replicaCmdsPath); err != nil {
+		return fmt.Errorf("local node data source unusable: %v (set proxy.local_node_data_path to the node's data directory containing replica_cmds, or disable proxy.enable_local_node to use the Hyperliquid API)", err)
+	}
+	
+	// A node running as another user commonly leaves replica_cmds unreadable.
+	// Keep running rather than failing: /health reports not ready, and the
+	// reader keeps re-checking so fixing permissions takes effect without a restart.
+	if _, err := os.ReadDir(replicaCmdsPath); os.IsPermission(err) {
+		logrus.WithError(err).WithField("path", replicaCmdsPath).Error("Local node data is not readable by the proxy; serving no data until read access is granted")
+		return nil
+	}
+	
+	// Surface a data path naming the other network right away; the asset
+	// based check needs traffic and shows up in /health later
+	p.networkMismatch()
+	
+	grace := time.Duration(p.config.Proxy.DataSourceGraceSec) * time.Second
+	if grace <= 0 {
+		return nil
+	}
+	
+	logrus.WithField("grace", grace).Info("Waiting for first block from local node")
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if !p.localNodeReader.GetLastBlockTime().IsZero() {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	
+	return fmt.Errorf("no blocks read from %s within %s (is the node running with replica_cmds output enabled? raise proxy.data_source_grace_sec for slow disks)", replicaCmdsPath, grace)
+}
+
 // Stop stops the proxy
 func (p *Proxy) Stop() {
 	logrus.Info("Stopping proxy...")
@@ -163,12 +372,37 @@ func (p *Proxy) processLocalNodeData() {
 				return
 			}
 			
+			// Replayed blocks only warm the caches
+			if p.localNodeReader.IsReplaying() {
+				p.discardClosedCandles()
+				continue
+			}
+			
+			// Nothing changed since the last tick, e.g. only empty blocks arrived
+			version := p.localNodeReader.DataVersion()
+			if version == p.lastDataVersion {
+				continue
+			}
+			p.lastDataVersion = version
+			
 			// Generate WebSocket messages from local node data
 			p.generateLocalNodeMessages()
 		}
 	}
 }
 
+// discardClosedCandles drains the closed candles stream without forwarding them
+func (p *Proxy) discardClosedCandles() {
+	closed := p.localNodeReader.ClosedCandles()
+	for {
+		select {
+		case <-closed:
+		default:
+			return
+		}
+	}
+}
+
 // generateLocalNodeMessages generates WebSocket messages from local node data
 func (p *Proxy) generateLocalNodeMessages() {
 	// Generate allMids messages
@@ -176,6 +410,27 @@ func (p *Proxy) generateLocalNodeMessages() {
 	
 	// Generate trades messages for each coin
 	p.generateTradesFromLocalNode()
+	
+	// Generate single-coin mid price messages
+	p.generateMidPxFromLocalNode()
+	
+	// Generate order book messages from the reconstructed books
+	p.generateL2BookFromLocalNode()
+	
+	// Generate best bid and offer messages from the same books
+	p.generateBboFromLocalNode()
+	
+	// Generate asset context messages from validator prices, fills and books
+	p.generateAssetCtxFromLocalNode()
+	
+	// Generate candle messages from the aggregated trades
+	p.generateCandlesFromLocalNode()
+	
+	// Generate combined mid and top of book messages
+	p.generateMidsBboFromLocalNode()
+	
+	// Generate incremental user fills
+	p.generateUserFillsFromLocalNode()
 }
 
 // generateAllMidsFromLocalNode generates allMids messages from local node data
@@ -197,6 +452,7 @@ func (p *Proxy) generateAllMidsFromLocalNode() {
 	
 	if !hasAllMidsSubscribers {
 		logrus.Debug("No allMids subscribers, skipping generation")
+		p.lastAllMids = nil
 		return
 	}
 	
@@ -253,6 +509,13 @@ func (p *Proxy) generateAllMidsFromLocalNode() {
 		return
 	}
 	
+	// Map keys marshal sorted, so unchanged prices give an identical frame
+	if bytes.Equal(messageBytes, p.lastAllMids) {
+		p.addSuppressedMessages(1)
+		return
+	}
+	p.lastAllMids = messageBytes
+	
 	// Forward to clients subscribed to allMids
 	p.forwardMessageToClients("allMids", messageBytes)
 	
@@ -266,191 +529,889 @@ func (p *Proxy) generateAllMidsFromLocalNode() {
 func (p *Proxy) generateTradesFromLocalNode() {
 	// Check which coins have trade subscribers
 	coinsWithSubscribers := make(map[string]bool)
+	firehose := false
 	p.subMu.RLock()
 	for _, subInfo := range p.globalSubscriptions {
 		if subInfo.Subscription.Type == "trades" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			if isTradesFirehose(subInfo.Subscription) {
+				firehose = true
+				continue
+			}
 			coinsWithSubscribers[subInfo.Subscription.Coin] = true
 		}
 	}
 	p.subMu.RUnlock()
 	
+	// The firehose covers every coin with fills. When it starts, coins only it
+	// covers record their latest fill rather than each sending a stale one.
+	firehoseStarting := firehose && !p.tradesFirehose
+	p.tradesFirehose = firehose
+	var firehoseOnly map[string]bool
+	if firehose {
+		firehoseOnly = make(map[string]bool)
+		for _, coin := range p.localNodeReader.GetTradeCoins() {
+			if !coinsWithSubscribers[coin] {
+				firehoseOnly[coin] = true
+				coinsWithSubscribers[coin] = true
+			}
+		}
+	}
+	
+	// Forget coins nobody is subscribed to anymore
+	for coin := range p.lastTrade {
+		if !coinsWithSubscribers[coin] {
+			delete(p.lastTrade, coin)
+		}
+	}
+	
 	// Generate trades for subscribed coins
 	for coin := range coinsWithSubscribers {
-		trades := p.localNodeReader.GetLatestTrades(coin, 10) // Get last 10 trades
+		trades := p.localNodeReader.GetRealTrades(coin, 0)
 		if len(trades) == 0 {
 			continue
 		}
 		
-		// Send the most recent trade as a trades message
-		latestTrade := trades[len(trades)-1]
-		
-		tradesMessage := map[string]interface{}{
-			"channel": "trades",
-			"data": map[string]interface{}{
-				"coin":  latestTrade.Coin,
-				"side":  latestTrade.Side,
-				"px":    latestTrade.Px,
-				"sz":    latestTrade.Sz,
-				"time":  latestTrade.Time,
-				"hash":  latestTrade.Hash,
-				"tid":   latestTrade.TID,
-				"users": latestTrade.Users,
-			},
+		// Forward the fills recorded since the last tick. When the last forwarded
+		// fill is unknown or was evicted, only the most recent one is sent; history
+		// goes out in the initial snapshot.
+		start := len(trades) - 1
+		last, ok := p.lastTrade[coin]
+		if !ok && firehoseStarting && firehoseOnly[coin] {
+			p.lastTrade[coin] = trades[len(trades)-1]
+			continue
+		}
+		if ok {
+			for i := len(trades) - 1; i >= 0; i-- {
+				if trades[i] == last {
+					start = i + 1
+					break
+				}
+			}
 		}
 		
-		messageBytes, err := json.Marshal(tradesMessage)
-		if err != nil {
-			logrus.WithError(err).Error("Failed to marshal trades message")
+		for _, trade := range trades[start:] {
+			messageBytes, err := p.buildTradeMessage(trade)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to marshal trades message")
+				continue
+			}
+			
+			// Forward to clients subscribed to this coin's trades, or to the firehose of its market
+			p.forwardMessage(messageBytes, func(key string, sub *types.SubscriptionRequest) bool {
+				if isTradesFirehose(sub) {
+					return p.inMarket(sub.Market, coin)
+				}
+				return sub.Type == "trades" && sub.Coin == coin
+			})
+			
+			logrus.WithFields(logrus.Fields{
+				"coin":  coin,
+				"side":  trade.Side,
+				"price": trade.Px,
+			}).Debug("Generated trade from local node")
+		}
+		p.lastTrade[coin] = trades[len(trades)-1]
+	}
+}
+
+// buildTradeMessage builds a trades channel message, adding the trade's
+// notional value when trade enrichment is enabled
+func (p *Proxy) buildTradeMessage(trade *types.WsTrade) ([]byte, error) {
+	data := map[string]interface{}{
+		"coin":  trade.Coin,
+		"side":  trade.Side,
+		"px":    trade.Px,
+		"sz":    trade.Sz,
+		"time":  trade.Time,
+		"hash":  trade.Hash,
+		"tid":   trade.TID,
+		"users": trade.Users,
+	}
+	
+	if p.config.Proxy.EnrichTradesNotional {
+		if notional, ok := computeNotional(trade.Px, trade.Sz); ok {
+			data["notional"] = notional
+		}
+	}
+	
+	return json.Marshal(map[string]interface{}{
+		"channel": "trades",
+		"data":    data,
+	})
+}
+
+// generateMidPxFromLocalNode emits the mid price of each midPx-subscribed coin when it changes
+func (p *Proxy) generateMidPxFromLocalNode() {
+	subscribedCoins := make(map[string]string) // subscription key -> coin
+	p.subMu.RLock()
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "midPx" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			subscribedCoins[key] = subInfo.Subscription.Coin
+		}
+	}
+	p.subMu.RUnlock()
+	
+	// Forget coins nobody is subscribed to anymore
+	for key := range p.lastMidPx {
+		if _, ok := subscribedCoins[key]; !ok {
+			delete(p.lastMidPx, key)
+		}
+	}
+	
+	for key, coin := range subscribedCoins {
+		mid, exists := p.localNodeReader.GetLatestPrice(coin)
+		if !exists || p.lastMidPx[key] == mid {
 			continue
 		}
 		
-		// Forward to clients subscribed to this coin's trades
-		p.forwardMessageToClients("trades", messageBytes)
+		messageBytes, err := p.buildMidPxMessage(coin, mid)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal midPx message")
+			continue
+		}
 		
-		logrus.WithFields(logrus.Fields{
-			"coin":  coin,
-			"side":  latestTrade.Side,
-			"price": latestTrade.Px,
-		}).Debug("Generated trade from local node")
+		p.lastMidPx[key] = mid
+		p.forwardMessageToSubscription(key, messageBytes)
 	}
 }
 
-// GetHub returns the client hub
-func (p *Proxy) GetHub() *client.Hub {
-	return p.hub
+// buildMidPxMessage builds a midPx channel message for a single coin
+func (p *Proxy) buildMidPxMessage(coin, mid string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "midPx",
+		"data": types.WsMidPx{
+			Coin: coin,
+			Mid:  mid,
+		},
+	})
 }
 
-// GetStats returns proxy statistics
-func (p *Proxy) GetStats() ProxyStats {
-	p.stats.mu.RLock()
-	defer p.stats.mu.RUnlock()
-	
-	stats := p.stats
-	stats.ConnectedClients = p.hub.GetClientCount()
-	
+// generateUserFillsFromLocalNode sends each userFills subscription the fills
+// recorded since the last tick. A subscription first seen here only records its
+// latest fill, as its snapshot was sent on subscribe; 0 marks a user without fills.
+func (p *Proxy) generateUserFillsFromLocalNode() {
+	subscribed := make(map[string]string) // subscription key -> user
 	p.subMu.RLock()
-	stats.ActiveSubscriptions = len(p.globalSubscriptions)
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "userFills" && subInfo.Subscription.User != "" && len(subInfo.Clients) > 0 {
+			subscribed[key] = subInfo.Subscription.User
+		}
+	}
 	p.subMu.RUnlock()
 	
-	return stats
-}
-
-// processClientMessages processes messages from clients
-func (p *Proxy) processClientMessages() {
-	for {
-		select {
-		case clientMsg := <-p.hub.ClientMessage:
-			p.handleClientMessage(clientMsg.Client, clientMsg.Message)
+	// Forget users nobody is subscribed to anymore
+	for key := range p.lastUserFill {
+		if _, ok := subscribed[key]; !ok {
+			delete(p.lastUserFill, key)
+		}
+	}
+	
+	for key, user := range subscribed {
+		userFills := p.localNodeReader.GetUserFills(user, 0)
+		if userFills == nil {
+			if _, tracked := p.lastUserFill[key]; !tracked {
+				p.lastUserFill[key] = 0
+			}
+			continue
+		}
+		fills := userFills.Fills
+		latest := fills[len(fills)-1].OID
+		
+		last, tracked := p.lastUserFill[key]
+		p.lastUserFill[key] = latest
+		if !tracked || last == latest {
+			continue
+		}
+		
+		// Fills after the last one sent; all retained fills if it was evicted
+		start := 0
+		for i := len(fills) - 1; i >= 0; i-- {
+			if fills[i].OID == last {
+				start = i + 1
+				break
+			}
+		}
+		userFills.Fills = fills[start:]
+		
+		messageBytes, err := p.buildUserFillsMessage(userFills)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal userFills message")
+			continue
 		}
+		p.forwardMessageToSubscription(key, messageBytes)
 	}
 }
 
-// handleClientMessage handles a message from a client
-func (p *Proxy) handleClientMessage(c *client.Client, data []byte) {
-	p.updateStatsActivity()
+// buildUserFillsMessage builds a userFills channel message
+func (p *Proxy) buildUserFillsMessage(fills *types.WsUserFills) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "userFills",
+		"data":    fills,
+	})
+}
+
+// generateMidsBboFromLocalNode sends the coins whose mid, bid or ask changed since the last tick
+func (p *Proxy) generateMidsBboFromLocalNode() {
+	hasSubscribers := false
+	p.subMu.RLock()
+	for _, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "midsBbo" && len(subInfo.Clients) > 0 {
+			hasSubscribers = true
+			break
+		}
+	}
+	p.subMu.RUnlock()
 	
-	var msg types.WSMessage
-	if err := json.Unmarshal(data, &msg); err != nil {
-		logrus.WithError(err).Error("Failed to parse client message")
-		p.sendErrorToClient(c, "Invalid message format")
+	if !hasSubscribers {
+		// New subscribers get a full snapshot, so start over once they come
+		p.lastMidsBbo = make(map[string]types.WsMidBbo)
 		return
 	}
 	
-	switch msg.Method {
-	case "subscribe":
-		p.handleSubscribe(c, msg.Subscription)
-	case "unsubscribe":
-		p.handleUnsubscribe(c, msg.Subscription)
-	case "post":
-		p.handlePostRequest(c, &msg)
-	default:
-		logrus.WithField("method", msg.Method).Warn("Unknown method")
-		p.sendErrorToClient(c, "Unknown method: "+msg.Method)
+	changed := make(map[string]types.WsMidBbo)
+	for coin, entry := range p.localNodeReader.GetAllMidsBbo() {
+		if last, sent := p.lastMidsBbo[coin]; !sent || last != entry {
+			changed[coin] = entry
+			p.lastMidsBbo[coin] = entry
+		}
 	}
-}
-
-// handleSubscribe handles subscription requests
-func (p *Proxy) handleSubscribe(c *client.Client, sub *types.SubscriptionRequest) {
-	if sub == nil {
-		p.sendErrorToClient(c, "Missing subscription details")
+	if len(changed) == 0 {
 		return
 	}
 	
-	logrus.WithFields(logrus.Fields{
-		"client_id": c.ID,
-		"type":      sub.Type,
-		"coin":      sub.Coin,
-		"user":      sub.User,
-		"local_node": p.useLocalNode,
-	}).Debug("Handling subscription")
+	messageBytes, err := p.buildMidsBboMessage(changed)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal midsBbo message")
+		return
+	}
+	p.forwardMessageToClients("midsBbo", messageBytes)
+}
+
+// buildMidsBboMessage builds a midsBbo channel message
+func (p *Proxy) buildMidsBboMessage(mids map[string]types.WsMidBbo) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "midsBbo",
+		"data":    types.MidsBbo{Mids: mids},
+	})
+}
+
+// generateL2BookFromLocalNode sends l2Book messages for subscribed coins whose book changed
+func (p *Proxy) generateL2BookFromLocalNode() {
+	subscribed := make(map[string]*types.SubscriptionRequest) // subscription key -> subscription
+	p.subMu.RLock()
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "l2Book" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			subscribed[key] = subInfo.Subscription
+		}
+	}
+	p.subMu.RUnlock()
 	
-	// Create subscription key
-	key := p.createSubscriptionKey(sub)
+	// Forget books nobody is subscribed to anymore
+	for key := range p.lastL2Book {
+		if _, ok := subscribed[key]; !ok {
+			delete(p.lastL2Book, key)
+		}
+	}
 	
-	// Add client to subscription
-	p.subMu.Lock()
-	subInfo, exists := p.globalSubscriptions[key]
-	if !exists {
-		subInfo = &SubscriptionInfo{
-			Subscription: sub,
-			Clients:      make(map[*client.Client]bool),
-			LastUpdate:   time.Now(),
+	for key, sub := range subscribed {
+		book := p.localNodeReader.GetL2Book(sub.Coin, nSigFigs(sub))
+		if book == nil {
+			continue
 		}
-		p.globalSubscriptions[key] = subInfo
 		
-		// Subscribe to Hyperliquid only if not using local node
-		if !p.useLocalNode && p.hlConnector != nil {
-			go func() {
-				if err := p.hlConnector.Subscribe(sub); err != nil {
-					logrus.WithError(err).Error("Failed to subscribe to Hyperliquid")
-					p.sendErrorToClient(c, "Failed to subscribe: "+err.Error())
-					
-					// Remove the subscription since it failed
-					p.subMu.Lock()
-					delete(p.globalSubscriptions, key)
-					p.subMu.Unlock()
-					return
-				}
-			}()
-		} else {
-			logrus.WithField("subscription_type", sub.Type).Debug("Using local node data for subscription")
+		levels, err := json.Marshal(book.Levels)
+		if err != nil || p.lastL2Book[key] == string(levels) {
+			continue
+		}
+		
+		messageBytes, err := p.buildL2BookMessage(book)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal l2Book message")
+			continue
 		}
+		
+		p.lastL2Book[key] = string(levels)
+		p.forwardMessageToSubscription(key, messageBytes)
 	}
+}
+
+// generateBboFromLocalNode sends bbo messages for subscribed coins whose best bid or ask changed
+func (p *Proxy) generateBboFromLocalNode() {
+	subscribed := make(map[string]string) // subscription key -> coin
+	p.subMu.RLock()
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "bbo" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			subscribed[key] = subInfo.Subscription.Coin
+		}
+	}
+	p.subMu.RUnlock()
 	
-	subInfo.Clients[c] = true
-	p.subMu.Unlock()
-	
-	// Add subscription to client
-	c.AddSubscription(key, sub)
-	
-	// Send subscription response
-	response := types.WSMessage{
-		Channel: "subscriptionResponse",
-		Data:    json.RawMessage(fmt.Sprintf(`{"method":"subscribe","subscription":%s}`, p.toJSON(sub))),
+	// Forget coins nobody is subscribed to anymore
+	for key := range p.lastBbo {
+		if _, ok := subscribed[key]; !ok {
+			delete(p.lastBbo, key)
+		}
 	}
-	c.SendMessage(response)
 	
-	// Send initial data if using local node
-	if p.useLocalNode && p.localNodeReader != nil {
-		p.sendInitialLocalNodeData(c, sub)
-	} else if subInfo.LastMessage != nil {
-		// Send last message if available from remote API
-		c.Send <- subInfo.LastMessage
+	for key, coin := range subscribed {
+		bbo := p.localNodeReader.GetBBO(coin)
+		if bbo == nil {
+			continue
+		}
+		
+		top, err := json.Marshal(bbo.BBO)
+		if err != nil || p.lastBbo[key] == string(top) {
+			continue
+		}
+		
+		messageBytes, err := p.buildBboMessage(bbo)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal bbo message")
+			continue
+		}
+		
+		p.lastBbo[key] = string(top)
+		p.forwardMessageToSubscription(key, messageBytes)
 	}
 }
 
-// sendInitialLocalNodeData sends initial data from local node to a newly subscribed client
-func (p *Proxy) sendInitialLocalNodeData(c *client.Client, sub *types.SubscriptionRequest) {
-	switch sub.Type {
-	case "allMids":
-		// Send ALL current prices (not just a fixed list!)
-		allPrices := p.localNodeReader.GetAllLatestPrices()
-		
-		logrus.WithFields(logrus.Fields{
-			"client_id": c.ID,
-			"prices_count": len(allPrices),
-		}).Info("=== SENDING INITIAL allMids to new client ===")
-		
+// buildBboMessage builds a bbo channel message
+func (p *Proxy) buildBboMessage(bbo *types.WsBbo) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "bbo",
+		"data":    bbo,
+	})
+}
+
+// generateAssetCtxFromLocalNode sends activeAssetCtx messages for subscribed coins whose context changed
+func (p *Proxy) generateAssetCtxFromLocalNode() {
+	subscribed := make(map[string]string) // subscription key -> coin
+	p.subMu.RLock()
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "activeAssetCtx" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			subscribed[key] = subInfo.Subscription.Coin
+		}
+	}
+	p.subMu.RUnlock()
+	
+	// Forget coins nobody is subscribed to anymore
+	for key := range p.lastAssetCtx {
+		if _, ok := subscribed[key]; !ok {
+			delete(p.lastAssetCtx, key)
+		}
+	}
+	
+	for key, coin := range subscribed {
+		ctx := p.localNodeReader.GetAssetCtx(coin)
+		if ctx == nil {
+			continue
+		}
+		
+		messageBytes, err := p.buildAssetCtxMessage(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal activeAssetCtx message")
+			continue
+		}
+		if p.lastAssetCtx[key] == string(messageBytes) {
+			continue
+		}
+		
+		p.lastAssetCtx[key] = string(messageBytes)
+		p.forwardMessageToSubscription(key, messageBytes)
+	}
+}
+
+// buildAssetCtxMessage builds an asset context message, on the activeSpotAssetCtx
+// channel for spot pairs as Hyperliquid does and on activeAssetCtx otherwise
+func (p *Proxy) buildAssetCtxMessage(ctx interface{}) ([]byte, error) {
+	channel := "activeAssetCtx"
+	if _, spot := ctx.(*types.WsActiveSpotAssetCtx); spot {
+		channel = "activeSpotAssetCtx"
+	}
+	return json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"data":    ctx,
+	})
+}
+
+// nSigFigs returns the aggregation requested by an l2Book subscription, 0 for full precision
+func nSigFigs(sub *types.SubscriptionRequest) int {
+	if sub.NSigFigs == nil {
+		return 0
+	}
+	return *sub.NSigFigs
+}
+
+// buildL2BookMessage builds an l2Book channel message
+func (p *Proxy) buildL2BookMessage(book *types.WsBook) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "l2Book",
+		"data":    book,
+	})
+}
+
+// generateCandlesFromLocalNode forwards candles closed since the last tick, then
+// sends the open candle of each candle subscription when it changed
+func (p *Proxy) generateCandlesFromLocalNode() {
+	// Drain the closed candles even without subscribers so the stream never backs up
+	closed := p.localNodeReader.ClosedCandles()
+	for drained := false; !drained; {
+		select {
+		case candle := <-closed:
+			sub := types.SubscriptionRequest{Type: "candle", Coin: candle.S, Interval: candle.I}
+			if messageBytes, err := p.buildCandleMessage(candle); err == nil {
+				p.forwardMessageToSubscription(sub.Key(), messageBytes)
+			}
+		default:
+			drained = true
+		}
+	}
+	
+	subscribed := make(map[string]*types.SubscriptionRequest) // subscription key -> subscription
+	p.subMu.RLock()
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Subscription.Type == "candle" && subInfo.Subscription.Coin != "" && len(subInfo.Clients) > 0 {
+			subscribed[key] = subInfo.Subscription
+		}
+	}
+	p.subMu.RUnlock()
+	
+	// Forget candles nobody is subscribed to anymore
+	for key := range p.lastCandle {
+		if _, ok := subscribed[key]; !ok {
+			delete(p.lastCandle, key)
+		}
+	}
+	
+	for key, sub := range subscribed {
+		candle := p.localNodeReader.GetCandle(sub.Coin, sub.Interval)
+		if candle == nil {
+			continue
+		}
+		if last, sent := p.lastCandle[key]; sent && last == *candle {
+			continue
+		}
+		
+		messageBytes, err := p.buildCandleMessage(candle)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal candle message")
+			continue
+		}
+		
+		p.lastCandle[key] = *candle
+		p.forwardMessageToSubscription(key, messageBytes)
+	}
+}
+
+// buildCandleMessage builds a candle channel message
+func (p *Proxy) buildCandleMessage(candle *types.Candle) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"channel": "candle",
+		"data":    candle,
+	})
+}
+
+// GetHub returns the client hub
+func (p *Proxy) GetHub() *client.Hub {
+	return p.hub
+}
+
+// GetStats returns proxy statistics
+func (p *Proxy) GetStats() ProxyStats {
+	p.stats.mu.RLock()
+	defer p.stats.mu.RUnlock()
+	
+	p.subMu.RLock()
+	activeSubscriptions := len(p.globalSubscriptions)
+	p.subMu.RUnlock()
+	
+	retainedTrades := 0
+	if p.localNodeReader != nil {
+		retainedTrades = p.localNodeReader.GetTotalTrades()
+	}
+	
+	messagesDropped, droppedByChannel := p.drops.totals()
+	counters := p.statsCounters()
+	
+	return ProxyStats{
+		ConnectedClients:    p.hub.GetClientCount(),
+		ActiveSubscriptions: activeSubscriptions,
+		MessagesProcessed:   p.stats.MessagesProcessed,
+		MessagesForwarded:   p.stats.MessagesForwarded,
+		PostRequestsHandled: p.stats.PostRequestsHandled,
+		PostRequestsInFlight: p.stats.PostRequestsInFlight,
+		MessagesSuppressed:  p.stats.MessagesSuppressed,
+		MessagesDropped:     messagesDropped,
+		DroppedByChannel:    droppedByChannel,
+		RetainedTrades:      retainedTrades,
+		LastActivity:        p.stats.LastActivity,
+		StartTime:           p.stats.StartTime,
+		SinceReset:          counters.since(p.stats.resetBaseline),
+		LastReset:           p.stats.LastReset,
+	}
+}
+
+// UpstreamConnected reports whether the Hyperliquid WebSocket is connected.
+// ok is false in local node mode, where there is no upstream connection.
+func (p *Proxy) UpstreamConnected() (connected bool, ok bool) {
+	if p.hlConnector == nil {
+		return false, false
+	}
+	return p.hlConnector.IsConnected(), true
+}
+
+// UpstreamRefCounts returns the references the Hyperliquid connector holds per
+// subscription key. ok is false in local node mode, where there is no upstream connection.
+func (p *Proxy) UpstreamRefCounts() (refCounts map[string]int, ok bool) {
+	if p.hlConnector == nil {
+		return nil, false
+	}
+	return p.hlConnector.GetSubscriptionRefCounts(), true
+}
+
+// GetSubscriptionSnapshot returns the active subscriptions sorted by key
+func (p *Proxy) GetSubscriptionSnapshot() []SubscriptionSnapshot {
+	p.subMu.RLock()
+	snapshot := make([]SubscriptionSnapshot, 0, len(p.globalSubscriptions))
+	for key, info := range p.globalSubscriptions {
+		entry := SubscriptionSnapshot{
+			Key:         key,
+			ClientCount: len(info.Clients),
+		}
+		if info.Subscription != nil {
+			entry.Type = info.Subscription.Type
+			entry.Coin = info.Subscription.Coin
+			entry.User = info.Subscription.User
+			entry.Interval = info.Subscription.Interval
+		}
+		if !info.LastUpdate.IsZero() {
+			entry.LastUpdate = info.LastUpdate.Unix()
+		}
+		snapshot = append(snapshot, entry)
+	}
+	p.subMu.RUnlock()
+	
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Key < snapshot[j].Key
+	})
+	return snapshot
+}
+
+// processClientMessages processes messages from clients
+func (p *Proxy) processClientMessages() {
+	for {
+		select {
+		case clientMsg := <-p.hub.ClientMessage:
+			// Binary frames are only understood by binary codecs
+			if clientMsg.Binary && !clientMsg.Client.Codec.Binary {
+				p.rejectBinaryFrame(clientMsg.Client, len(clientMsg.Message))
+				continue
+			}
+			p.handleClientMessage(clientMsg.Client, clientMsg.Message)
+		}
+	}
+}
+
+// rejectBinaryFrame answers a binary frame from a client using a text codec
+func (p *Proxy) rejectBinaryFrame(c *client.Client, size int) {
+	logrus.WithFields(logrus.Fields{
+		"client_id": c.ID,
+		"encoding":  c.Codec.Name,
+		"bytes":     size,
+	}).Warn("Rejected binary frame from client")
+	p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, fmt.Sprintf("Binary frames are not accepted with the %s encoding, send requests as JSON text frames", c.Codec.Name), false))
+}
+
+// handleClientMessage handles a message from a client
+func (p *Proxy) handleClientMessage(c *client.Client, data []byte) {
+	p.updateStatsActivity()
+	
+	var msg types.WSMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logrus.WithError(err).WithField("client_id", c.ID).Warn("Failed to parse client message")
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, "Invalid message format, expected a JSON object such as {\"method\":\"subscribe\",\"subscription\":{...}}", false))
+		return
+	}
+	
+	// Namespaced clients address channels as "<namespace>:<type>"
+	if msg.Subscription != nil {
+		msg.Subscription.Type = c.StripNamespace(msg.Subscription.Type)
+		
+		// Subscriptions are keyed by resolved names whatever form the client uses
+		if c.SymbolFormat == SymbolFormatRaw {
+			if msg.Subscription.Coin != "" {
+				msg.Subscription.Coin = p.ResolveSymbol(msg.Subscription.Coin)
+			}
+			for i, coin := range msg.Subscription.Coins {
+				msg.Subscription.Coins[i] = p.ResolveSymbol(coin)
+			}
+		}
+	}
+	
+	switch msg.Method {
+	case "subscribe":
+		p.handleSubscribe(c, msg.Subscription)
+	case "unsubscribe":
+		if msg.Subscription == nil && msg.SubscriptionID != nil {
+			sub, exists := c.GetSubscriptionByID(*msg.SubscriptionID)
+			if !exists {
+				p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown subscription id %d", *msg.SubscriptionID), false))
+				return
+			}
+			msg.Subscription = sub
+		}
+		p.handleUnsubscribe(c, msg.Subscription)
+	case "post":
+		p.handlePostRequest(c, &msg)
+	default:
+		logrus.WithField("method", msg.Method).Warn("Unknown method")
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, "Unknown method: "+msg.Method, false))
+	}
+}
+
+// handleSubscribe handles subscription requests
+func (p *Proxy) handleSubscribe(c *client.Client, sub *types.SubscriptionRequest) {
+	// Reject unknown types and requests missing a field their channel needs
+	// before any subscription state is touched
+	if err := types.ValidateSubscription(sub); err != nil {
+		p.sendErrorToClient(c, types.AsProxyError(err))
+		return
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"client_id": c.ID,
+		"type":      sub.Type,
+		"coin":      sub.Coin,
+		"user":      sub.User,
+		"local_node": p.useLocalNode,
+	}).Debug("Handling subscription")
+	
+	// midPx is generated by the proxy itself from the local node's data
+	if sub.Type == "midPx" && !p.useLocalNode {
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeUnsupported, "midPx subscription is only available in local node mode", false))
+		return
+	}
+	
+	// midsBbo is built from the local node's price cache and order books
+	if sub.Type == "midsBbo" && !p.useLocalNode {
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeUnsupported, "midsBbo subscription is only available in local node mode", false))
+		return
+	}
+	
+	if proxyErr := p.validateMarketFilter(sub); proxyErr != nil {
+		p.sendErrorToClient(c, proxyErr)
+		return
+	}
+	
+	if len(sub.Coins) > 0 {
+		p.handleBatchSubscribe(c, sub)
+		return
+	}
+	
+	// Create subscription key
+	key := sub.Key()
+	
+	// Claim the client's subscription slot first so a client at its limit never registers globally
+	maxSubs := p.config.Proxy.MaxSubscriptionsPerClient
+	subscriptionID, count, ok := c.TryAddSubscription(key, sub, maxSubs)
+	if !ok {
+		p.sendErrorToClient(c, subscriptionLimitError(count, maxSubs))
+		return
+	}
+	
+	added := p.addSubscriber(c, key, sub, subscriptionID)
+	
+	// Send subscription response with the id the client can unsubscribe with,
+	// unless it is relayed from Hyperliquid once the upstream subscribe is acknowledged
+	if !added.awaitsAck {
+		p.sendSubscriptionResponse(c, nil, sub, subscriptionID)
+	}
+	
+	p.startSubscription(c, added)
+}
+
+// subscriptionLimitError is the error sent to a client whose subscribe would
+// take it past its subscription limit
+func subscriptionLimitError(count, maxSubs int) *types.ProxyError {
+	proxyErr := types.NewProxyError(types.ErrCodeLimitExceeded, fmt.Sprintf("Subscription limit reached (%d/%d)", count, maxSubs), false)
+	proxyErr.Details = map[string]interface{}{
+		"subscriptions":     count,
+		"max_subscriptions": maxSubs,
+	}
+	return proxyErr
+}
+
+// addedSubscriber is a client just added to a global subscription, whose
+// upstream subscribe and initial data are handled by startSubscription once
+// the client got its subscriptionResponse
+type addedSubscriber struct {
+	key           string
+	sub           *types.SubscriptionRequest
+	info          *SubscriptionInfo
+	needsUpstream bool
+	awaitsAck     bool // the subscriptionResponse waits on Hyperliquid's acknowledgment
+	pending       bool
+	lastMessage   []byte
+}
+
+// addSubscriber adds a client to the global subscription for key, creating it
+// if this is its first client. subscriptionID is the id the client's
+// subscriptionResponse carries, 0 if the client is answered separately.
+func (p *Proxy) addSubscriber(c *client.Client, key string, sub *types.SubscriptionRequest, subscriptionID int64) *addedSubscriber {
+	added := &addedSubscriber{key: key, sub: sub}
+	
+	p.subMu.Lock()
+	subInfo, exists := p.globalSubscriptions[key]
+	if !exists {
+		// Client-only flags stay out of the shared subscription sent upstream
+		shared := *sub
+		shared.CompressSnapshot = false
+		
+		subInfo = &SubscriptionInfo{
+			Subscription: &shared,
+			Clients:      make(map[*client.Client]bool),
+			LastUpdate:   time.Now(),
+		}
+		p.globalSubscriptions[key] = subInfo
+		
+		// A coin the local node can't resolve yet stays pending until a metadata refresh lists it
+		if p.useLocalNode && p.isUnknownCoin(sub) {
+			subInfo.Pending = true
+		}
+		
+		// Subscribe to Hyperliquid only if not using local node
+		if !p.useLocalNode && p.hlConnector != nil {
+			added.needsUpstream = true
+			subInfo.UpstreamPending = true
+			subInfo.AwaitingAck = make(map[*client.Client]int64)
+		} else {
+			logrus.WithField("subscription_type", sub.Type).Debug("Using local node data for subscription")
+		}
+	}
+	
+	// Register the client before subscribing upstream so the first frames
+	// Hyperliquid sends for this subscription already have a recipient
+	subInfo.Clients[c] = true
+	if subInfo.AwaitingAck != nil {
+		subInfo.AwaitingAck[c] = subscriptionID
+		added.awaitsAck = subscriptionID != 0
+	}
+	added.info = subInfo
+	added.pending = subInfo.Pending
+	added.lastMessage = subInfo.LastMessage
+	p.subMu.Unlock()
+	
+	return added
+}
+
+// startSubscription subscribes upstream if needed and sends the client its
+// initial data, or tells it the subscription is pending
+func (p *Proxy) startSubscription(c *client.Client, added *addedSubscriber) {
+	sub := added.sub
+	
+	if added.needsUpstream {
+		go p.subscribeUpstream(added.key, added.info)
+	}
+	
+	if added.pending {
+		logrus.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"type":      sub.Type,
+			"coin":      sub.Coin,
+		}).Info("Subscription pending until coin appears in asset metadata")
+		p.sendNotificationToClient(c, fmt.Sprintf("Subscription to %s for %s is pending: coin not listed yet", sub.Type, sub.Coin))
+		return
+	}
+	
+	// Send initial data if using local node
+	snapshot := &snapshotBatch{}
+	if p.useLocalNode && p.localNodeReader != nil {
+		p.addInitialLocalNodeData(c, sub, snapshot)
+		p.addSnapshotComplete(snapshot, sub)
+	} else if added.lastMessage != nil {
+		// Send last message if available from remote API
+		if sub.Type == "l2Book" {
+			p.addL2BookSnapshot(snapshot, sub, added.lastMessage)
+		} else {
+			snapshot.add(added.lastMessage)
+		}
+		
+		// A new upstream subscription gets its marker once Hyperliquid's snapshot arrives
+		if !added.needsUpstream {
+			p.addSnapshotComplete(snapshot, sub)
+		}
+	}
+	p.sendSnapshot(c, snapshot)
+}
+
+// subscribeUpstream subscribes to Hyperliquid for a newly registered
+// subscription, removing it again if the upstream subscribe fails. If every
+// client left while the subscribe was in flight, the upstream subscription is
+// undone right away instead of being left without recipients.
+func (p *Proxy) subscribeUpstream(key string, subInfo *SubscriptionInfo) {
+	sub := subInfo.Subscription
+	err := p.hlConnector.Subscribe(sub)
+	
+	p.subMu.Lock()
+	subInfo.UpstreamPending = false
+	current, exists := p.globalSubscriptions[key]
+	p.subMu.Unlock()
+	
+	if err != nil {
+		logrus.WithError(err).Error("Failed to subscribe to Hyperliquid")
+		proxyErr := types.AsProxyError(err)
+		p.settleSubscriptionAck(key, subInfo, nil, types.NewProxyError(proxyErr.Code, "Failed to subscribe: "+proxyErr.Message, proxyErr.Retryable))
+		return
+	}
+	
+	// Give the reference back if the clients left meanwhile; a later subscription
+	// under the same key holds its own reference
+	if !exists || current != subInfo {
+		logrus.WithField("subscription_key", key).Debug("Clients left before the upstream subscribe completed, unsubscribing")
+		if err := p.hlConnector.Unsubscribe(sub); err != nil {
+			logrus.WithError(err).Error("Failed to unsubscribe from Hyperliquid")
+		}
+		return
+	}
+	
+	// Answer the waiting clients with the proxy's own response if Hyperliquid never acknowledges
+	time.AfterFunc(subscriptionAckTimeout, func() {
+		p.settleSubscriptionAck(key, subInfo, nil, nil)
+	})
+}
+
+// dropSubscription removes a subscription left without clients and unsubscribes
+// from Hyperliquid in remote mode. While the upstream subscribe is still in
+// flight, subscribeUpstream unsubscribes once it completes instead, so the
+// unsubscribe can't overtake it. Caller must hold subMu.
+func (p *Proxy) dropSubscription(key string, subInfo *SubscriptionInfo) {
+	delete(p.globalSubscriptions, key)
+	logrus.WithField("subscription_key", key).Debug("Removed empty subscription")
+	
+	if p.useLocalNode || p.hlConnector == nil || subInfo.UpstreamPending {
+		return
+	}
+	sub := subInfo.Subscription
+	go func() {
+		if err := p.hlConnector.Unsubscribe(sub); err != nil {
+			logrus.WithError(err).Error("Failed to unsubscribe from Hyperliquid")
+		}
+	}()
+}
+
+// addInitialLocalNodeData adds the initial data from local node for a newly
+// subscribed client to its snapshot
+func (p *Proxy) addInitialLocalNodeData(c *client.Client, sub *types.SubscriptionRequest, snapshot *snapshotBatch) {
+	switch sub.Type {
+	case "allMids":
+		// Send ALL current prices (not just a fixed list!)
+		allPrices := p.localNodeReader.GetAllLatestPrices()
+		
+		logrus.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"prices_count": len(allPrices),
+		}).Info("=== SENDING INITIAL allMids to new client ===")
+		
 		if len(allPrices) > 0 {
 			allMids := types.AllMids{Mids: allPrices}
 			data, err := json.Marshal(allMids)
@@ -459,36 +1420,24 @@ func (p *Proxy) sendInitialLocalNodeData(c *client.Client, sub *types.Subscripti
 					Channel: "allMids",
 					Data:    data,
 				}
-				c.SendMessage(message)
+				messageBytes, _ := json.Marshal(message)
+				snapshot.add(messageBytes)
 				logrus.WithFields(logrus.Fields{
 					"client_id": c.ID,
 					"prices_sent": len(allPrices),
-				}).Info("=== SENT INITIAL allMids to client ===")
+				}).Info("=== QUEUED INITIAL allMids for client ===")
 			}
 		}
 		
 	case "trades":
-		if sub.Coin != "" {
-			// Send recent trades for the specific coin
-			trades := p.localNodeReader.GetLatestTrades(sub.Coin, 5) // Send last 5 trades
+		// Send recent trades for the specific coin, up to the configured depth
+		depth := p.config.GetInitialSnapshotDepth("trades")
+		if sub.Coin != "" && depth > 0 {
+			trades := p.localNodeReader.GetRealTrades(sub.Coin, depth)
 			for _, trade := range trades {
-				tradesMessage := map[string]interface{}{
-					"channel": "trades",
-					"data": map[string]interface{}{
-						"coin":  trade.Coin,
-						"side":  trade.Side,
-						"px":    trade.Px,
-						"sz":    trade.Sz,
-						"time":  trade.Time,
-						"hash":  trade.Hash,
-						"tid":   trade.TID,
-						"users": trade.Users,
-					},
-				}
-				
-				messageBytes, err := json.Marshal(tradesMessage)
+				messageBytes, err := p.buildTradeMessage(trade)
 				if err == nil {
-					c.Send <- messageBytes
+					snapshot.add(messageBytes)
 				}
 			}
 			logrus.WithFields(logrus.Fields{
@@ -497,13 +1446,141 @@ func (p *Proxy) sendInitialLocalNodeData(c *client.Client, sub *types.Subscripti
 				"trades_count": len(trades),
 			}).Debug("Sent initial trades from local node")
 		}
+		
+	case "midPx":
+		if mid, exists := p.localNodeReader.GetLatestPrice(sub.Coin); exists {
+			if messageBytes, err := p.buildMidPxMessage(sub.Coin, mid); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+		
+	case "userFills":
+		userFills := p.localNodeReader.GetUserFills(sub.User, 0)
+		if userFills == nil {
+			userFills = &types.WsUserFills{User: sub.User, Fills: []types.WsFill{}}
+		}
+		isSnapshot := true
+		userFills.IsSnapshot = &isSnapshot
+		if messageBytes, err := p.buildUserFillsMessage(userFills); err == nil {
+			snapshot.add(messageBytes)
+		}
+		
+	case "midsBbo":
+		if mids := p.localNodeReader.GetAllMidsBbo(); len(mids) > 0 {
+			if messageBytes, err := p.buildMidsBboMessage(mids); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+		
+	case "candle":
+		// Closed candles rebuilt from retained trades first, so charts have history right away
+		backfill := p.localNodeReader.BackfillCandles(sub.Coin, sub.Interval, p.config.Proxy.CandleBackfillCount, p.config.Proxy.CandleBackfillGaps)
+		for _, candle := range backfill {
+			if messageBytes, err := p.buildCandleMessage(candle); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+		if len(backfill) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"client_id": c.ID,
+				"coin":      sub.Coin,
+				"interval":  sub.Interval,
+				"candles":   len(backfill),
+			}).Debug("Sent candle backfill from local node")
+		}
+		
+		if candle := p.localNodeReader.GetCandle(sub.Coin, sub.Interval); candle != nil {
+			if messageBytes, err := p.buildCandleMessage(candle); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+		
+	case "l2Book":
+		if book := p.localNodeReader.GetL2Book(sub.Coin, nSigFigs(sub)); book != nil {
+			if messageBytes, err := p.buildL2BookMessage(book); err == nil {
+				p.addL2BookSnapshot(snapshot, sub, messageBytes)
+			}
+		}
+		
+	case "bbo":
+		if bbo := p.localNodeReader.GetBBO(sub.Coin); bbo != nil {
+			if messageBytes, err := p.buildBboMessage(bbo); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+		
+	case "activeAssetCtx":
+		if ctx := p.localNodeReader.GetAssetCtx(sub.Coin); ctx != nil {
+			if messageBytes, err := p.buildAssetCtxMessage(ctx); err == nil {
+				snapshot.add(messageBytes)
+			}
+		}
+	}
+}
+
+// isUnknownCoin reports whether a coin-scoped subscription targets a coin the AssetFetcher doesn't know
+func (p *Proxy) isUnknownCoin(sub *types.SubscriptionRequest) bool {
+	if sub.Coin == "" || p.assetFetcher == nil || !isCoinScoped(sub.Type) || isTradesFirehose(sub) {
+		return false
+	}
+	_, exists := p.assetFetcher.GetAssetByName(sub.Coin)
+	return !exists
+}
+
+// isCoinScoped reports whether a subscription type is keyed by coin
+func isCoinScoped(subType string) bool {
+	switch subType {
+	case "trades", "l2Book", "bbo", "candle", "activeAssetCtx", "midPx":
+		return true
+	}
+	return false
+}
+
+// activatePendingSubscriptions activates pending subscriptions whose coin is now listed
+func (p *Proxy) activatePendingSubscriptions() {
+	type activation struct {
+		sub     *types.SubscriptionRequest
+		clients []*client.Client
+	}
+	
+	var activated []activation
+	p.subMu.Lock()
+	for _, subInfo := range p.globalSubscriptions {
+		if !subInfo.Pending || p.isUnknownCoin(subInfo.Subscription) {
+			continue
+		}
+		subInfo.Pending = false
+		
+		clients := make([]*client.Client, 0, len(subInfo.Clients))
+		for c := range subInfo.Clients {
+			clients = append(clients, c)
+		}
+		activated = append(activated, activation{sub: subInfo.Subscription, clients: clients})
+	}
+	p.subMu.Unlock()
+	
+	for _, a := range activated {
+		logrus.WithFields(logrus.Fields{
+			"type":    a.sub.Type,
+			"coin":    a.sub.Coin,
+			"clients": len(a.clients),
+		}).Info("Pending subscription is now active")
+		
+		for _, c := range a.clients {
+			p.sendNotificationToClient(c, fmt.Sprintf("Subscription to %s for %s is now active", a.sub.Type, a.sub.Coin))
+			if p.localNodeReader != nil {
+				snapshot := &snapshotBatch{}
+				p.addInitialLocalNodeData(c, a.sub, snapshot)
+				p.sendSnapshot(c, snapshot)
+			}
+		}
 	}
 }
 
 // handleUnsubscribe handles unsubscription requests
 func (p *Proxy) handleUnsubscribe(c *client.Client, sub *types.SubscriptionRequest) {
 	if sub == nil {
-		p.sendErrorToClient(c, "Missing subscription details")
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, "Missing subscription details", false))
 		return
 	}
 	
@@ -514,42 +1591,50 @@ func (p *Proxy) handleUnsubscribe(c *client.Client, sub *types.SubscriptionReque
 		"user":      sub.User,
 	}).Debug("Handling unsubscription")
 	
-	key := p.createSubscriptionKey(sub)
+	if len(sub.Coins) > 0 {
+		p.handleBatchUnsubscribe(c, sub)
+		return
+	}
+	
+	subscriptionID := p.removeSubscriber(c, sub.Key())
 	
+	// Send unsubscription response, with the id when the client was subscribed
+	data := fmt.Sprintf(`{"method":"unsubscribe","subscription":%s}`, p.toJSON(sub))
+	if subscriptionID != 0 {
+		data = fmt.Sprintf(`{"method":"unsubscribe","subscription":%s,"subscriptionId":%d}`, p.toJSON(sub), subscriptionID)
+	}
+	response := types.WSMessage{
+		Channel: "subscriptionResponse",
+		Data:    json.RawMessage(data),
+	}
+	c.SendMessage(response)
+}
+
+// removeSubscriber removes a client from the subscription for key, dropping the
+// subscription once it has no clients left. Returns the id the client's
+// subscription had, 0 if the client wasn't subscribed.
+func (p *Proxy) removeSubscriber(c *client.Client, key string) int64 {
 	p.subMu.Lock()
 	subInfo, exists := p.globalSubscriptions[key]
 	if exists {
 		delete(subInfo.Clients, c)
+		delete(subInfo.AwaitingAck, c)
 		
 		// If no more clients, unsubscribe from Hyperliquid (only if not using local node)
 		if len(subInfo.Clients) == 0 {
-			delete(p.globalSubscriptions, key)
-			if !p.useLocalNode && p.hlConnector != nil {
-				go func() {
-					if err := p.hlConnector.Unsubscribe(sub); err != nil {
-						logrus.WithError(err).Error("Failed to unsubscribe from Hyperliquid")
-					}
-				}()
-			}
+			p.dropSubscription(key, subInfo)
 		}
 	}
 	p.subMu.Unlock()
 	
 	// Remove subscription from client
-	c.RemoveSubscription(key)
-	
-	// Send unsubscription response
-	response := types.WSMessage{
-		Channel: "subscriptionResponse",
-		Data:    json.RawMessage(fmt.Sprintf(`{"method":"unsubscribe","subscription":%s}`, p.toJSON(sub))),
-	}
-	c.SendMessage(response)
+	return c.RemoveSubscription(key)
 }
 
 // handlePostRequest handles POST requests via WebSocket
 func (p *Proxy) handlePostRequest(c *client.Client, msg *types.WSMessage) {
 	if msg.Request == nil || msg.ID == nil {
-		p.sendErrorToClient(c, "Invalid POST request format")
+		p.sendErrorToClient(c, types.NewProxyError(types.ErrCodeInvalidRequest, "Invalid POST request format", false))
 		return
 	}
 	
@@ -562,27 +1647,62 @@ func (p *Proxy) handlePostRequest(c *client.Client, msg *types.WSMessage) {
 	
 	if p.useLocalNode {
 		// For local node mode, we can't handle POST requests as they require the Hyperliquid API
-		p.sendPostErrorToClient(c, *msg.ID, "POST requests not supported in local node mode")
+		p.sendPostErrorToClient(c, *msg.ID, types.NewProxyError(types.ErrCodeUnsupported, "POST requests not supported in local node mode", false))
 		return
 	}
 	
 	if p.hlConnector == nil {
-		p.sendPostErrorToClient(c, *msg.ID, "Hyperliquid connector not available")
+		p.sendPostErrorToClient(c, *msg.ID, types.NewProxyError(types.ErrCodeNotConnected, "Hyperliquid connector not available", true))
 		return
 	}
 	
-	// Forward request to Hyperliquid
+	// Serve identical info queries from the cache while fresh
+	if msg.Request.Type == "info" {
+		if response, ok := p.infoCache.get(msg.Request.Payload); ok {
+			logrus.WithField("client_id", c.ID).Debug("Serving info request from cache")
+			p.sendPostResponseToClient(c, *msg.ID, response)
+			return
+		}
+	}
+	
+	// Reserve a slot so a flood of POST requests can't exhaust resources
+	if !p.acquirePostSlot() {
+		logrus.WithField("client_id", c.ID).Warn("Rejecting POST request, too many in flight")
+		p.sendPostErrorToClient(c, *msg.ID, types.NewProxyError(types.ErrCodeBusy, "server busy, too many concurrent post requests", true))
+		return
+	}
+	
+	// Wait for the response off the client message loop
+	go func() {
+		defer p.releasePostSlot()
+		p.forwardPostRequest(c, msg)
+	}()
+}
+
+// forwardPostRequest forwards a POST request to Hyperliquid and replies to the client
+func (p *Proxy) forwardPostRequest(c *client.Client, msg *types.WSMessage) {
 	response, err := p.hlConnector.PostRequest(msg.Request.Type, msg.Request.Payload)
 	if err != nil {
 		logrus.WithError(err).Error("POST request failed")
-		p.sendPostErrorToClient(c, *msg.ID, err.Error())
+		p.sendPostErrorToClient(c, *msg.ID, err)
 		return
 	}
 	
-	// Send response back to client
+	if msg.Request.Type == "info" && response.Response.Type != "error" {
+		p.infoCache.set(msg.Request.Payload, response)
+	}
+	
+	p.sendPostResponseToClient(c, *msg.ID, response)
+}
+
+// sendPostResponseToClient sends a POST response to a client under the client's request ID
+func (p *Proxy) sendPostResponseToClient(c *client.Client, requestID int64, response *types.PostResponse) {
+	reply := *response
+	reply.ID = requestID
+	
 	responseMsg := types.WSMessage{
 		Channel: "post",
-		Data:    json.RawMessage(p.toJSON(response)),
+		Data:    json.RawMessage(p.toJSON(reply)),
 	}
 	c.SendMessage(responseMsg)
 	
@@ -591,6 +1711,33 @@ func (p *Proxy) handlePostRequest(c *client.Client, msg *types.WSMessage) {
 	p.stats.mu.Unlock()
 }
 
+// acquirePostSlot reserves an in-flight POST slot, returning false when saturated
+func (p *Proxy) acquirePostSlot() bool {
+	if p.postSem != nil {
+		select {
+		case p.postSem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	
+	p.stats.mu.Lock()
+	p.stats.PostRequestsInFlight++
+	p.stats.mu.Unlock()
+	return true
+}
+
+// releasePostSlot releases a slot reserved by acquirePostSlot
+func (p *Proxy) releasePostSlot() {
+	if p.postSem != nil {
+		<-p.postSem
+	}
+	
+	p.stats.mu.Lock()
+	p.stats.PostRequestsInFlight--
+	p.stats.mu.Unlock()
+}
+
 // handleHyperliquidMessage handles messages from Hyperliquid (only used when not in local node mode)
 func (p *Proxy) handleHyperliquidMessage(data []byte) {
 	p.updateStatsActivity()
@@ -606,99 +1753,320 @@ func (p *Proxy) handleHyperliquidMessage(data []byte) {
 		return
 	}
 	
-	// Skip subscription responses and POST responses (handled elsewhere)
-	if msg.Channel == "subscriptionResponse" || msg.Channel == "post" {
+	switch msg.Channel {
+	case "subscriptionResponse":
+		p.handleUpstreamSubscriptionResponse(msg.Data)
+		return
+	case "error":
+		p.handleUpstreamError(msg.Data)
+		return
+	case "post":
+		// POST responses are routed by the connector
 		return
 	}
 	
 	// Forward message to clients
 	p.forwardMessageToClients(msg.Channel, data)
+	p.forwardSnapshotComplete(msg.Channel, msg.Data)
 }
 
-// safelyTryToSendMessage safely attempts to send a message to a client channel
-// Returns true if successful, false if the channel is closed or full
-func (p *Proxy) safelyTryToSendMessage(clientChannel chan []byte, data []byte, clientID string) (success bool) {
-	defer func() {
-		if r := recover(); r != nil {
-			logrus.WithFields(logrus.Fields{
-				"client_id": clientID,
-				"error":     r,
-			}).Debug("Recovered from panic while sending message to client")
-			success = false
-		}
-	}()
+// snapshotSendTimeout bounds how long a client's initial snapshot, all of its
+// frames together, waits for buffer space
+const snapshotSendTimeout = 5 * time.Second
+
+// snapshotBatch collects the frames of a client's initial snapshot, which are
+// queued together once the snapshot is built
+type snapshotBatch struct {
+	frames   [][]byte
+	channels []string // channel each frame counts a drop against
+}
+
+// add appends a snapshot frame
+func (s *snapshotBatch) add(frame []byte) {
+	s.addAs(frame, frameChannel(frame))
+}
+
+// addAs appends a snapshot frame, counting a drop against channel
+func (s *snapshotBatch) addAs(frame []byte, channel string) {
+	s.frames = append(s.frames, frame)
+	s.channels = append(s.channels, channel)
+}
+
+// sendSnapshot delivers an initial snapshot without holding up the caller, the
+// client message loop: the client sends it from its own goroutine, waiting up
+// to snapshotSendTimeout for buffer space instead of dropping it, since a missed
+// snapshot leaves the client without state until the next update. The frames
+// from the first one that doesn't fit in time on are dropped. Updates sent to
+// the client meanwhile are held until after the snapshot.
+func (p *Proxy) sendSnapshot(c *client.Client, snapshot *snapshotBatch) {
+	if len(snapshot.frames) == 0 {
+		return
+	}
 	
-	select {
-	case clientChannel <- data:
-		return true
-	default:
-		// Channel is full or closed
-		return false
+	c.QueueSnapshot(snapshot.frames, snapshotSendTimeout, func(sent int) {
+		logrus.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"sent":      sent,
+			"dropped":   len(snapshot.frames) - sent,
+		}).Warn("Dropped initial snapshot, client buffer stayed full or client went away")
+		for _, channel := range snapshot.channels[sent:] {
+			p.drops.record(channel, c.ID, "snapshot")
+		}
+	})
+}
+
+// addL2BookSnapshot adds an initial l2Book snapshot to a client's snapshot, as a
+// gzip-compressed binary frame when the client asked for it with
+// compressSnapshot and the snapshot reaches compressed_snapshot_min_bytes
+func (p *Proxy) addL2BookSnapshot(snapshot *snapshotBatch, sub *types.SubscriptionRequest, data []byte) {
+	if sub.CompressSnapshot && len(data) >= p.config.Proxy.CompressedSnapshotMinBytes {
+		snapshot.addAs(client.CompressedFrame(data), "l2Book")
+		return
 	}
+	snapshot.add(data)
+}
+
+// safelyTryToSendMessage attempts to send a message to a client without blocking
+// Returns true if successful, false if the client is closed or its buffer is full
+func (p *Proxy) safelyTryToSendMessage(c *client.Client, data []byte) bool {
+	return c.TrySend(data)
 }
 
 // forwardMessageToClients forwards a message to relevant clients
 func (p *Proxy) forwardMessageToClients(channel string, data []byte) {
+	p.forwardMessage(data, func(key string, sub *types.SubscriptionRequest) bool {
+		return string(sub.Type) == channel
+	})
+}
+
+// forwardMessageToSubscription forwards a message to the clients of a single subscription
+func (p *Proxy) forwardMessageToSubscription(subscriptionKey string, data []byte) {
+	p.forwardMessage(data, func(key string, sub *types.SubscriptionRequest) bool {
+		return key == subscriptionKey
+	})
+}
+
+// forwardMessage forwards a message to the clients of every subscription accepted by match.
+// Subscriptions whose type has a minimum update interval are throttled: a message
+// arriving inside the window replaces any message already waiting and is delivered
+// when the window elapses, so clients always end up with the latest state.
+func (p *Proxy) forwardMessage(data []byte, match func(key string, sub *types.SubscriptionRequest) bool) {
 	p.subMu.Lock()
 	defer p.subMu.Unlock()
 	
 	forwardedCount := 0
+	suppressedCount := 0
 	clientsToRemove := make(map[*client.Client][]string) // client -> list of subscription keys to remove
 	
 	for key, subInfo := range p.globalSubscriptions {
-		// Match channel with subscription type
-		if string(subInfo.Subscription.Type) == channel {
+		if match(key, subInfo.Subscription) {
 			// Update last message
 			subInfo.LastMessage = data
-			subInfo.LastUpdate = time.Now()
 			
-			// Forward to all clients subscribed to this
-			for c := range subInfo.Clients {
-				// Try to send message to client safely
-				if p.safelyTryToSendMessage(c.Send, data, c.ID) {
-					forwardedCount++
+			if wait := p.throttleWait(subInfo); wait > 0 {
+				if subInfo.ThrottledMessage == nil {
+					throttledKey := key
+					time.AfterFunc(wait, func() { p.flushThrottledMessage(throttledKey) })
 				} else {
-					// Client channel is full or closed - mark for removal
-					logrus.WithField("client_id", c.ID).Debug("Client channel closed or full, removing from subscription")
-					if clientsToRemove[c] == nil {
-						clientsToRemove[c] = make([]string, 0)
-					}
-					clientsToRemove[c] = append(clientsToRemove[c], key)
+					suppressedCount++
 				}
+				subInfo.ThrottledMessage = data
+				continue
 			}
+			
+			forwardedCount += p.deliverToSubscription(key, subInfo, data, clientsToRemove)
 		}
 	}
 	
-	// Clean up disconnected clients
+	p.removeFailedClients(clientsToRemove)
+	p.addForwardedMessages(forwardedCount)
+	p.addSuppressedMessages(suppressedCount)
+}
+
+// throttleWait returns how long a subscription must wait before its next update,
+// 0 when it may be sent now. Caller must hold subMu.
+func (p *Proxy) throttleWait(subInfo *SubscriptionInfo) time.Duration {
+	intervalMs := p.config.Proxy.MinUpdateIntervalMs[subInfo.Subscription.Type]
+	if intervalMs <= 0 {
+		return 0
+	}
+	
+	next := subInfo.LastUpdate.Add(time.Duration(intervalMs) * time.Millisecond)
+	if wait := time.Until(next); wait > 0 {
+		return wait
+	}
+	// A message already waiting goes out first, by its scheduled flush
+	if subInfo.ThrottledMessage != nil {
+		return time.Millisecond
+	}
+	return 0
+}
+
+// flushThrottledMessage delivers the message held back for a throttled subscription
+func (p *Proxy) flushThrottledMessage(key string) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	
+	subInfo, exists := p.globalSubscriptions[key]
+	if !exists || subInfo.ThrottledMessage == nil {
+		return
+	}
+	
+	data := subInfo.ThrottledMessage
+	subInfo.ThrottledMessage = nil
+	
+	clientsToRemove := make(map[*client.Client][]string)
+	forwardedCount := p.deliverToSubscription(key, subInfo, data, clientsToRemove)
+	p.removeFailedClients(clientsToRemove)
+	p.addForwardedMessages(forwardedCount)
+}
+
+// deliverToSubscription sends a message to every client of a subscription,
+// collecting clients that can't keep up in clientsToRemove. Caller must hold subMu.
+func (p *Proxy) deliverToSubscription(key string, subInfo *SubscriptionInfo, data []byte, clientsToRemove map[*client.Client][]string) int {
+	subInfo.LastUpdate = time.Now()
+	
+	// Forward to all clients subscribed to this
+	failed := p.sendToClients(subInfo.Clients, data)
+	for _, c := range failed {
+		// Client channel is full or closed - mark for removal
+		p.drops.record(subInfo.Subscription.Type, c.ID, "forward")
+		logrus.WithField("client_id", c.ID).Debug("Client channel closed or full, removing from subscription")
+		clientsToRemove[c] = append(clientsToRemove[c], key)
+	}
+	return len(subInfo.Clients) - len(failed)
+}
+
+// removeFailedClients drops clients from the subscriptions they could not be
+// delivered to, removing subscriptions left without clients. Caller must hold subMu.
+func (p *Proxy) removeFailedClients(clientsToRemove map[*client.Client][]string) {
 	for client, subscriptionKeys := range clientsToRemove {
 		for _, key := range subscriptionKeys {
+			// Free the client's slot too, it no longer receives this subscription
+			client.RemoveSubscription(key)
 			if subInfo, exists := p.globalSubscriptions[key]; exists {
 				delete(subInfo.Clients, client)
+				delete(subInfo.AwaitingAck, client)
 				
 				// If no more clients for this subscription, remove the subscription entirely
 				if len(subInfo.Clients) == 0 {
-					delete(p.globalSubscriptions, key)
-					logrus.WithField("subscription_key", key).Debug("Removed empty subscription")
+					p.dropSubscription(key, subInfo)
 				}
 			}
 		}
 	}
+}
+
+// removeClient drops a disconnecting client from every subscription it holds
+func (p *Proxy) removeClient(c *client.Client) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
 	
-	if forwardedCount > 0 {
+	keys := make([]string, 0)
+	for key, subInfo := range p.globalSubscriptions {
+		if subInfo.Clients[c] {
+			keys = append(keys, key)
+		}
+	}
+	p.removeFailedClients(map[*client.Client][]string{c: keys})
+}
+
+// addSuppressedMessages adds to the suppressed messages counter
+func (p *Proxy) addSuppressedMessages(count int) {
+	if count > 0 {
 		p.stats.mu.Lock()
-		p.stats.MessagesForwarded += int64(forwardedCount)
+		p.stats.MessagesSuppressed += int64(count)
 		p.stats.mu.Unlock()
 	}
 }
 
+// addForwardedMessages adds to the forwarded messages counter
+func (p *Proxy) addForwardedMessages(count int) {
+	if count > 0 {
+		p.stats.mu.Lock()
+		p.stats.MessagesForwarded += int64(count)
+		p.stats.mu.Unlock()
+	}
+}
+
+// runSubscriptionKeepalive periodically sends keepalive frames on quiet subscriptions
+func (p *Proxy) runSubscriptionKeepalive() {
+	interval := time.Duration(p.config.Proxy.SubscriptionKeepaliveSec) * time.Second
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		p.sendSubscriptionKeepalives(interval)
+	}
+}
+
+// sendSubscriptionKeepalives sends a minimal keepalive frame to clients of every
+// subscription that has had no data (or keepalive) for the given interval
+func (p *Proxy) sendSubscriptionKeepalives(interval time.Duration) {
+	now := time.Now()
+	
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	
+	for _, subInfo := range p.globalSubscriptions {
+		lastActivity := subInfo.LastUpdate
+		if subInfo.LastKeepalive.After(lastActivity) {
+			lastActivity = subInfo.LastKeepalive
+		}
+		if now.Sub(lastActivity) < interval {
+			continue
+		}
+		
+		keepalive := []byte(fmt.Sprintf(`{"channel":%q,"keepalive":true}`, subInfo.Subscription.Type))
+		for c := range subInfo.Clients {
+			p.safelyTryToSendMessage(c, keepalive)
+		}
+		subInfo.LastKeepalive = now
+	}
+}
+
 // handleHyperliquidConnect handles Hyperliquid connection events
-func (p *Proxy) handleHyperliquidConnect() {
+func (p *Proxy) handleHyperliquidConnect(reconnected bool) {
+	if reconnected {
+		logrus.Info("Reconnected to Hyperliquid WebSocket")
+		return
+	}
 	logrus.Info("Connected to Hyperliquid WebSocket")
 }
 
 // handleHyperliquidDisconnect handles Hyperliquid disconnection events
 func (p *Proxy) handleHyperliquidDisconnect(err error) {
 	logrus.WithError(err).Warn("Disconnected from Hyperliquid WebSocket")
+	
+	p.statusMu.Lock()
+	wasPaused := p.upstreamPaused
+	p.upstreamPaused = true
+	p.statusMu.Unlock()
+	
+	if !wasPaused && p.config.Proxy.NotifyUpstreamStatus {
+		p.broadcastNotification("data paused: upstream disconnected")
+	}
+}
+
+// handleHyperliquidResubscribed handles subscriptions being restored after a
+// (re)connect, telling clients data resumed if it had been paused
+func (p *Proxy) handleHyperliquidResubscribed(count int) {
+	p.statusMu.Lock()
+	wasPaused := p.upstreamPaused
+	p.upstreamPaused = false
+	p.upstreamReady = true
+	p.statusMu.Unlock()
+	
+	if wasPaused && p.config.Proxy.NotifyUpstreamStatus {
+		p.broadcastNotification("data resumed, resubscribed")
+	}
+}
+
+// broadcastNotification sends a notification channel message to every connected client
+func (p *Proxy) broadcastNotification(notification string) {
+	for _, c := range p.hub.GetClients() {
+		p.sendNotificationToClient(c, notification)
+	}
 }
 
 // handleHyperliquidError handles Hyperliquid error events
@@ -706,33 +2074,69 @@ func (p *Proxy) handleHyperliquidError(err error) {
 	logrus.WithError(err).Error("Hyperliquid WebSocket error")
 }
 
-// sendErrorToClient sends an error message to a client
-func (p *Proxy) sendErrorToClient(c *client.Client, errorMsg string) {
+// sendErrorToClient sends an error message to a client, including the
+// error code and retry hint when err is a ProxyError
+func (p *Proxy) sendErrorToClient(c *client.Client, err error) {
+	proxyErr := types.AsProxyError(err)
 	response := map[string]interface{}{
-		"error": errorMsg,
-		"time":  time.Now().Unix(),
+		"error":     proxyErr.Message,
+		"code":      proxyErr.Code,
+		"retryable": proxyErr.Retryable,
+		"time":      time.Now().Unix(),
+	}
+	if proxyErr.Details != nil {
+		response["details"] = proxyErr.Details
 	}
 	c.SendMessage(response)
 }
 
+// sendNotificationToClient sends a notification channel message to a client
+func (p *Proxy) sendNotificationToClient(c *client.Client, notification string) {
+	data, err := json.Marshal(types.Notification{Notification: notification})
+	if err != nil {
+		return
+	}
+	c.SendMessage(types.WSMessage{
+		Channel: "notification",
+		Data:    data,
+	})
+}
+
 // sendPostErrorToClient sends a POST error response to a client
-func (p *Proxy) sendPostErrorToClient(c *client.Client, requestID int64, errorMsg string) {
+func (p *Proxy) sendPostErrorToClient(c *client.Client, requestID int64, err error) {
+	proxyErr := types.AsProxyError(err)
+	payload, _ := json.Marshal(proxyErr.Message)
+	
 	response := types.WSMessage{
 		Channel: "post",
-		Data: json.RawMessage(fmt.Sprintf(`{
-			"id": %d,
-			"response": {
-				"type": "error",
-				"payload": "%s"
-			}
-		}`, requestID, errorMsg)),
+		Data: json.RawMessage(p.toJSON(types.PostResponse{
+			ID: requestID,
+			Response: types.PostResponseInner{
+				Type:      "error",
+				Payload:   payload,
+				Code:      proxyErr.Code,
+				Retryable: &proxyErr.Retryable,
+			},
+		})),
 	}
 	c.SendMessage(response)
 }
 
-// updateStats updates proxy statistics
+// updateStats periodically logs proxy statistics
 func (p *Proxy) updateStats() {
-	ticker := time.NewTicker(10 * time.Second)
+	interval := p.statsInterval()
+	if interval <= 0 {
+		logrus.Debug("Periodic statistics logging disabled")
+		return
+	}
+	
+	level, err := logrus.ParseLevel(p.config.Logging.StatsLevel)
+	if err != nil {
+		logrus.WithField("level", p.config.Logging.StatsLevel).Warn("Invalid stats log level, using debug")
+		level = logrus.DebugLevel
+	}
+	
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	
 	for range ticker.C {
@@ -744,10 +2148,15 @@ func (p *Proxy) updateStats() {
 			"messages_fwd":     stats.MessagesForwarded,
 			"post_requests":    stats.PostRequestsHandled,
 			"local_node":       p.useLocalNode,
-		}).Debug("Proxy statistics")
+		}).Log(level, "Proxy statistics")
 	}
 }
 
+// statsInterval returns the configured statistics logging interval
+func (p *Proxy) statsInterval() time.Duration {
+	return time.Duration(p.config.Logging.StatsIntervalSec) * time.Second
+}
+
 // updateStatsActivity updates the last activity timestamp
 func (p *Proxy) updateStatsActivity() {
 	p.stats.mu.Lock()
@@ -755,24 +2164,6 @@ func (p *Proxy) updateStatsActivity() {
 	p.stats.mu.Unlock()
 }
 
-// createSubscriptionKey creates a unique key for a subscription
-func (p *Proxy) createSubscriptionKey(sub *types.SubscriptionRequest) string {
-	key := sub.Type
-	if sub.User != "" {
-		key += "-" + sub.User
-	}
-	if sub.Coin != "" {
-		key += "-" + sub.Coin
-	}
-	if sub.Interval != "" {
-		key += "-" + sub.Interval
-	}
-	if sub.Dex != "" {
-		key += "-" + sub.Dex
-	}
-	return key
-}
-
 // toJSON converts an object to JSON string
 func (p *Proxy) toJSON(obj interface{}) string {
 	data, err := json.Marshal(obj)
@@ -782,12 +2173,25 @@ func (p *Proxy) toJSON(obj interface{}) string {
 	return string(data)
 }
 
+// ReloadAssetMap reloads the static asset map file used by the local node reader
+func (p *Proxy) ReloadAssetMap() error {
+	if p.localNodeReader == nil {
+		return nil
+	}
+	return p.localNodeReader.ReloadAssetMap()
+}
+
 // GetAssetStats returns asset statistics from the AssetFetcher
 func (p *Proxy) GetAssetStats() map[string]interface{} {
 	if p.assetFetcher == nil {
+		// Same keys as AssetFetcher.GetAssetStats so /assets consumers needn't special-case
 		return map[string]interface{}{
-			"status": "not_available",
-			"reason": "asset fetcher not initialized",
+			"status":       "not_available",
+			"reason":       "asset fetcher not initialized",
+			"perp_assets":  0,
+			"spot_assets":  0,
+			"total_assets": 0,
+			"last_updated": nil,
 		}
 	}
 	return p.assetFetcher.GetAssetStats()
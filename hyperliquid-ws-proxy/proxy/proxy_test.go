@@ -0,0 +1,838 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestMain(m *testing.M) {
+	// Per-block and per-client info logs drown test output
+	logrus.SetLevel(logrus.WarnLevel)
+	os.Exit(m.Run())
+}
+
+// newTestProxy creates a local node proxy over an empty data directory, with
+// its hub running but nothing else started. configure, if set, adjusts the
+// default configuration first.
+func newTestProxy(t *testing.T, configure func(cfg *config.Config)) *Proxy {
+	t.Helper()
+	
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Proxy.EnableLocalNode = true
+	cfg.Proxy.LocalNodeDataPath = t.TempDir()
+	if configure != nil {
+		configure(cfg)
+	}
+	
+	p := NewProxy(cfg)
+	go p.hub.Run()
+	return p
+}
+
+// captureLogs records log entries at level and above until the test ends
+func captureLogs(t *testing.T, level logrus.Level) *logtest.Hook {
+	t.Helper()
+	
+	previous := logrus.GetLevel()
+	logrus.SetLevel(level)
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() {
+		logrus.SetLevel(previous)
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+	return hook
+}
+
+// countLogs returns how many captured entries carry message
+func countLogs(hook *logtest.Hook, message string) int {
+	count := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == message {
+			count++
+		}
+	}
+	return count
+}
+
+// startUpstream serves a Hyperliquid WebSocket stub passing every message it
+// receives to handle, and returns its URL
+func startUpstream(t *testing.T, handle func(conn *websocket.Conn, msg types.WSMessage)) string {
+	t.Helper()
+	
+	upgrader := websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg types.WSMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			handle(conn, msg)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return "ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws"
+}
+
+// waitForFrame returns the next frame queued for c on channel, skipping others
+func waitForFrame(t *testing.T, c *client.Client, channel string) string {
+	t.Helper()
+	
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case frame := <-c.Send:
+			if frameChannel(frame) == channel {
+				return string(frame)
+			}
+		case <-timeout:
+			t.Fatalf("no %s frame received", channel)
+		}
+	}
+}
+
+// listAssets makes the asset fetcher list names as perps, indexed in order
+func listAssets(p *Proxy, names ...string) {
+	p.assetFetcher.mu.Lock()
+	defer p.assetFetcher.mu.Unlock()
+	
+	for i, name := range names {
+		asset := &AssetInfo{Index: i, Name: name}
+		p.assetFetcher.perpAssets[i] = asset
+		p.assetFetcher.assetsByName[name] = asset
+	}
+}
+
+// setLatestPrice records price as the last fill of coin on the local node
+func setLatestPrice(p *Proxy, coin, price string) {
+	p.localNodeReader.dataMu.Lock()
+	defer p.localNodeReader.dataMu.Unlock()
+	
+	p.localNodeReader.latestPrices[coin] = price
+}
+
+// framesOn returns the frames queued for c on channel, emptying its buffer
+func framesOn(c *client.Client, channel string) []string {
+	var frames []string
+	for {
+		select {
+		case frame := <-c.Send:
+			if frameChannel(frame) == channel {
+				frames = append(frames, string(frame))
+			}
+		default:
+			return frames
+		}
+	}
+}
+
+func TestMidPxEmitsOnlyChangesOfTheSubscribedCoin(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC", "ETH")
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "midPx", Coin: "BTC"})
+	framesOn(c, "midPx")
+	
+	setLatestPrice(p, "BTC", "100")
+	setLatestPrice(p, "ETH", "10")
+	p.generateMidPxFromLocalNode()
+	if frames := framesOn(c, "midPx"); len(frames) != 1 || frames[0] != `{"channel":"midPx","data":{"coin":"BTC","mid":"100"}}` {
+		t.Fatalf("first midPx frames = %q, want the BTC mid only", frames)
+	}
+	
+	// An unchanged mid, or a change on another coin, sends nothing
+	setLatestPrice(p, "ETH", "11")
+	p.generateMidPxFromLocalNode()
+	if frames := framesOn(c, "midPx"); len(frames) != 0 {
+		t.Fatalf("frames without a BTC change = %q, want none", frames)
+	}
+	
+	setLatestPrice(p, "BTC", "101")
+	p.generateMidPxFromLocalNode()
+	if frames := framesOn(c, "midPx"); len(frames) != 1 || frames[0] != `{"channel":"midPx","data":{"coin":"BTC","mid":"101"}}` {
+		t.Fatalf("frames after a BTC change = %q, want the new mid", frames)
+	}
+}
+
+func TestPostRequestsBeyondTheLimitGetBusy(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.EnableLocalNode = false
+		cfg.Proxy.MaxConcurrentPosts = 2
+	})
+	for i := 0; i < 2; i++ {
+		if !p.acquirePostSlot() {
+			t.Fatalf("slot %d refused below the limit", i)
+		}
+	}
+	if inFlight := p.GetStats().PostRequestsInFlight; inFlight != 2 {
+		t.Fatalf("in-flight POST requests = %d, want 2", inFlight)
+	}
+	
+	// The request is refused before it reaches the unconnected connector
+	c := client.NewClient(nil, p.hub)
+	id := int64(7)
+	p.handlePostRequest(c, &types.WSMessage{Method: "post", ID: &id, Request: &types.PostRequest{Type: "info", Payload: json.RawMessage(`{"type":"meta"}`)}})
+	frames := framesOn(c, "post")
+	if len(frames) != 1 {
+		t.Fatalf("post frames = %q, want one busy error", frames)
+	}
+	var reply struct {
+		Data types.PostResponse `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(frames[0]), &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Data.ID != id || reply.Data.Response.Type != "error" || reply.Data.Response.Code != types.ErrCodeBusy {
+		t.Fatalf("reply = %+v, want a busy error for request %d", reply.Data, id)
+	}
+	
+	p.releasePostSlot()
+	if !p.acquirePostSlot() {
+		t.Fatal("released slot not reusable")
+	}
+}
+
+func TestPendingSubscriptionStartsOnceCoinIsListed(t *testing.T) {
+	var listed atomic.Bool
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Type == "spotMeta":
+			w.Write([]byte(`{"universe":[],"tokens":[]}`))
+		case listed.Load():
+			w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5},{"name":"NEW","szDecimals":2}]}`))
+		default:
+			w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5}]}`))
+		}
+	}))
+	defer info.Close()
+	
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.MainnetURL = "ws" + strings.TrimPrefix(info.URL, "http") + "/ws"
+	})
+	if err := p.assetFetcher.fetchAssets(); err != nil {
+		t.Fatal(err)
+	}
+	
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	sub := &types.SubscriptionRequest{Type: "trades", Coin: "NEW"}
+	p.handleSubscribe(c, sub)
+	if responses := framesOn(c, "subscriptionResponse"); len(responses) != 1 {
+		t.Fatalf("subscription responses = %q, want one", responses)
+	}
+	p.subMu.RLock()
+	pending := p.globalSubscriptions[sub.Key()].Pending
+	p.subMu.RUnlock()
+	if !pending {
+		t.Fatal("subscription to an unlisted coin is not pending")
+	}
+	
+	// A metadata refresh listing the coin activates the subscription
+	listed.Store(true)
+	if err := p.assetFetcher.fetchAssets(); err != nil {
+		t.Fatal(err)
+	}
+	p.assetFetcher.notifyUpdate()
+	if notifications := framesOn(c, "notification"); len(notifications) != 1 || !strings.Contains(notifications[0], "trades for NEW is now active") {
+		t.Fatalf("notifications = %q, want the subscription reported active", notifications)
+	}
+	
+	// Fills of the coin now reach the subscriber
+	order := limitOrder(true, "12", "1")
+	order.Asset = 1
+	p.localNodeReader.processOrders([]Order{order}, nil, decodeStatuses(t, `[{"filled":{"totalSz":"1","avgPx":"12","oid":1}}]`), "2025-01-01T00:00:00.000", "0xtaker")
+	p.generateTradesFromLocalNode()
+	if trades := framesOn(c, "trades"); len(trades) != 1 || !strings.Contains(trades[0], `"coin":"NEW"`) {
+		t.Fatalf("trades frames = %q, want the NEW fill", trades)
+	}
+}
+
+func TestErrorCodeAndRetryableReachTheClient(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.EnableLocalNode = false
+	})
+	c := client.NewClient(nil, p.hub)
+	
+	// A subscribe failure carries its code in the error frame
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "midPx", Coin: "BTC"})
+	var errorFrame struct {
+		Code      string `json:"code"`
+		Retryable *bool  `json:"retryable"`
+	}
+	select {
+	case frame := <-c.Send:
+		if err := json.Unmarshal(frame, &errorFrame); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("no error frame for an unsupported subscription")
+	}
+	if errorFrame.Code != types.ErrCodeUnsupported || errorFrame.Retryable == nil || *errorFrame.Retryable {
+		t.Fatalf("error frame = %+v, want a non-retryable unsupported error", errorFrame)
+	}
+	
+	// The connector's error reaches the POST reply unchanged
+	id := int64(3)
+	p.handlePostRequest(c, &types.WSMessage{Method: "post", ID: &id, Request: &types.PostRequest{Type: "info", Payload: json.RawMessage(`{"type":"meta"}`)}})
+	var reply struct {
+		Data types.PostResponse `json:"data"`
+	}
+	select {
+	case frame := <-c.Send:
+		if err := json.Unmarshal(frame, &reply); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply to a POST request while disconnected")
+	}
+	response := reply.Data.Response
+	if response.Code != types.ErrCodeNotConnected || response.Retryable == nil || !*response.Retryable {
+		t.Fatalf("POST reply = %+v, want a retryable not_connected error", response)
+	}
+}
+
+func TestKeepaliveOnlyOnQuietSubscriptions(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC")
+	c := client.NewClient(nil, p.hub)
+	sub := &types.SubscriptionRequest{Type: "trades", Coin: "BTC"}
+	p.handleSubscribe(c, sub)
+	framesOn(c, "subscriptionResponse")
+	
+	p.sendSubscriptionKeepalives(time.Second)
+	if frames := framesOn(c, "trades"); len(frames) != 0 {
+		t.Fatalf("frames on a fresh subscription = %q, want none", frames)
+	}
+	
+	// Quiet for longer than the interval
+	p.subMu.Lock()
+	p.globalSubscriptions[sub.Key()].LastUpdate = time.Now().Add(-2 * time.Second)
+	p.subMu.Unlock()
+	p.sendSubscriptionKeepalives(time.Second)
+	if frames := framesOn(c, "trades"); len(frames) != 1 || frames[0] != `{"channel":"trades","keepalive":true}` {
+		t.Fatalf("frames on a quiet subscription = %q, want one keepalive", frames)
+	}
+	
+	// The keepalive itself restarts the interval
+	p.sendSubscriptionKeepalives(time.Second)
+	if frames := framesOn(c, "trades"); len(frames) != 0 {
+		t.Fatalf("frames right after a keepalive = %q, want none", frames)
+	}
+}
+
+func TestStartFailsWithoutAViableDataSource(t *testing.T) {
+	infoURL := startInfoServer(t, `{"universe":[{"name":"BTC","szDecimals":5}]}`, `{"universe":[],"tokens":[]}`)
+	
+	withReplicaCmds := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(withReplicaCmds, "replica_cmds"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		localNode bool
+		dataPath  string
+		want      string
+	}{
+		{"local node without replica_cmds", true, t.TempDir(), "local node data source unusable"},
+		{"local node producing no blocks", true, withReplicaCmds, "no blocks read"},
+		// The info server refuses WebSocket upgrades like an unreachable upstream
+		{"unreachable upstream", false, "", "failed to connect to Hyperliquid"},
+	}
+	
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := config.LoadConfig("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Hyperliquid.MainnetURL = "ws" + strings.TrimPrefix(infoURL, "http") + "/ws"
+			cfg.Proxy.EnableLocalNode = tc.localNode
+			cfg.Proxy.LocalNodeDataPath = tc.dataPath
+			cfg.Proxy.DataSourceGraceSec = 1
+			
+			p := NewProxy(cfg)
+			t.Cleanup(p.assetFetcher.Stop)
+			err = p.Start()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Start() = %v, want an error about %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNamespacedSubscribeUsesTheSharedChannel(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC")
+	c := client.NewClient(nil, p.hub)
+	c.Namespace = "app1"
+	
+	p.handleClientMessage(c, []byte(`{"method":"subscribe","subscription":{"type":"app1:trades","coin":"BTC"}}`))
+	key := (&types.SubscriptionRequest{Type: "trades", Coin: "BTC"}).Key()
+	p.subMu.RLock()
+	subInfo, exists := p.globalSubscriptions[key]
+	registered := exists && subInfo.Clients[c]
+	p.subMu.RUnlock()
+	if !registered {
+		t.Fatalf("namespaced subscribe not registered under %s", key)
+	}
+}
+
+func TestUpstreamDataRightAfterSubscribeIsDelivered(t *testing.T) {
+	// The stub answers a subscribe with data before acknowledging it
+	url := startUpstream(t, func(conn *websocket.Conn, msg types.WSMessage) {
+		if msg.Method != "subscribe" || msg.Subscription == nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"trades","data":[{"coin":"BTC","side":"B","px":"100","sz":"1","time":1,"hash":"0x1","tid":1}]}`))
+		ack, _ := json.Marshal(types.WSMessage{Method: "subscribe", Subscription: msg.Subscription})
+		conn.WriteJSON(types.WSMessage{Channel: "subscriptionResponse", Data: ack})
+	})
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.MainnetURL = url
+		cfg.Proxy.EnableLocalNode = false
+	})
+	if err := p.hlConnector.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.hlConnector.Disconnect)
+	
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "trades", Coin: "BTC"})
+	if frame := waitForFrame(t, c, "trades"); !strings.Contains(frame, `"tid":1`) {
+		t.Fatalf("first trades frame = %s, want the one sent right after subscribing", frame)
+	}
+}
+
+func TestInitialTradesSnapshotHonorsConfiguredDepth(t *testing.T) {
+	cases := []struct {
+		depth int
+		want  int
+	}{
+		{3, 3},
+		{50, 10}, // clamped to the history retained
+		{0, 0},
+	}
+	
+	for _, tc := range cases {
+		p := newTestProxy(t, func(cfg *config.Config) {
+			cfg.Proxy.InitialSnapshotDepths = map[string]int{"trades": tc.depth}
+		})
+		listAssets(p, "BTC")
+		for i := 1; i <= 10; i++ {
+			px := fmt.Sprint(100 + i)
+			p.localNodeReader.processOrders([]Order{limitOrder(true, px, "1")}, nil, decodeStatuses(t, fmt.Sprintf(`[{"filled":{"totalSz":"1","avgPx":"%s","oid":%d}}]`, px, i)), "2025-01-01T00:00:00.000", "0xtaker")
+		}
+		
+		snapshot := &snapshotBatch{}
+		p.addInitialLocalNodeData(client.NewClient(nil, p.hub), &types.SubscriptionRequest{Type: "trades", Coin: "BTC"}, snapshot)
+		if len(snapshot.frames) != tc.want {
+			t.Fatalf("depth %d sent %d trades, want %d", tc.depth, len(snapshot.frames), tc.want)
+		}
+		if tc.want > 0 && !strings.Contains(string(snapshot.frames[len(snapshot.frames)-1]), `"px":"110"`) {
+			t.Fatalf("depth %d snapshot ends with %s, want the latest trade", tc.depth, snapshot.frames[len(snapshot.frames)-1])
+		}
+	}
+}
+
+func TestUpstreamPauseAndResumeNotified(t *testing.T) {
+	// The stub drops the first connection once a subscription arrives on it
+	var dropped atomic.Bool
+	url := startUpstream(t, func(conn *websocket.Conn, msg types.WSMessage) {
+		if msg.Method == "subscribe" && dropped.CompareAndSwap(false, true) {
+			conn.Close()
+		}
+	})
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.MainnetURL = url
+		cfg.Proxy.EnableLocalNode = false
+		cfg.Proxy.NotifyUpstreamStatus = true
+		cfg.Proxy.ReconnectInterval = 1
+	})
+	if err := p.hlConnector.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.hlConnector.Disconnect)
+	
+	// Subscribe once the connection's initial resubscribe round is over
+	for deadline := time.Now().Add(5 * time.Second); !p.Ready(); time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not ready after connecting")
+		}
+	}
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "trades", Coin: "BTC"})
+	
+	if frame := waitForFrame(t, c, "notification"); !strings.Contains(frame, "data paused") {
+		t.Fatalf("first notification = %s, want data paused", frame)
+	}
+	if frame := waitForFrame(t, c, "notification"); !strings.Contains(frame, "data resumed, resubscribed") {
+		t.Fatalf("second notification = %s, want data resumed", frame)
+	}
+}
+
+// fillSendBuffer fills the rest of a client's Send buffer
+func fillSendBuffer(c *client.Client) {
+	for len(c.Send) < cap(c.Send) {
+		c.Send <- []byte(`{"channel":"filler"}`)
+	}
+}
+
+func TestDropsCountedAtEachSite(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC")
+	
+	// Forwarding to a full client
+	slow := client.NewClient(nil, p.hub)
+	sub := &types.SubscriptionRequest{Type: "trades", Coin: "BTC"}
+	p.handleSubscribe(slow, sub)
+	fillSendBuffer(slow)
+	p.forwardMessageToSubscription(sub.Key(), []byte(`{"channel":"trades","data":[]}`))
+	
+	// A snapshot, and an update held behind it, that a client goes away before taking
+	gone := client.NewClient(nil, p.hub)
+	p.hub.Register <- gone
+	fillSendBuffer(gone)
+	snapshot := &snapshotBatch{}
+	snapshot.add([]byte(`{"channel":"l2Book","data":{}}`))
+	p.sendSnapshot(gone, snapshot)
+	p.safelyTryToSendMessage(gone, []byte(`{"channel":"allMids","data":{}}`))
+	p.hub.Unregister <- gone
+	
+	want := map[string]int64{"trades": 1, "l2Book": 1, "allMids": 1}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := p.GetStats()
+		if stats.MessagesDropped == 3 && fmt.Sprint(stats.DroppedByChannel) == fmt.Sprint(want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("drops = %d by channel %v, want 3 by channel %v", stats.MessagesDropped, stats.DroppedByChannel, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnsubscribeBySubscriptionID(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC", "ETH")
+	c := client.NewClient(nil, p.hub)
+	
+	subscribe := func(coin string) int64 {
+		p.handleClientMessage(c, []byte(`{"method":"subscribe","subscription":{"type":"trades","coin":"`+coin+`"}}`))
+		var response struct {
+			Data struct {
+				SubscriptionID int64 `json:"subscriptionId"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(waitForFrame(t, c, "subscriptionResponse")), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Data.SubscriptionID
+	}
+	btc, eth := subscribe("BTC"), subscribe("ETH")
+	if btc == 0 || eth == 0 || btc == eth {
+		t.Fatalf("subscription ids %d and %d, want two distinct ids", btc, eth)
+	}
+	
+	p.handleClientMessage(c, []byte(fmt.Sprintf(`{"method":"unsubscribe","subscriptionId":%d}`, btc)))
+	p.subMu.RLock()
+	_, btcLeft := p.globalSubscriptions[(&types.SubscriptionRequest{Type: "trades", Coin: "BTC"}).Key()]
+	_, ethLeft := p.globalSubscriptions[(&types.SubscriptionRequest{Type: "trades", Coin: "ETH"}).Key()]
+	p.subMu.RUnlock()
+	if btcLeft || !ethLeft {
+		t.Fatalf("after unsubscribing id %d: BTC subscribed %v, ETH subscribed %v, want only ETH left", btc, btcLeft, ethLeft)
+	}
+	
+	// The id is gone with its subscription
+	framesOn(c, "")
+	p.handleClientMessage(c, []byte(fmt.Sprintf(`{"method":"unsubscribe","subscriptionId":%d}`, btc)))
+	if frames := framesOn(c, ""); len(frames) != 1 || !strings.Contains(frames[0], "Unknown subscription id") {
+		t.Fatalf("reply to a stale id = %q, want an unknown id error", frames)
+	}
+}
+
+func TestUnregisterRacesForwarding(t *testing.T) {
+	p := newTestProxy(t, nil)
+	frame := []byte(`{"channel":"allMids","data":{"mids":{"BTC":"100"}}}`)
+	
+	stop := make(chan struct{})
+	var forwarders sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		forwarders.Add(1)
+		go func() {
+			defer forwarders.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					p.forwardMessageToClients("allMids", frame)
+				}
+			}
+		}()
+	}
+	
+	var clients sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		clients.Add(1)
+		go func() {
+			defer clients.Done()
+			for j := 0; j < 50; j++ {
+				c := client.NewClient(nil, p.hub)
+				p.hub.Register <- c
+				p.handleSubscribe(c, &types.SubscriptionRequest{Type: "allMids"})
+				p.hub.Unregister <- c
+			}
+		}()
+	}
+	clients.Wait()
+	close(stop)
+	forwarders.Wait()
+	
+	// Unregistered clients were detached from every subscription
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	for key, subInfo := range p.globalSubscriptions {
+		if len(subInfo.Clients) != 0 {
+			t.Fatalf("subscription %s still has %d clients after they all unregistered", key, len(subInfo.Clients))
+		}
+	}
+}
+
+func TestStatsLoggedAtConfiguredIntervalAndLevel(t *testing.T) {
+	hook := captureLogs(t, logrus.DebugLevel)
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Logging.StatsIntervalSec = 1
+		cfg.Logging.StatsLevel = "info"
+	})
+	if interval := p.statsInterval(); interval != time.Second {
+		t.Fatalf("stats interval = %s, want the configured 1s", interval)
+	}
+	
+	go p.updateStats()
+	time.Sleep(500 * time.Millisecond)
+	if countLogs(hook, "Proxy statistics") != 0 {
+		t.Fatal("statistics logged before the first interval elapsed")
+	}
+	time.Sleep(800 * time.Millisecond)
+	
+	var found bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Proxy statistics" {
+			found = true
+			if entry.Level != logrus.InfoLevel {
+				t.Fatalf("statistics logged at %s, want info", entry.Level)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("statistics not logged after the interval")
+	}
+}
+
+func TestStatsLoggingDisabledAtZero(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Logging.StatsIntervalSec = 0
+	})
+	
+	done := make(chan struct{})
+	go func() {
+		p.updateStats()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("updateStats kept running with a 0 interval")
+	}
+}
+
+func TestSubscribeMissingRequiredFieldIsRejected(t *testing.T) {
+	p := newTestProxy(t, nil)
+	c := client.NewClient(nil, p.hub)
+	
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "activeAssetData", User: "0xabc"})
+	var errorFrame struct {
+		Code    string                 `json:"code"`
+		Details map[string]interface{} `json:"details"`
+	}
+	select {
+	case frame := <-c.Send:
+		if err := json.Unmarshal(frame, &errorFrame); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("activeAssetData without a coin was accepted silently")
+	}
+	if errorFrame.Code != types.ErrCodeInvalidRequest || fmt.Sprint(errorFrame.Details["missing_fields"]) != "[coin]" {
+		t.Fatalf("error frame = %+v, want invalid_request naming the coin", errorFrame)
+	}
+	if len(c.GetSubscriptions()) != 0 {
+		t.Fatal("subscription registered although a required field is missing")
+	}
+}
+
+func TestSubscribeUnknownTypeIsRejected(t *testing.T) {
+	p := newTestProxy(t, nil)
+	c := client.NewClient(nil, p.hub)
+	
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "l3Book", Coin: "BTC"})
+	var errorFrame struct {
+		Code    string                 `json:"code"`
+		Details map[string]interface{} `json:"details"`
+	}
+	select {
+	case frame := <-c.Send:
+		if err := json.Unmarshal(frame, &errorFrame); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("unknown subscription type was accepted silently")
+	}
+	if errorFrame.Code != types.ErrCodeInvalidRequest || errorFrame.Details["type"] != "l3Book" {
+		t.Fatalf("error frame = %+v, want invalid_request naming the type", errorFrame)
+	}
+	if len(c.GetSubscriptions()) != 0 {
+		t.Fatal("subscription registered for an unknown type")
+	}
+}
+
+func TestClientLeavingDuringUpstreamSubscribeLeavesNoOrphan(t *testing.T) {
+	unsubscribed := make(chan string, 10)
+	url := startUpstream(t, func(conn *websocket.Conn, msg types.WSMessage) {
+		if msg.Method == "unsubscribe" && msg.Subscription != nil {
+			unsubscribed <- msg.Subscription.Coin
+		}
+	})
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Hyperliquid.MainnetURL = url
+		cfg.Proxy.EnableLocalNode = false
+		cfg.Proxy.UpstreamSubscribeBatch = 1
+	})
+	if err := p.hlConnector.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.hlConnector.Disconnect)
+	
+	// Use up the pacing window so the next upstream subscribe stays in flight
+	if err := p.hlConnector.Subscribe(&types.SubscriptionRequest{Type: "trades", Coin: "ETH"}); err != nil {
+		t.Fatal(err)
+	}
+	
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	sub := &types.SubscriptionRequest{Type: "trades", Coin: "BTC"}
+	p.handleSubscribe(c, sub)
+	p.hub.Unregister <- c
+	
+	select {
+	case coin := <-unsubscribed:
+		if coin != "BTC" {
+			t.Fatalf("unsubscribed %s upstream, want BTC", coin)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream subscription left behind after its only client left")
+	}
+	if refs := p.hlConnector.GetSubscriptionRefCounts(); refs[sub.Key()] != 0 {
+		t.Fatalf("upstream subscription still holds %d references", refs[sub.Key()])
+	}
+	p.subMu.RLock()
+	_, exists := p.globalSubscriptions[sub.Key()]
+	p.subMu.RUnlock()
+	if exists {
+		t.Fatal("subscription without clients still registered")
+	}
+}
+
+func TestOnlyRequestedL2BookSnapshotsAreCompressed(t *testing.T) {
+	p := newTestProxy(t, nil)
+	data := []byte(`{"channel":"l2Book","data":{"coin":"BTC","levels":[[],[]]}}`)
+	
+	snapshot := &snapshotBatch{}
+	p.addL2BookSnapshot(snapshot, &types.SubscriptionRequest{Type: "l2Book", Coin: "BTC", CompressSnapshot: true}, data)
+	p.addL2BookSnapshot(snapshot, &types.SubscriptionRequest{Type: "l2Book", Coin: "BTC"}, data)
+	if string(snapshot.frames[0]) != string(client.CompressedFrame(data)) || snapshot.channels[0] != "l2Book" {
+		t.Fatalf("requested snapshot queued as %q, want it marked for compression", snapshot.frames[0])
+	}
+	if string(snapshot.frames[1]) != string(data) {
+		t.Fatalf("snapshot queued as %q, want plain JSON without compressSnapshot", snapshot.frames[1])
+	}
+}
+
+// readError returns the error message of the next frame on conn
+func readError(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var response struct {
+		Error string `json:"error"`
+	}
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("no error frame received: %v", err)
+	}
+	return response.Error
+}
+
+func TestBinaryFrameRejectedOnJSONConnection(t *testing.T) {
+	p := newTestProxy(t, nil)
+	go p.processClientMessages()
+	conn := dialProxy(t, p, "")
+	
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte(`{"method":"hello"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if message := readError(t, conn); !strings.Contains(message, "Binary frames are not accepted with the json encoding") {
+		t.Fatalf("binary frame answered with %q, want a clear rejection", message)
+	}
+	
+	// The connection keeps working for text frames
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"hello"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if message := readError(t, conn); message != "Unknown method: hello" {
+		t.Fatalf("text frame after the rejection answered with %q, want it handled as a request", message)
+	}
+}
+
+func TestBinaryFrameRoutedOnBinaryCodec(t *testing.T) {
+	p := newTestProxy(t, nil)
+	go p.processClientMessages()
+	conn := dialProxy(t, p, "?encoding="+client.CodecJSONBinary.Name)
+	
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte(`{"method":"hello"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if message := readError(t, conn); message != "Unknown method: hello" {
+		t.Fatalf("binary frame answered with %q, want it handled as a request", message)
+	}
+}
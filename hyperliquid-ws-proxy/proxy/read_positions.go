@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SaveReadPositions writes how far each block file was read to the read
+// positions file, if configured, so a restart resumes where reading stopped
+func (r *LocalNodeReader) SaveReadPositions() error {
+	path := r.options.ReadPositionsFile
+	if path == "" {
+		return nil
+	}
+	
+	r.dataMu.RLock()
+	positions := make(map[string]int64, len(r.lastReadFiles))
+	for filePath, pos := range r.lastReadFiles {
+		positions[filePath] = pos
+	}
+	r.dataMu.RUnlock()
+	
+	data, err := json.Marshal(positions)
+	if err != nil {
+		return fmt.Errorf("failed to encode read positions: %w", err)
+	}
+	
+	// Write then rename so a crash mid-write never leaves a truncated file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write read positions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write read positions: %w", err)
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"path":  path,
+		"files": len(positions),
+	}).Debug("Saved block file read positions")
+	return nil
+}
+
+// loadReadPositions restores the positions saved by SaveReadPositions, dropping
+// files that no longer exist or shrank below the saved position. Once positions
+// are restored the cold start policy no longer applies.
+func (r *LocalNodeReader) loadReadPositions() error {
+	data, err := os.ReadFile(r.options.ReadPositionsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read read positions: %w", err)
+	}
+	
+	var positions map[string]int64
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return fmt.Errorf("failed to decode read positions: %w", err)
+	}
+	
+	pruned := 0
+	for filePath, pos := range positions {
+		stat, err := os.Stat(filePath)
+		if err != nil || stat.Size() < pos {
+			delete(positions, filePath)
+			pruned++
+		}
+	}
+	
+	r.dataMu.Lock()
+	for filePath, pos := range positions {
+		r.lastReadFiles[filePath] = pos
+	}
+	r.dataMu.Unlock()
+	
+	if len(positions) > 0 {
+		r.coldStartDone = true
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"path":   r.options.ReadPositionsFile,
+		"files":  len(positions),
+		"pruned": pruned,
+	}).Info("Restored block file read positions")
+	return nil
+}
+
+// runReadPositionsCheckpoint saves the read positions every interval until the reader stops
+func (r *LocalNodeReader) runReadPositionsCheckpoint(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		if !r.IsRunning() {
+			return
+		}
+		if err := r.SaveReadPositions(); err != nil {
+			logrus.WithError(err).Warn("Failed to checkpoint block file read positions")
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Layout of the date directories under each replica_cmds timestamp directory
+const replicaCmdsDateLayout = "20060102"
+
+// ReplayProgress reports the replay of historical block files at startup
+type ReplayProgress struct {
+	Active         bool  `json:"active"`
+	From           int64 `json:"from"`            // unix millis replayed from
+	FilesTotal     int   `json:"files_total"`
+	FilesDone      int   `json:"files_done"`
+	BlocksReplayed int64 `json:"blocks_replayed"`
+	ReplayedUpTo   int64 `json:"replayed_up_to"`  // block time of the last replayed block, unix millis
+}
+
+// GetReplayProgress returns the progress of the startup replay
+func (r *LocalNodeReader) GetReplayProgress() ReplayProgress {
+	r.replayMu.RLock()
+	defer r.replayMu.RUnlock()
+	return r.replay
+}
+
+// IsReplaying reports whether historical block files are still being replayed
+func (r *LocalNodeReader) IsReplaying() bool {
+	r.replayMu.RLock()
+	defer r.replayMu.RUnlock()
+	return r.replay.Active
+}
+
+// skipReplayedBlock reports whether a block read during the replay predates
+// the replay start and must be skipped, counting the blocks replayed otherwise
+func (r *LocalNodeReader) skipReplayedBlock(blockTime int64) bool {
+	r.replayMu.Lock()
+	defer r.replayMu.Unlock()
+	
+	if !r.replay.Active {
+		return false
+	}
+	if blockTime < r.replay.From {
+		return true
+	}
+	r.replay.BlocksReplayed++
+	r.replay.ReplayedUpTo = blockTime
+	return false
+}
+
+// replayHistory processes the block files of every replica_cmds date directory
+// from the replay start forward, oldest first, before live tailing begins. Blocks
+// only warm the caches, retention limits apply as for live blocks, and the proxy
+// sends nothing to clients until the replay is done.
+func (r *LocalNodeReader) replayHistory() {
+	from := time.UnixMilli(r.GetReplayProgress().From).UTC()
+	files := r.replayFiles(from)
+	
+	r.replayMu.Lock()
+	r.replay.FilesTotal = len(files)
+	r.replayMu.Unlock()
+	
+	logrus.WithFields(logrus.Fields{
+		"from":  from.Format(time.RFC3339),
+		"files": len(files),
+	}).Info("Replaying local node block files")
+	
+	lastLog := time.Now()
+	for i, filePath := range files {
+		if !r.IsRunning() {
+			break
+		}
+		r.readWholeBlockFile(filePath)
+		
+		r.replayMu.Lock()
+		r.replay.FilesDone = i + 1
+		progress := r.replay
+		r.replayMu.Unlock()
+		
+		if time.Since(lastLog) >= 10*time.Second {
+			lastLog = time.Now()
+			logrus.WithFields(logrus.Fields{
+				"files_done":      progress.FilesDone,
+				"files_total":     progress.FilesTotal,
+				"blocks_replayed": progress.BlocksReplayed,
+				"replayed_up_to":  time.UnixMilli(progress.ReplayedUpTo).UTC().Format(time.RFC3339),
+			}).Info("Replay progress")
+		}
+	}
+	
+	// The replay positioned the reader, live tailing continues from there
+	r.coldStartDone = true
+	
+	r.replayMu.Lock()
+	r.replay.Active = false
+	progress := r.replay
+	r.replayMu.Unlock()
+	
+	logrus.WithFields(logrus.Fields{
+		"files":           progress.FilesDone,
+		"blocks_replayed": progress.BlocksReplayed,
+	}).Info("Replay complete, tailing live blocks")
+}
+
+// replayFiles lists the block files of the date directories on or after the
+// day of from, across every timestamp directory, oldest first
+func (r *LocalNodeReader) replayFiles(from time.Time) []string {
+	replicaCmdsPath := filepath.Join(r.dataPath, "replica_cmds")
+	fromDay := from.Format(replicaCmdsDateLayout)
+	
+	var files []string
+	for _, timestampDir := range sortedEntries(replicaCmdsPath, true) {
+		timestampPath := filepath.Join(replicaCmdsPath, timestampDir)
+		for _, dateDir := range sortedEntries(timestampPath, true) {
+			if _, err := time.Parse(replicaCmdsDateLayout, dateDir); err == nil && dateDir < fromDay {
+				continue
+			}
+			datePath := filepath.Join(timestampPath, dateDir)
+			for _, fileName := range sortedEntries(datePath, false) {
+				files = append(files, filepath.Join(datePath, fileName))
+			}
+		}
+	}
+	return files
+}
+
+// sortedEntries returns the sorted names of a directory's subdirectories, or of
+// its files when dirs is false
+func sortedEntries(path string, dirs bool) []string {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() == dirs {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readWholeBlockFile reads a block file to its end from the last read position,
+// in as many reads as readBlockFile needs
+func (r *LocalNodeReader) readWholeBlockFile(filePath string) {
+	for r.IsRunning() {
+		stat, err := os.Stat(filePath)
+		if err != nil {
+			return
+		}
+		
+		r.dataMu.RLock()
+		pos := r.lastReadFiles[filePath]
+		r.dataMu.RUnlock()
+		if pos >= stat.Size() {
+			return
+		}
+		
+		r.readBlockFile(filePath, pos)
+		
+		r.dataMu.RLock()
+		advanced := r.lastReadFiles[filePath] > pos
+		r.dataMu.RUnlock()
+		if !advanced {
+			return
+		}
+	}
+}
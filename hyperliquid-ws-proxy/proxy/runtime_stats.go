@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Minimum time between runtime.ReadMemStats calls, which briefly stops the world,
+// so frequent /stats polling can't stall the proxy
+const memStatsMaxAge = time.Second
+
+// RuntimeStats holds process health figures for the /stats endpoint
+type RuntimeStats struct {
+	Goroutines     int
+	HeapAlloc      uint64 // bytes of allocated heap objects
+	HeapInuse      uint64 // bytes in in-use heap spans
+	MonitoredFiles int    // block files tracked by the local node reader
+	Replay         *ReplayProgress // startup replay of historical blocks, nil when not configured
+}
+
+// memStatsCache holds the last runtime.MemStats read
+type memStatsCache struct {
+	mu     sync.Mutex
+	stats  runtime.MemStats
+	readAt time.Time
+}
+
+// GetRuntimeStats returns goroutine, heap and monitored file counts. Memory
+// figures are at most memStatsMaxAge old.
+func (p *Proxy) GetRuntimeStats() RuntimeStats {
+	p.memStats.mu.Lock()
+	if time.Since(p.memStats.readAt) >= memStatsMaxAge {
+		runtime.ReadMemStats(&p.memStats.stats)
+		p.memStats.readAt = time.Now()
+	}
+	stats := RuntimeStats{
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  p.memStats.stats.HeapAlloc,
+		HeapInuse:  p.memStats.stats.HeapInuse,
+	}
+	p.memStats.mu.Unlock()
+	
+	if p.localNodeReader != nil {
+		stats.MonitoredFiles = p.localNodeReader.GetMonitoredFiles()
+		if !p.localNodeReader.options.ReplayFrom.IsZero() {
+			replay := p.localNodeReader.GetReplayProgress()
+			stats.Replay = &replay
+		}
+	}
+	return stats
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+// snapshotChannels are the user channels opening with an isSnapshot frame that
+// is followed by incremental updates
+var snapshotChannels = map[string]bool{
+	"userFills":                   true,
+	"userFundings":                true,
+	"userNonFundingLedgerUpdates": true,
+	"userTwapSliceFills":          true,
+	"userTwapHistory":             true,
+}
+
+// buildSnapshotCompleteMessage builds the snapshotComplete frame telling clients
+// the snapshot of a subscription was delivered and incremental updates follow
+func (p *Proxy) buildSnapshotCompleteMessage(sub *types.SubscriptionRequest) []byte {
+	return []byte(fmt.Sprintf(`{"channel":"snapshotComplete","data":{"subscription":%s}}`, p.toJSON(sub)))
+}
+
+// addSnapshotComplete ends the initial snapshot of a user channel subscription
+// with the snapshotComplete marker, when snapshot_complete_marker is enabled
+func (p *Proxy) addSnapshotComplete(snapshot *snapshotBatch, sub *types.SubscriptionRequest) {
+	if !p.config.Proxy.SnapshotCompleteMarker || !snapshotChannels[sub.Type] {
+		return
+	}
+	snapshot.add(p.buildSnapshotCompleteMessage(sub))
+}
+
+// forwardSnapshotComplete follows an isSnapshot frame Hyperliquid sent on a user
+// channel with the snapshotComplete marker, to the same clients
+func (p *Proxy) forwardSnapshotComplete(channel string, data json.RawMessage) {
+	if !p.config.Proxy.SnapshotCompleteMarker || !snapshotChannels[channel] {
+		return
+	}
+	
+	var snapshot struct {
+		User       string `json:"user"`
+		IsSnapshot bool   `json:"isSnapshot"`
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil || !snapshot.IsSnapshot {
+		return
+	}
+	p.forwardMessageToClients(channel, p.buildSnapshotCompleteMessage(&types.SubscriptionRequest{Type: channel, User: snapshot.User}))
+}
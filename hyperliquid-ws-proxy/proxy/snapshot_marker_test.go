@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/types"
+)
+
+func TestSnapshotCompleteBetweenSnapshotAndUpdates(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.SnapshotCompleteMarker = true
+	})
+	fill := func(oid int) {
+		statuses := decodeStatuses(t, fmt.Sprintf(`[{"filled":{"totalSz":"1","avgPx":"100","oid":%d}}]`, oid))
+		p.localNodeReader.processOrders([]Order{limitOrder(true, "100", "1")}, nil, statuses, "2025-01-01T00:00:00.000", "0xabc")
+	}
+	fill(1)
+	
+	c := client.NewClient(nil, p.hub)
+	p.hub.Register <- c
+	p.handleSubscribe(c, &types.SubscriptionRequest{Type: "userFills", User: "0xabc"})
+	p.generateUserFillsFromLocalNode()
+	fill(2)
+	p.generateUserFillsFromLocalNode()
+	
+	// The subscription response aside, frames come as snapshot, marker, update
+	var channels []string
+	timeout := time.After(5 * time.Second)
+	for len(channels) < 3 {
+		select {
+		case frame := <-c.Send:
+			channel := frameChannel(frame)
+			if channel == "subscriptionResponse" {
+				continue
+			}
+			if channel == "userFills" && strings.Contains(string(frame), `"isSnapshot":true`) {
+				channel = "userFills snapshot"
+			}
+			channels = append(channels, channel)
+		case <-timeout:
+			t.Fatalf("frames so far %v, want a snapshot, its marker and an update", channels)
+		}
+	}
+	if got := strings.Join(channels, ", "); got != "userFills snapshot, snapshotComplete, userFills" {
+		t.Fatalf("frames in order: %s, want the marker between the snapshot and the update", got)
+	}
+}
+
+func TestSnapshotCompleteOnlyWhenEnabled(t *testing.T) {
+	p := newTestProxy(t, nil)
+	sub := &types.SubscriptionRequest{Type: "userFills", User: "0xabc"}
+	
+	snapshot := &snapshotBatch{}
+	p.addSnapshotComplete(snapshot, sub)
+	if len(snapshot.frames) != 0 {
+		t.Fatalf("marker added with snapshot_complete_marker off: %s", snapshot.frames[0])
+	}
+	
+	// Channels without a snapshot never get one
+	p.config.Proxy.SnapshotCompleteMarker = true
+	p.addSnapshotComplete(snapshot, &types.SubscriptionRequest{Type: "orderUpdates", User: "0xabc"})
+	if len(snapshot.frames) != 0 {
+		t.Fatalf("marker added for orderUpdates: %s", snapshot.frames[0])
+	}
+}
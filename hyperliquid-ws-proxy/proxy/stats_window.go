@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatsCounters are the proxy's cumulative message counters. They are never
+// reset, so they stay valid Prometheus counters; even at a million messages per
+// second an int64 takes about 290,000 years to wrap, so wraparound isn't handled.
+type StatsCounters struct {
+	MessagesProcessed   int64 `json:"messages_processed"`
+	MessagesForwarded   int64 `json:"messages_forwarded"`
+	MessagesSuppressed  int64 `json:"messages_suppressed"`
+	MessagesDropped     int64 `json:"messages_dropped"`
+	PostRequestsHandled int64 `json:"post_requests_handled"`
+}
+
+// since returns the counts accumulated after the baseline was taken
+func (c StatsCounters) since(baseline StatsCounters) StatsCounters {
+	return StatsCounters{
+		MessagesProcessed:   c.MessagesProcessed - baseline.MessagesProcessed,
+		MessagesForwarded:   c.MessagesForwarded - baseline.MessagesForwarded,
+		MessagesSuppressed:  c.MessagesSuppressed - baseline.MessagesSuppressed,
+		MessagesDropped:     c.MessagesDropped - baseline.MessagesDropped,
+		PostRequestsHandled: c.PostRequestsHandled - baseline.PostRequestsHandled,
+	}
+}
+
+// statsCounters returns the current cumulative counters. Caller must hold p.stats.mu.
+func (p *Proxy) statsCounters() StatsCounters {
+	messagesDropped, _ := p.drops.totals()
+	return StatsCounters{
+		MessagesProcessed:   p.stats.MessagesProcessed,
+		MessagesForwarded:   p.stats.MessagesForwarded,
+		MessagesSuppressed:  p.stats.MessagesSuppressed,
+		MessagesDropped:     messagesDropped,
+		PostRequestsHandled: p.stats.PostRequestsHandled,
+	}
+}
+
+// resetStatsWindow starts a new since-reset window at the current counters.
+// The cumulative counters are left untouched.
+func (p *Proxy) resetStatsWindow() {
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+	
+	p.stats.resetBaseline = p.statsCounters()
+	p.stats.LastReset = time.Now()
+}
+
+// runStatsReset starts a new since-reset stats window every interval
+func (p *Proxy) runStatsReset(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		p.resetStatsWindow()
+		logrus.WithField("interval", interval).Debug("Reset statistics window")
+	}
+}
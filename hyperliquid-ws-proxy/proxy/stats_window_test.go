@@ -0,0 +1,31 @@
+package proxy
+
+import "testing"
+
+func TestStatsCountersConsistentAcrossReset(t *testing.T) {
+	p := newTestProxy(t, nil)
+	p.addForwardedMessages(5)
+	p.addSuppressedMessages(2)
+	
+	before := p.GetStats()
+	if before.SinceReset.MessagesForwarded != 5 || before.SinceReset.MessagesSuppressed != 2 {
+		t.Fatalf("since reset before any reset = %+v, want the cumulative counts", before.SinceReset)
+	}
+	
+	p.resetStatsWindow()
+	if stats := p.GetStats(); stats.SinceReset != (StatsCounters{}) || stats.MessagesForwarded != 5 {
+		t.Fatalf("right after a reset: since reset %+v, forwarded %d, want zero and 5", stats.SinceReset, stats.MessagesForwarded)
+	}
+	
+	p.addForwardedMessages(3)
+	after := p.GetStats()
+	if after.MessagesForwarded != 8 || after.MessagesSuppressed != 2 {
+		t.Fatalf("cumulative forwarded, suppressed = %d, %d, want 8, 2 as resets never touch them", after.MessagesForwarded, after.MessagesSuppressed)
+	}
+	if after.SinceReset.MessagesForwarded != 3 || after.SinceReset.MessagesSuppressed != 0 {
+		t.Fatalf("since reset = %+v, want only the 3 forwarded after it", after.SinceReset)
+	}
+	if !after.LastReset.After(before.LastReset) {
+		t.Fatalf("last reset %s not after the start %s", after.LastReset, before.LastReset)
+	}
+}
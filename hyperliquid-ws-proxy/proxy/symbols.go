@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Symbol formats a client can select when connecting
+const (
+	SymbolFormatName = "name" // resolved names such as BTC or PURR/USDC
+	SymbolFormatRaw  = "raw"  // node asset ids: ASSET_<n> for perps, @<n> for spot pairs
+)
+
+// rawSymbol returns the raw asset id form of a resolved symbol, or the symbol
+// itself when the AssetFetcher doesn't know it
+func (p *Proxy) rawSymbol(name string) string {
+	if p.assetFetcher == nil {
+		return name
+	}
+	
+	asset, exists := p.assetFetcher.GetAssetByName(name)
+	if !exists {
+		return name
+	}
+	if asset.IsSpot {
+		return "@" + strconv.Itoa(asset.TokenIndex)
+	}
+	return "ASSET_" + strconv.Itoa(asset.Index)
+}
+
+// ResolveSymbol returns the resolved name of a raw asset id symbol, or the
+// symbol itself when it isn't a raw id or the asset is unknown
+func (p *Proxy) ResolveSymbol(symbol string) string {
+	if p.assetFetcher == nil {
+		return symbol
+	}
+	
+	switch {
+	case strings.HasPrefix(symbol, "ASSET_"):
+		if index, err := strconv.Atoi(strings.TrimPrefix(symbol, "ASSET_")); err == nil {
+			if asset, exists := p.assetFetcher.GetPerpAsset(index); exists {
+				return asset.Name
+			}
+		}
+	case strings.HasPrefix(symbol, "@"):
+		if index, err := strconv.Atoi(strings.TrimPrefix(symbol, "@")); err == nil {
+			if asset, exists := p.assetFetcher.GetSpotAsset(index); exists {
+				return asset.Name
+			}
+		}
+	}
+	return symbol
+}
+
+// RawSymbolFrame rewrites the symbols of an outbound frame to raw asset ids:
+// every "coin" field and "coins" list, the keys of "mids" maps and a candle's
+// "s" field.
+// Frames that aren't JSON objects are returned unchanged.
+func (p *Proxy) RawSymbolFrame(frame []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(frame))
+	decoder.UseNumber() // keep large integers such as tids exact
+	
+	var message map[string]interface{}
+	if err := decoder.Decode(&message); err != nil {
+		return frame
+	}
+	
+	data, exists := message["data"]
+	if !exists {
+		return frame
+	}
+	
+	if candle, ok := data.(map[string]interface{}); ok && message["channel"] == "candle" {
+		if symbol, ok := candle["s"].(string); ok {
+			candle["s"] = p.rawSymbol(symbol)
+		}
+	}
+	message["data"] = p.rawSymbols(data)
+	
+	rewritten, err := json.Marshal(message)
+	if err != nil {
+		return frame
+	}
+	return rewritten
+}
+
+// rawSymbols walks a decoded JSON value converting symbols to raw asset ids
+func (p *Proxy) rawSymbols(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch {
+			case key == "coin":
+				if symbol, ok := field.(string); ok {
+					v[key] = p.rawSymbol(symbol)
+				}
+			case key == "coins":
+				if coins, ok := field.([]interface{}); ok {
+					for i, coin := range coins {
+						if symbol, ok := coin.(string); ok {
+							coins[i] = p.rawSymbol(symbol)
+						}
+					}
+				}
+			case key == "mids":
+				if mids, ok := field.(map[string]interface{}); ok {
+					raw := make(map[string]interface{}, len(mids))
+					for symbol, mid := range mids {
+						raw[p.rawSymbol(symbol)] = mid
+					}
+					v[key] = raw
+				}
+			default:
+				v[key] = p.rawSymbols(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = p.rawSymbols(v[i])
+		}
+	}
+	return value
+}
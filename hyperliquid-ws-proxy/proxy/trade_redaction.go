@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// Redaction of the users of public trades, for clients without an API key
+const (
+	TradeUsersRedact = "redact" // replace addresses with an empty string
+	TradeUsersHash   = "hash"   // replace addresses with a keyed hash, stable until restart
+)
+
+// newTradeUsersKey returns a random key for hashing trade users, so hashes
+// can't be matched against hashes of known addresses
+func newTradeUsersKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// RedactsTradeUsers reports whether trades delivered to a client that
+// authenticated with keyName have their users redacted
+func (p *Proxy) RedactsTradeUsers(keyName string) bool {
+	return p.config.Proxy.TradeUsersRedaction != "" && keyName == ""
+}
+
+// RedactTradeUsersFrame rewrites the users of every trade in a trades frame per
+// trade_users_redaction. Other frames, including userFills, are returned unchanged.
+func (p *Proxy) RedactTradeUsersFrame(frame []byte) []byte {
+	if !bytes.Contains(frame, []byte(`"users"`)) {
+		return frame
+	}
+	
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(frame, &message); err != nil {
+		return frame
+	}
+	var channel string
+	if err := json.Unmarshal(message["channel"], &channel); err != nil || channel != "trades" {
+		return frame
+	}
+	
+	var trades []map[string]json.RawMessage
+	if err := json.Unmarshal(message["data"], &trades); err != nil {
+		return frame
+	}
+	for _, trade := range trades {
+		var users []string
+		if err := json.Unmarshal(trade["users"], &users); err != nil {
+			continue
+		}
+		for i, user := range users {
+			users[i] = p.redactTradeUser(user)
+		}
+		if redacted, err := json.Marshal(users); err == nil {
+			trade["users"] = redacted
+		}
+	}
+	
+	data, err := json.Marshal(trades)
+	if err != nil {
+		return frame
+	}
+	message["data"] = data
+	
+	rewritten, err := json.Marshal(message)
+	if err != nil {
+		return frame
+	}
+	return rewritten
+}
+
+// redactTradeUser returns the redacted form of a trade user address
+func (p *Proxy) redactTradeUser(user string) string {
+	if p.config.Proxy.TradeUsersRedaction != TradeUsersHash || user == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, p.tradeUsersKey)
+	mac.Write([]byte(strings.ToLower(user)))
+	return "0x" + hex.EncodeToString(mac.Sum(nil)[:20])
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"hyperliquid-ws-proxy/config"
+)
+
+const (
+	buyer  = "0x1111111111111111111111111111111111111111"
+	seller = "0x2222222222222222222222222222222222222222"
+)
+
+// tradeUsers decodes the users of every trade in a trades frame
+func tradeUsers(t *testing.T, frame []byte) [][]string {
+	t.Helper()
+	
+	var message struct {
+		Channel string `json:"channel"`
+		Data    []struct {
+			Users []string `json:"users"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(frame, &message); err != nil {
+		t.Fatalf("invalid trades frame %s: %v", frame, err)
+	}
+	if message.Channel != "trades" {
+		t.Fatalf("frame channel = %q, want trades", message.Channel)
+	}
+	users := make([][]string, len(message.Data))
+	for i, trade := range message.Data {
+		users[i] = trade.Users
+	}
+	return users
+}
+
+func TestPublicTradesAreRedacted(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.TradeUsersRedaction = TradeUsersRedact
+	})
+	if !p.RedactsTradeUsers("") {
+		t.Fatal("trades to a client without an API key are not redacted")
+	}
+	
+	frame := []byte(`{"channel":"trades","data":[{"coin":"BTC","side":"B","px":"100","sz":"1","time":1,"hash":"0x0","tid":1,"users":["` + buyer + `","` + seller + `"]}]}`)
+	users := tradeUsers(t, p.RedactTradeUsersFrame(frame))
+	if len(users) != 1 || len(users[0]) != 2 || users[0][0] != "" || users[0][1] != "" {
+		t.Fatalf("redacted users = %v, want two empty strings", users)
+	}
+}
+
+func TestPublicTradeUsersAreHashed(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.TradeUsersRedaction = TradeUsersHash
+	})
+	
+	frame := []byte(`{"channel":"trades","data":[{"coin":"BTC","users":["` + buyer + `","` + seller + `"]},{"coin":"BTC","users":["` + seller + `","` + buyer + `"]}]}`)
+	users := tradeUsers(t, p.RedactTradeUsersFrame(frame))
+	if len(users) != 2 {
+		t.Fatalf("got %d trades, want 2", len(users))
+	}
+	for _, pair := range users {
+		for _, user := range pair {
+			if user == buyer || user == seller {
+				t.Fatalf("hashed users %v still hold an address", users)
+			}
+			if len(user) != 42 || user[:2] != "0x" {
+				t.Fatalf("hashed user %q is not shaped like an address", user)
+			}
+		}
+	}
+	
+	// The same address hashes the same way, in any trade
+	if users[0][0] != users[1][1] || users[0][1] != users[1][0] {
+		t.Fatalf("hashes are not stable across trades: %v", users)
+	}
+	if users[0][0] == users[0][1] {
+		t.Fatalf("buyer and seller hash alike: %v", users)
+	}
+}
+
+func TestUserFillsKeepAddresses(t *testing.T) {
+	p := newTestProxy(t, func(cfg *config.Config) {
+		cfg.Proxy.TradeUsersRedaction = TradeUsersRedact
+	})
+	
+	frame := []byte(`{"channel":"userFills","data":{"user":"` + buyer + `","fills":[{"coin":"BTC","px":"100","sz":"1","side":"B","users":["` + buyer + `","` + seller + `"]}]}}`)
+	if rewritten := p.RedactTradeUsersFrame(frame); string(rewritten) != string(frame) {
+		t.Fatalf("userFills frame rewritten to %s", rewritten)
+	}
+	
+	// Clients with an API key get trades as they are
+	if p.RedactsTradeUsers("trader") {
+		t.Fatal("trades to a client with an API key are redacted")
+	}
+}
+
+func TestTradesUnredactedByDefault(t *testing.T) {
+	p := newTestProxy(t, nil)
+	if p.RedactsTradeUsers("") {
+		t.Fatal("trades are redacted without trade_users_redaction")
+	}
+}
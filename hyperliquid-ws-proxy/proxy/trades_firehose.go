@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"hyperliquid-ws-proxy/types"
+)
+
+// TradesFirehoseCoin subscribes a trades subscription to every coin, which the
+// proxy serves from the local node's fills
+const TradesFirehoseCoin = "*"
+
+// Markets a trades firehose subscription can be narrowed to with its market field
+const (
+	MarketAll  = "all"
+	MarketPerp = "perp"
+	MarketSpot = "spot"
+)
+
+// isTradesFirehose reports whether a subscription is the all-coins trades firehose
+func isTradesFirehose(sub *types.SubscriptionRequest) bool {
+	return sub.Type == "trades" && sub.Coin == TradesFirehoseCoin
+}
+
+// validateMarketFilter checks the market field of a subscription, which only
+// the trades firehose takes
+func (p *Proxy) validateMarketFilter(sub *types.SubscriptionRequest) *types.ProxyError {
+	if isTradesFirehose(sub) && !p.useLocalNode {
+		return types.NewProxyError(types.ErrCodeUnsupported, "trades firehose (coin \"*\") is only available in local node mode", false)
+	}
+	if sub.Market == "" {
+		return nil
+	}
+	if !isTradesFirehose(sub) {
+		return types.NewProxyError(types.ErrCodeInvalidRequest, "market only applies to the trades firehose (coin \"*\")", false)
+	}
+	switch sub.Market {
+	case MarketAll, MarketPerp, MarketSpot:
+		return nil
+	}
+	return types.NewProxyError(types.ErrCodeInvalidRequest, "market must be \"perp\", \"spot\" or \"all\", got \""+sub.Market+"\"", false)
+}
+
+// inMarket reports whether a coin belongs to the market a firehose subscription
+// selected, telling spot pairs from perps by the AssetFetcher's IsSpot
+func (p *Proxy) inMarket(market, coin string) bool {
+	switch market {
+	case MarketPerp:
+		return !p.localNodeReader.isSpotCoin(coin)
+	case MarketSpot:
+		return p.localNodeReader.isSpotCoin(coin)
+	}
+	return true
+}
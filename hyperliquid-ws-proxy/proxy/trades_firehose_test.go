@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/types"
+)
+
+// storeTrades records fills on the local node as if read from blocks
+func storeTrades(p *Proxy, trades ...*types.WsTrade) {
+	p.localNodeReader.dataMu.Lock()
+	defer p.localNodeReader.dataMu.Unlock()
+	
+	for _, trade := range trades {
+		p.localNodeReader.storeTrade(trade.Coin, trade)
+	}
+}
+
+func TestTradesFirehoseMarketFilter(t *testing.T) {
+	p := newTestProxy(t, nil)
+	listAssets(p, "BTC")
+	purr := &AssetInfo{Index: 1, Name: "PURR/USDC", IsSpot: true}
+	p.assetFetcher.mu.Lock()
+	p.assetFetcher.spotAssets[1] = purr
+	p.assetFetcher.assetsByName[purr.Name] = purr
+	p.assetFetcher.mu.Unlock()
+	storeTrades(p,
+		&types.WsTrade{Coin: "BTC", Px: "100", Sz: "1", TID: 1},
+		&types.WsTrade{Coin: "PURR/USDC", Px: "0.2", Sz: "10", TID: 2})
+	
+	subscribers := map[string]*client.Client{}
+	for _, market := range []string{MarketPerp, MarketSpot, MarketAll} {
+		c := client.NewClient(nil, p.hub)
+		p.hub.Register <- c
+		p.handleSubscribe(c, &types.SubscriptionRequest{Type: "trades", Coin: TradesFirehoseCoin, Market: market})
+		subscribers[market] = c
+	}
+	
+	// The firehose starts at the fills already retained, then forwards new ones
+	p.generateTradesFromLocalNode()
+	for _, c := range subscribers {
+		framesOn(c, "trades")
+	}
+	storeTrades(p,
+		&types.WsTrade{Coin: "BTC", Px: "101", Sz: "1", TID: 3},
+		&types.WsTrade{Coin: "PURR/USDC", Px: "0.21", Sz: "10", TID: 4})
+	p.generateTradesFromLocalNode()
+	
+	// Trade 3 is a perp fill, trade 4 a spot fill
+	want := map[string]map[string]bool{
+		MarketPerp: {`"tid":3`: true, `"tid":4`: false},
+		MarketSpot: {`"tid":3`: false, `"tid":4`: true},
+		MarketAll:  {`"tid":3`: true, `"tid":4`: true},
+	}
+	for market, c := range subscribers {
+		frames := strings.Join(framesOn(c, "trades"), "\n")
+		for tid, wanted := range want[market] {
+			if strings.Contains(frames, tid) != wanted {
+				t.Errorf("%s firehose received %s: %v, want %v (frames %s)", market, tid, !wanted, wanted, frames)
+			}
+		}
+	}
+}
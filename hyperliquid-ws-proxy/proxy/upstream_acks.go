@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/client"
+	"hyperliquid-ws-proxy/types"
+)
+
+// subscriptionAckTimeout bounds how long clients wait on Hyperliquid's
+// acknowledgment of a subscribe before getting the proxy's own response
+const subscriptionAckTimeout = 5 * time.Second
+
+// sendSubscriptionResponse sends a client its subscriptionResponse: Hyperliquid's
+// acknowledgment when ack is set, a response built by the proxy otherwise. Both
+// carry the id the client can unsubscribe with.
+func (p *Proxy) sendSubscriptionResponse(c *client.Client, ack json.RawMessage, sub *types.SubscriptionRequest, subscriptionID int64) {
+	data := json.RawMessage(fmt.Sprintf(`{"method":"subscribe","subscription":%s,"subscriptionId":%d}`, p.toJSON(sub), subscriptionID))
+	if ack != nil {
+		data = withSubscriptionID(ack, subscriptionID)
+	}
+	
+	c.SendMessage(types.WSMessage{
+		Channel: "subscriptionResponse",
+		Data:    data,
+	})
+}
+
+// withSubscriptionID adds the proxy's subscription id to the data of an upstream
+// subscriptionResponse, returning it unchanged if it isn't a JSON object
+func withSubscriptionID(ack json.RawMessage, subscriptionID int64) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(ack, &fields); err != nil {
+		return ack
+	}
+	fields["subscriptionId"] = json.RawMessage(strconv.FormatInt(subscriptionID, 10))
+	
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return ack
+	}
+	return data
+}
+
+// settleSubscriptionAck answers the clients waiting on Hyperliquid's
+// acknowledgment of a subscription: with the upstream subscriptionResponse when
+// ack is set, with upstreamErr when the subscribe failed or was rejected, in
+// which case the subscription is removed, or with the proxy's own response when
+// neither arrived in time. Only the first outcome is delivered.
+func (p *Proxy) settleSubscriptionAck(key string, subInfo *SubscriptionInfo, ack json.RawMessage, upstreamErr *types.ProxyError) {
+	p.subMu.Lock()
+	waiting := subInfo.AwaitingAck
+	subInfo.AwaitingAck = nil
+	if waiting != nil && upstreamErr != nil && p.globalSubscriptions[key] == subInfo {
+		// The upstream subscription doesn't exist, so neither does ours
+		delete(p.globalSubscriptions, key)
+	}
+	p.subMu.Unlock()
+	
+	if waiting == nil {
+		return
+	}
+	
+	for c, subscriptionID := range waiting {
+		if upstreamErr != nil {
+			p.sendErrorToClient(c, upstreamErr)
+			
+			// Release the client's slot
+			c.RemoveSubscription(key)
+			continue
+		}
+		
+		// Batch subscribers were answered when they subscribed
+		if subscriptionID != 0 {
+			p.sendSubscriptionResponse(c, ack, subInfo.Subscription, subscriptionID)
+		}
+	}
+	
+	logrus.WithFields(logrus.Fields{
+		"subscription_key": key,
+		"clients":          len(waiting),
+		"acknowledged":     ack != nil,
+		"rejected":         upstreamErr != nil,
+	}).Debug("Settled upstream subscribe")
+}
+
+// pendingSubscription returns the subscription under key if it still waits on
+// Hyperliquid's acknowledgment
+func (p *Proxy) pendingSubscription(key string) (*SubscriptionInfo, bool) {
+	p.subMu.RLock()
+	defer p.subMu.RUnlock()
+	
+	subInfo, exists := p.globalSubscriptions[key]
+	if !exists || subInfo.AwaitingAck == nil {
+		return nil, false
+	}
+	return subInfo, true
+}
+
+// handleUpstreamSubscriptionResponse relays Hyperliquid's acknowledgment of a
+// subscribe to the clients waiting on it. Acknowledgments nobody waits on, such
+// as those of resubscribes after a reconnect or of unsubscribes, are dropped.
+func (p *Proxy) handleUpstreamSubscriptionResponse(data json.RawMessage) {
+	var response struct {
+		Method       string                     `json:"method"`
+		Subscription *types.SubscriptionRequest `json:"subscription"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil || response.Method != "subscribe" || response.Subscription == nil {
+		return
+	}
+	
+	key := response.Subscription.Key()
+	if subInfo, ok := p.pendingSubscription(key); ok {
+		p.settleSubscriptionAck(key, subInfo, data, nil)
+	}
+}
+
+// handleUpstreamError delivers an error from Hyperliquid to the clients whose
+// subscribe caused it. Hyperliquid names the subscription in the error text,
+// e.g. `Invalid subscription {"type":"trades","coin":"XYZ"}`.
+func (p *Proxy) handleUpstreamError(data json.RawMessage) {
+	var message string
+	if err := json.Unmarshal(data, &message); err != nil {
+		message = string(data)
+	}
+	
+	sub, ok := upstreamErrorSubscription(message)
+	if ok {
+		key := sub.Key()
+		if subInfo, pending := p.pendingSubscription(key); pending {
+			// Hyperliquid already streams it, which is all the clients asked for
+			if strings.HasPrefix(message, "Already subscribed") {
+				p.settleSubscriptionAck(key, subInfo, nil, nil)
+				return
+			}
+			
+			proxyErr := types.NewProxyError(types.ErrCodeUpstream, "Hyperliquid rejected the subscription: "+message, false)
+			proxyErr.Details = map[string]interface{}{
+				"subscription": sub,
+			}
+			p.settleSubscriptionAck(key, subInfo, nil, proxyErr)
+			p.hlConnector.ForgetSubscription(sub)
+			logrus.WithFields(logrus.Fields{
+				"subscription_key": key,
+				"error":            message,
+			}).Warn("Hyperliquid rejected subscription")
+			return
+		}
+	}
+	
+	logrus.WithField("error", message).Warn("Hyperliquid error not tied to a pending subscription")
+}
+
+// upstreamErrorSubscription extracts the subscription an upstream error message names
+func upstreamErrorSubscription(message string) (*types.SubscriptionRequest, bool) {
+	start := strings.IndexByte(message, '{')
+	if start < 0 {
+		return nil, false
+	}
+	
+	var sub types.SubscriptionRequest
+	if err := json.NewDecoder(strings.NewReader(message[start:])).Decode(&sub); err != nil || sub.Type == "" {
+		return nil, false
+	}
+	return &sub, true
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"strings"
+
+	"hyperliquid-ws-proxy/types"
+)
+
+const (
+	maxFillsPerUser = 100   // fills retained per user address
+	maxFillUsers    = 20000 // user addresses retained, the least recently filled is evicted first
+)
+
+// normalizeUser lowercases a hex address so lookups ignore checksum casing
+func normalizeUser(user string) string {
+	return strings.ToLower(user)
+}
+
+// storeUserFill records a fill for a user address. Caller must hold dataMu.
+func (r *LocalNodeReader) storeUserFill(user string, fill types.WsFill) {
+	if user == "" {
+		return
+	}
+	user = normalizeUser(user)
+	
+	fills, exists := r.userFills[user]
+	if !exists && len(r.userFills) >= maxFillUsers {
+		r.evictFillUser()
+	}
+	
+	fills = append(fills, fill)
+	if excess := len(fills) - maxFillsPerUser; excess > 0 {
+		fills = fills[excess:]
+	}
+	r.userFills[user] = fills
+}
+
+// evictFillUser drops the user whose last fill is the oldest. Caller must hold dataMu.
+func (r *LocalNodeReader) evictFillUser() {
+	victim := ""
+	oldest := int64(0)
+	for user, fills := range r.userFills {
+		last := fills[len(fills)-1].Time
+		if victim == "" || last < oldest {
+			victim = user
+			oldest = last
+		}
+	}
+	delete(r.userFills, victim)
+}
+
+// GetUserFills returns up to limit of a user's most recent fills, oldest first,
+// or nil if no fill was seen for the address. A limit of 0 returns all retained fills.
+func (r *LocalNodeReader) GetUserFills(user string, limit int) *types.WsUserFills {
+	r.dataMu.RLock()
+	defer r.dataMu.RUnlock()
+	
+	fills, exists := r.userFills[normalizeUser(user)]
+	if !exists {
+		return nil
+	}
+	
+	if limit > 0 && len(fills) > limit {
+		fills = fills[len(fills)-limit:]
+	}
+	
+	return &types.WsUserFills{
+		User:  user,
+		Fills: append([]types.WsFill(nil), fills...),
+	}
+}
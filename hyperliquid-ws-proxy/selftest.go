@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/config"
+)
+
+// runSelfTest connects a loopback client to the proxy's own /ws endpoint,
+// subscribes to allMids and waits for the first allMids frame, exercising the
+// whole pipeline from the data source through the proxy and server to a client
+func runSelfTest(cfg *config.Config, timeout time.Duration) error {
+	host := cfg.Server.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	url := "ws://" + net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port)) + "/ws"
+
+	// Connections need a key once api_keys are configured
+	header := http.Header{}
+	if key := selfTestKey(cfg); key != "" {
+		header.Set("Authorization", "Bearer "+key)
+	}
+
+	return selfTest(url, header, timeout)
+}
+
+// selfTest dials the WebSocket endpoint at url with the given handshake header,
+// subscribes to allMids and waits up to timeout for the first allMids frame
+func selfTest(url string, header http.Header, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	// The server starts in the background, so retry until it accepts connections
+	var (
+		conn *websocket.Conn
+		err  error
+	)
+	for {
+		conn, _, err = websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not connect to %s: %v", url, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	defer conn.Close()
+
+	subscribe := map[string]interface{}{
+		"method":       "subscribe",
+		"subscription": map[string]string{"type": "allMids"},
+	}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return fmt.Errorf("failed to subscribe to allMids: %v", err)
+	}
+
+	conn.SetReadDeadline(deadline)
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("no allMids data within %s: %v", timeout, err)
+		}
+
+		// Frames may be batched, one JSON message per line
+		for _, line := range bytes.Split(message, []byte("\n")) {
+			var frame struct {
+				Channel string `json:"channel"`
+			}
+			if json.Unmarshal(line, &frame) == nil && frame.Channel == "allMids" {
+				logrus.WithField("url", url).Info("Self-test received allMids data")
+				return nil
+			}
+		}
+	}
+}
+
+// selfTestKey returns the key the self-test connects with: the first
+// configured API key by name, empty when none is configured
+func selfTestKey(cfg *config.Config) string {
+	names := make([]string, 0, len(cfg.Proxy.APIKeys))
+	for name, key := range cfg.Proxy.APIKeys {
+		if key != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return cfg.Proxy.APIKeys[names[0]]
+}
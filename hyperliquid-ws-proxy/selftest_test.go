@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/proxy"
+	"hyperliquid-ws-proxy/server"
+)
+
+// filledBlock is a replica_cmds block holding a single BTC order that filled
+const filledBlock = `{"abci_block":{"time":"2025-01-01T00:00:00.000","round":1,"signed_action_bundles":[["0xbundle",{"signed_actions":[{"signature":{"r":"0x0","s":"0x0","v":27},"action":{"type":"order","orders":[{"a":0,"b":true,"p":"100","s":"1","r":false,"t":{"limit":{"tif":"Ioc"}}}],"grouping":"na"},"nonce":1}],"broadcaster":"0x0","broadcaster_nonce":1}]]},"resps":{"Full":[["0xbundle",[{"user":"0xtaker","res":{"status":"ok","response":{"type":"order","data":{"statuses":[{"filled":{"totalSz":"1","avgPx":"100","oid":1}}]}}}}]]]}}`
+
+func TestSelfTestKey(t *testing.T) {
+	cfg := &config.Config{}
+	if key := selfTestKey(cfg); key != "" {
+		t.Fatalf("selfTestKey without keys = %q, want none", key)
+	}
+
+	cfg.Proxy.APIKeys = map[string]string{"zeta": "z-key", "alpha": "a-key", "empty": ""}
+	if key := selfTestKey(cfg); key != "a-key" {
+		t.Fatalf("selfTestKey = %q, want the key named first", key)
+	}
+}
+
+func TestSelfTestPassesWithWorkingPipeline(t *testing.T) {
+	url := startTestPipeline(t, filledBlock+"\n")
+	if err := selfTest(url, nil, 5*time.Second); err != nil {
+		t.Fatalf("selfTest failed against a working pipeline: %v", err)
+	}
+}
+
+func TestSelfTestFailsWithoutData(t *testing.T) {
+	// The node never writes a block, so the proxy never becomes ready
+	url := startTestPipeline(t, "")
+	if err := selfTest(url, nil, time.Second); err == nil {
+		t.Fatal("selfTest passed although the node produced no data")
+	}
+}
+
+// startTestPipeline runs a local node proxy reading blocks from a temporary
+// replica_cmds directory, with asset metadata from a stub info endpoint, behind
+// the server's handler. It returns the /ws URL. An empty blocks writes no file.
+func startTestPipeline(t *testing.T, blocks string) string {
+	t.Helper()
+
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Type {
+		case "meta":
+			w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5}]}`))
+		case "spotMeta":
+			w.Write([]byte(`{"universe":[],"tokens":[]}`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(info.Close)
+
+	dataPath := t.TempDir()
+	datePath := filepath.Join(dataPath, "replica_cmds", "2025-01-01T00:00:00Z", "20250101")
+	if err := os.MkdirAll(datePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if blocks != "" {
+		if err := os.WriteFile(filepath.Join(datePath, "0"), []byte(blocks), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Hyperliquid.MainnetURL = "ws" + strings.TrimPrefix(info.URL, "http") + "/ws"
+	cfg.Proxy.EnableLocalNode = true
+	cfg.Proxy.LocalNodeDataPath = dataPath
+	if blocks == "" {
+		cfg.Proxy.DataSourceGraceSec = 0
+	} else {
+		cfg.Proxy.DataSourceGraceSec = 5
+	}
+
+	p := proxy.NewProxy(cfg)
+	if err := p.Start(); err != nil {
+		t.Fatalf("proxy failed to start: %v", err)
+	}
+	t.Cleanup(p.Stop)
+
+	srv := httptest.NewServer(server.NewServer(cfg, p).Handler())
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"hyperliquid-ws-proxy/config"
+)
+
+func TestAuthenticate(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Proxy.APIKeys = map[string]string{"desk": "secret"}
+	s := &Server{config: cfg}
+	
+	cases := []struct {
+		name     string
+		query    string
+		header   string
+		wantKey  string
+		wantAuth bool
+	}{
+		{"no credentials", "", "", "", false},
+		{"bearer header", "", "Bearer secret", "desk", true},
+		{"query token", "?token=secret", "", "desk", true},
+		{"wrong key", "?token=nope", "", "", false},
+		{"bearer header wins over query token", "?token=nope", "Bearer secret", "desk", true},
+		{"basic auth falls back to query token", "?token=secret", "Basic dXNlcjpwYXNz", "desk", true},
+		{"basic auth alone", "", "Basic dXNlcjpwYXNz", "", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/ws"+c.query, nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		key, ok := s.authenticate(r)
+		if key != c.wantKey || ok != c.wantAuth {
+			t.Errorf("%s: authenticate = (%q, %v), want (%q, %v)", c.name, key, ok, c.wantKey, c.wantAuth)
+		}
+	}
+}
+
+func TestAuthenticateWithoutKeys(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: cfg}
+	
+	if _, ok := s.authenticate(httptest.NewRequest("GET", "/ws", nil)); !ok {
+		t.Fatal("request refused with no api_keys configured")
+	}
+}
@@ -1,9 +1,15 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,6 +18,9 @@ import (
 	"hyperliquid-ws-proxy/proxy"
 )
 
+// validNamespace restricts client namespaces to short identifier-like names
+var validNamespace = regexp.MustCompile(`^[A-Za-z0-9_-]{0,64}$`)
+
 // Server represents the HTTP server
 type Server struct {
 	config *config.Config
@@ -29,6 +38,25 @@ func NewServer(cfg *config.Config, p *proxy.Proxy) *Server {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	s.server = &http.Server{
+		Addr:         s.config.GetServerAddress(),
+		Handler:      s.Handler(),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	
+	logrus.WithField("address", s.config.GetServerAddress()).Info("Starting HTTP server")
+	
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("server failed to start: %v", err)
+	}
+	
+	return nil
+}
+
+// Handler returns the server's HTTP handler with all endpoints registered
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	
 	// WebSocket endpoint (matches Hyperliquid's /ws path)
@@ -37,6 +65,9 @@ func (s *Server) Start() error {
 	// Health check endpoint
 	mux.HandleFunc("/health", s.handleHealth)
 	
+	// Readiness endpoint, /ws refuses clients until it reports ready
+	mux.HandleFunc("/ready", s.handleReady)
+	
 	// Statistics endpoint
 	mux.HandleFunc("/stats", s.handleStats)
 	
@@ -46,33 +77,44 @@ func (s *Server) Start() error {
 	// Assets endpoint
 	mux.HandleFunc("/assets", s.handleAssets)
 	
-	// CORS middleware for web clients
-	handler := s.corsMiddleware(mux)
-	
-	s.server = &http.Server{
-		Addr:         s.config.GetServerAddress(),
-		Handler:      handler,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
-	}
+	// Active subscriptions endpoint
+	mux.HandleFunc("/subscriptions", s.handleSubscriptions)
 	
-	logrus.WithField("address", s.config.GetServerAddress()).Info("Starting HTTP server")
+	// Prometheus metrics endpoint
+	mux.HandleFunc("/metrics", s.handleMetrics)
 	
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server failed to start: %v", err)
-	}
+	// Per-client traffic endpoint
+	mux.HandleFunc("/clients", s.handleClients)
 	
-	return nil
+	// CORS middleware for web clients
+	return s.corsMiddleware(mux)
 }
 
-// Stop stops the HTTP server
+// Stop gracefully stops the HTTP server, forcing close once the shutdown timeout elapses
 func (s *Server) Stop() error {
-	if s.server != nil {
-		logrus.Info("Stopping HTTP server")
-		return s.server.Close()
+	if s.server == nil {
+		return nil
 	}
-	return nil
+	
+	timeout := time.Duration(s.config.Server.ShutdownTimeoutSec) * time.Second
+	logrus.WithField("timeout", timeout).Info("Stopping HTTP server")
+	
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error":             err,
+			"connected_clients": s.proxy.GetHub().GetClientCount(),
+		}).Warn("Shutdown timeout reached, forcing close")
+		err = s.server.Close()
+	}
+	
+	// WebSocket connections are hijacked, so Shutdown doesn't wait for them:
+	// drain them with a close frame
+	s.proxy.GetHub().Shutdown()
+	return err
 }
 
 // handleWebSocket handles WebSocket connections
@@ -84,35 +126,152 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		"origin":      r.Header.Get("Origin"),
 	}).Info("New WebSocket connection")
 	
+	// Refuse clients until there is data to serve them
+	if !s.proxy.Ready() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Proxy not ready, no data received yet", http.StatusServiceUnavailable)
+		return
+	}
+	
+	// Require a known API key when keys are configured
+	keyName, ok := s.authenticate(r)
+	if !ok {
+		logrus.WithField("remote_addr", r.RemoteAddr).Warn("Rejected WebSocket connection without a valid API key")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="hyperliquid-ws-proxy"`)
+		http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+	
 	// Check client limits
 	if s.proxy.GetHub().GetClientCount() >= s.config.Proxy.MaxClients {
 		http.Error(w, "Too many clients connected", http.StatusTooManyRequests)
 		return
 	}
 	
+	opts := client.ConnectOptions{
+		Compression:      s.config.Proxy.EnableCompression,
+		CompressionLevel: s.config.Proxy.CompressionLevel,
+		AllowedOrigins:   s.config.Server.AllowedOrigins,
+		KeyName:          keyName,
+	}
+	if s.overflowEligible(keyName) {
+		opts.OverflowDir = s.config.Proxy.DiskOverflowDir
+		opts.OverflowMaxBytes = s.config.Proxy.DiskOverflowMaxBytes
+	}
+	switch symbols := r.URL.Query().Get("symbols"); symbols {
+	case "", proxy.SymbolFormatName:
+	case proxy.SymbolFormatRaw:
+		opts.SymbolFormat = proxy.SymbolFormatRaw
+		opts.Rewrite = s.proxy.RawSymbolFrame
+	default:
+		http.Error(w, "Invalid symbols format, use name or raw", http.StatusBadRequest)
+		return
+	}
+	if s.proxy.RedactsTradeUsers(keyName) {
+		if symbolRewrite := opts.Rewrite; symbolRewrite != nil {
+			opts.Rewrite = func(frame []byte) []byte {
+				return symbolRewrite(s.proxy.RedactTradeUsersFrame(frame))
+			}
+		} else {
+			opts.Rewrite = s.proxy.RedactTradeUsersFrame
+		}
+	}
+	if s.config.Proxy.EnableNamespaces {
+		opts.Namespace = r.URL.Query().Get("namespace")
+		if !validNamespace.MatchString(opts.Namespace) {
+			http.Error(w, "Invalid namespace", http.StatusBadRequest)
+			return
+		}
+	}
+	
 	// Upgrade to WebSocket
-	client.ServeWS(s.proxy.GetHub(), w, r)
+	client.ServeWS(s.proxy.GetHub(), w, r, opts)
+}
+
+// authenticate checks the API key presented as a Bearer Authorization header or
+// the token query parameter, returning the name of the matching key. Other
+// Authorization schemes, such as a reverse proxy's Basic auth, are ignored. Any
+// request is accepted when no keys are configured.
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	if len(s.config.Proxy.APIKeys) == 0 {
+		return "", true
+	}
+	
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	if token == "" {
+		return "", false
+	}
+	
+	for name, key := range s.config.Proxy.APIKeys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
 }
 
-// handleHealth handles health check requests
+// overflowEligible reports whether a client authenticated with the given key
+// name gets a disk overflow queue
+func (s *Server) overflowEligible(keyName string) bool {
+	if s.config.Proxy.DiskOverflowDir == "" {
+		return false
+	}
+	if len(s.config.Proxy.DiskOverflowKeys) == 0 {
+		return true
+	}
+	for _, name := range s.config.Proxy.DiskOverflowKeys {
+		if name == keyName {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHealth handles health check requests, returning 503 when unhealthy.
+// A degraded proxy still answers 200 so brief upstream reconnects don't fail it over.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
+	status := s.proxy.HealthStatus()
+	
 	health := map[string]interface{}{
-		"status":    "healthy",
+		"status":    status.Status,
 		"timestamp": time.Now().Unix(),
 		"uptime":    time.Since(s.proxy.GetStats().StartTime).Seconds(),
 		"version":   "1.0.0",
+		"details":   status,
 	}
 	
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(health)
 }
 
+// handleReady handles readiness requests, returning 503 until the proxy has data to serve
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	
+	ready := s.proxy.Ready()
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":     ready,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
 // handleStats handles statistics requests
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	
 	stats := s.proxy.GetStats()
+	runtimeStats := s.proxy.GetRuntimeStats()
+	bytesSent, bytesReceived := s.proxy.GetHub().BytesTotals()
 	
 	response := map[string]interface{}{
 		"connected_clients":      stats.ConnectedClients,
@@ -120,9 +279,31 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		"messages_processed":     stats.MessagesProcessed,
 		"messages_forwarded":     stats.MessagesForwarded,
 		"post_requests_handled":  stats.PostRequestsHandled,
+		"post_requests_in_flight": stats.PostRequestsInFlight,
+		"messages_suppressed":    stats.MessagesSuppressed,
+		"messages_dropped":       stats.MessagesDropped,
+		"messages_dropped_by_channel": stats.DroppedByChannel,
+		"retained_trades":        stats.RetainedTrades,
 		"last_activity":          stats.LastActivity.Unix(),
 		"start_time":             stats.StartTime.Unix(),
 		"uptime_seconds":         time.Since(stats.StartTime).Seconds(),
+		"goroutines":             runtimeStats.Goroutines,
+		"heap_alloc_bytes":       runtimeStats.HeapAlloc,
+		"heap_inuse_bytes":       runtimeStats.HeapInuse,
+		"files_monitored":        runtimeStats.MonitoredFiles,
+		"bytes_sent":             bytesSent,
+		"bytes_received":         bytesReceived,
+	}
+	
+	// Progress of the startup replay when one is configured
+	if runtimeStats.Replay != nil {
+		response["replay"] = runtimeStats.Replay
+	}
+	
+	// Counters of the current window when periodic resets are enabled
+	if s.config.Logging.StatsResetIntervalSec > 0 {
+		response["since_reset"] = stats.SinceReset
+		response["last_reset"] = stats.LastReset.Unix()
 	}
 	
 	json.NewEncoder(w).Encode(response)
@@ -142,13 +323,16 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 			"stats":       "/stats",
 			"info":        "/info",
 			"assets":      "/assets",
+			"subscriptions": "/subscriptions",
+			"metrics":     "/metrics",
+			"clients":     "/clients",
 		},
 		"supported_subscriptions": []string{
 			"allMids", "l2Book", "trades", "candle", "bbo",
 			"notification", "webData2", "orderUpdates", "userEvents",
 			"userFills", "userFundings", "userNonFundingLedgerUpdates",
 			"activeAssetCtx", "activeAssetData", "userTwapSliceFills",
-			"userTwapHistory",
+			"userTwapHistory", "midPx", "midsBbo",
 		},
 		"features": []string{
 			"Real-time WebSocket proxy",
@@ -189,6 +373,143 @@ func (s *Server) handleAssets(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleSubscriptions lists the active subscriptions and their client counts
+func (s *Server) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	
+	subscriptions := s.proxy.GetSubscriptionSnapshot()
+	
+	response := map[string]interface{}{
+		"count":         len(subscriptions),
+		"subscriptions": subscriptions,
+		"timestamp":     time.Now().Unix(),
+	}
+	
+	// Upstream references per key, to spot drift from the proxy's subscriptions
+	if refCounts, ok := s.proxy.UpstreamRefCounts(); ok {
+		response["upstream_ref_counts"] = refCounts
+	}
+	
+	json.NewEncoder(w).Encode(response)
+}
+
+// Clients listed by /clients unless ?limit= asks for another count, and the most it may ask for
+const (
+	defaultClientsLimit = 100
+	maxClientsLimit     = 1000
+)
+
+// clientTraffic describes one connected client for the /clients endpoint
+type clientTraffic struct {
+	ID            string `json:"id"`
+	KeyName       string `json:"key_name,omitempty"`
+	Subscriptions int    `json:"subscriptions"`
+	BytesSent     int64  `json:"bytes_sent"`
+	BytesReceived int64  `json:"bytes_received"`
+}
+
+// handleClients lists connected clients with their traffic, heaviest senders
+// first, along with the totals across every client served so far
+func (s *Server) handleClients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	
+	limit := defaultClientsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxClientsLimit)
+	}
+	
+	hub := s.proxy.GetHub()
+	clients := hub.GetClients()
+	traffic := make([]clientTraffic, 0, len(clients))
+	for _, c := range clients {
+		traffic = append(traffic, clientTraffic{
+			ID:            c.ID,
+			KeyName:       c.KeyName,
+			Subscriptions: len(c.GetSubscriptions()),
+			BytesSent:     c.BytesSent(),
+			BytesReceived: c.BytesReceived(),
+		})
+	}
+	sort.Slice(traffic, func(i, j int) bool {
+		return traffic[i].BytesSent > traffic[j].BytesSent
+	})
+	if len(traffic) > limit {
+		traffic = traffic[:limit]
+	}
+	
+	bytesSent, bytesReceived := hub.BytesTotals()
+	response := map[string]interface{}{
+		"count":          len(clients),
+		"clients":        traffic,
+		"bytes_sent":     bytesSent,
+		"bytes_received": bytesReceived,
+		"timestamp":      time.Now().Unix(),
+	}
+	
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleMetrics exposes the proxy statistics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	
+	stats := s.proxy.GetStats()
+	
+	var b strings.Builder
+	writeMetric(&b, "connected_clients", "gauge", "Number of connected WebSocket clients.", float64(stats.ConnectedClients))
+	writeMetric(&b, "active_subscriptions", "gauge", "Number of distinct active subscriptions.", float64(stats.ActiveSubscriptions))
+	writeMetric(&b, "messages_processed_total", "counter", "Messages received from the data source.", float64(stats.MessagesProcessed))
+	writeMetric(&b, "messages_forwarded_total", "counter", "Messages delivered to clients.", float64(stats.MessagesForwarded))
+	writeMetric(&b, "messages_suppressed_total", "counter", "Unchanged or throttled messages not sent to clients.", float64(stats.MessagesSuppressed))
+	writeMetric(&b, "messages_dropped_total", "counter", "Messages dropped because a client could not take them.", float64(stats.MessagesDropped))
+	writeMetric(&b, "post_requests_handled_total", "counter", "POST requests handled for clients.", float64(stats.PostRequestsHandled))
+	writeMetric(&b, "post_requests_in_flight", "gauge", "POST requests currently waiting on a response.", float64(stats.PostRequestsInFlight))
+	
+	bytesSent, bytesReceived := s.proxy.GetHub().BytesTotals()
+	writeMetric(&b, "client_bytes_sent_total", "counter", "Payload bytes written to clients, before compression.", float64(bytesSent))
+	writeMetric(&b, "client_bytes_received_total", "counter", "Payload bytes read from clients.", float64(bytesReceived))
+	
+	// Only remote API mode has an upstream connection
+	if connected, ok := s.proxy.UpstreamConnected(); ok {
+		value := 0.0
+		if connected {
+			value = 1
+		}
+		writeMetric(&b, "upstream_connected", "gauge", "Whether the Hyperliquid WebSocket connection is up (1) or down (0).", value)
+	}
+	
+	// Clients per subscription type
+	clientsByType := make(map[string]int)
+	for _, sub := range s.proxy.GetSubscriptionSnapshot() {
+		clientsByType[sub.Type] += sub.ClientCount
+	}
+	subTypes := make([]string, 0, len(clientsByType))
+	for subType := range clientsByType {
+		subTypes = append(subTypes, subType)
+	}
+	sort.Strings(subTypes)
+	
+	fmt.Fprintln(&b, "# HELP subscription_clients Number of client subscriptions per subscription type.")
+	fmt.Fprintln(&b, "# TYPE subscription_clients gauge")
+	for _, subType := range subTypes {
+		fmt.Fprintf(&b, "subscription_clients{type=%q} %d\n", subType, clientsByType[subType])
+	}
+	
+	w.Write([]byte(b.String()))
+}
+
+// writeMetric writes a single unlabelled metric with its HELP and TYPE lines
+func writeMetric(b *strings.Builder, name, metricType, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+	fmt.Fprintf(b, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
 // corsMiddleware adds CORS headers
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
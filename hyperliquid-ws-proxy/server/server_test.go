@@ -0,0 +1,122 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"hyperliquid-ws-proxy/config"
+	"hyperliquid-ws-proxy/proxy"
+)
+
+func TestWebSocketRefusedUntilReady(t *testing.T) {
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "spotMeta") {
+			w.Write([]byte(`{"universe":[],"tokens":[]}`))
+			return
+		}
+		w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5}]}`))
+	}))
+	defer info.Close()
+	
+	dataPath := t.TempDir()
+	datePath := filepath.Join(dataPath, "replica_cmds", "2025-01-01T00:00:00Z", "20250101")
+	if err := os.MkdirAll(datePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Hyperliquid.MainnetURL = "ws" + strings.TrimPrefix(info.URL, "http") + "/ws"
+	cfg.Proxy.EnableLocalNode = true
+	cfg.Proxy.LocalNodeDataPath = dataPath
+	cfg.Proxy.DataSourceGraceSec = 0
+	
+	p := proxy.NewProxy(cfg)
+	if err := p.Start(); err != nil {
+		t.Fatalf("proxy failed to start: %v", err)
+	}
+	defer p.Stop()
+	
+	srv := httptest.NewServer(NewServer(cfg, p).Handler())
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	
+	// No block was read yet
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("connection accepted before the proxy was ready")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("early connection got %v, want 503", resp)
+	}
+	if retry := resp.Header.Get("Retry-After"); retry == "" {
+		t.Fatal("503 response carries no Retry-After header")
+	}
+	
+	// The first block makes the proxy ready
+	block := `{"abci_block":{"time":"2025-01-01T00:00:00.000","round":1,"signed_action_bundles":[]}}` + "\n"
+	if err := os.WriteFile(filepath.Join(datePath, "0"), []byte(block), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, resp, err = websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("connection failed with %v: %v", resp, err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connections still refused after the first block was read")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestStopForcesCloseAfterShutdownTimeout(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Proxy.EnableLocalNode = true
+	cfg.Proxy.LocalNodeDataPath = t.TempDir()
+	cfg.Server.ShutdownTimeoutSec = 1
+	s := NewServer(cfg, proxy.NewProxy(cfg))
+	
+	// A request still in flight keeps Shutdown waiting
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	s.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(listener)
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+	
+	begin := time.Now()
+	s.Stop()
+	if elapsed := time.Since(begin); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Fatalf("Stop returned after %s, want the 1s shutdown timeout", elapsed)
+	}
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"errors"
+)
+
+// Error codes surfaced to clients in error frames
+const (
+	ErrCodeInvalidRequest = "invalid_request"
+	ErrCodeUnsupported    = "unsupported"
+	ErrCodeNotConnected   = "not_connected"
+	ErrCodeTimeout        = "timeout"
+	ErrCodeBusy           = "busy"
+	ErrCodeUpstream       = "upstream_error"
+	ErrCodeInternal       = "internal_error"
+	ErrCodeLimitExceeded  = "limit_exceeded"
+)
+
+// ProxyError is a structured error that flows from the connector and
+// local node reader up to the client-facing error frames
+type ProxyError struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Retryable bool                   `json:"retryable"`
+	Details   map[string]interface{} `json:"details,omitempty"` // extra context for the client, e.g. limits
+}
+
+// NewProxyError creates a new ProxyError
+func NewProxyError(code, message string, retryable bool) *ProxyError {
+	return &ProxyError{
+		Code:      code,
+		Message:   message,
+		Retryable: retryable,
+	}
+}
+
+// Error implements the error interface
+func (e *ProxyError) Error() string {
+	return e.Message
+}
+
+// AsProxyError returns err as a ProxyError, wrapping plain errors as
+// non-retryable internal errors
+func AsProxyError(err error) *ProxyError {
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return proxyErr
+	}
+	return NewProxyError(ErrCodeInternal, err.Error(), false)
+}
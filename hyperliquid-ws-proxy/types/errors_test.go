@@ -0,0 +1,22 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAsProxyErrorKeepsCodeThroughWrapping(t *testing.T) {
+	err := fmt.Errorf("posting: %w", NewProxyError(ErrCodeTimeout, "request timeout", true))
+	proxyErr := AsProxyError(err)
+	if proxyErr.Code != ErrCodeTimeout || !proxyErr.Retryable || proxyErr.Message != "request timeout" {
+		t.Fatalf("AsProxyError(wrapped timeout) = %+v", proxyErr)
+	}
+}
+
+func TestAsProxyErrorWrapsPlainErrors(t *testing.T) {
+	proxyErr := AsProxyError(errors.New("boom"))
+	if proxyErr.Code != ErrCodeInternal || proxyErr.Retryable || proxyErr.Message != "boom" {
+		t.Fatalf("AsProxyError(plain error) = %+v, want a non-retryable internal error", proxyErr)
+	}
+}
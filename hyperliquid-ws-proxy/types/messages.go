@@ -2,27 +2,171 @@ package types
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 // Base message structures
 type WSMessage struct {
-	Method       string                 `json:"method,omitempty"`
-	Subscription *SubscriptionRequest   `json:"subscription,omitempty"`
-	Channel      string                 `json:"channel,omitempty"`
-	Data         json.RawMessage        `json:"data,omitempty"`
-	ID           *int64                 `json:"id,omitempty"`
-	Request      *PostRequest           `json:"request,omitempty"`
+	Method         string               `json:"method,omitempty"`
+	Subscription   *SubscriptionRequest `json:"subscription,omitempty"`
+	Channel        string               `json:"channel,omitempty"`
+	Data           json.RawMessage      `json:"data,omitempty"`
+	ID             *int64               `json:"id,omitempty"`
+	Request        *PostRequest         `json:"request,omitempty"`
+	SubscriptionID *int64               `json:"subscriptionId,omitempty"` // unsubscribe by the id returned on subscribe
 }
 
 type SubscriptionRequest struct {
-	Type            string  `json:"type"`
-	User            string  `json:"user,omitempty"`
-	Coin            string  `json:"coin,omitempty"`
-	Interval        string  `json:"interval,omitempty"`
-	Dex             string  `json:"dex,omitempty"`
-	NSigFigs        *int    `json:"nSigFigs,omitempty"`
-	Mantissa        *int    `json:"mantissa,omitempty"`
-	AggregateByTime *bool   `json:"aggregateByTime,omitempty"`
+	Type            string   `json:"type"`
+	User            string   `json:"user,omitempty"`
+	Coin            string   `json:"coin,omitempty"`
+	Coins           []string `json:"coins,omitempty"` // several coins in one request, see Expand
+	Interval        string   `json:"interval,omitempty"`
+	Dex             string   `json:"dex,omitempty"`
+	NSigFigs        *int     `json:"nSigFigs,omitempty"`
+	Mantissa        *int     `json:"mantissa,omitempty"`
+	AggregateByTime *bool    `json:"aggregateByTime,omitempty"`
+	MinSz           *string  `json:"minSz,omitempty"`
+	Depth           *int     `json:"depth,omitempty"`
+	Market          string   `json:"market,omitempty"` // "perp", "spot" or "all", narrows the trades firehose (coin "*")
+
+	// CompressSnapshot asks for the initial l2Book snapshot as a gzip-compressed
+	// binary frame. It only concerns the requesting client, so it is not part of
+	// Key and is never sent to Hyperliquid.
+	CompressSnapshot bool `json:"compressSnapshot,omitempty"`
+}
+
+// Key returns the canonical key identifying a subscription. Every field that
+// changes the data delivered is part of the key, so two requests share a key
+// only if they are interchangeable. Coins is not part of it: a request listing
+// several coins is keyed per coin after Expand. Fields are labelled and their
+// values escaped, as in "l2Book?coin=BTC&nSigFigs=5", so values can't be
+// mistaken for one another.
+func (s *SubscriptionRequest) Key() string {
+	fields := url.Values{}
+	if s.User != "" {
+		fields.Set("user", s.User)
+	}
+	if s.Coin != "" {
+		fields.Set("coin", s.Coin)
+	}
+	if s.Interval != "" {
+		fields.Set("interval", s.Interval)
+	}
+	if s.Dex != "" {
+		fields.Set("dex", s.Dex)
+	}
+	if s.NSigFigs != nil {
+		fields.Set("nSigFigs", strconv.Itoa(*s.NSigFigs))
+	}
+	if s.Mantissa != nil {
+		fields.Set("mantissa", strconv.Itoa(*s.Mantissa))
+	}
+	if s.AggregateByTime != nil {
+		fields.Set("aggregateByTime", strconv.FormatBool(*s.AggregateByTime))
+	}
+	if s.MinSz != nil {
+		fields.Set("minSz", *s.MinSz)
+	}
+	if s.Depth != nil {
+		fields.Set("depth", strconv.Itoa(*s.Depth))
+	}
+	if s.Market != "" && s.Market != "all" {
+		fields.Set("market", s.Market)
+	}
+
+	key := url.PathEscape(s.Type)
+	if len(fields) > 0 {
+		key += "?" + fields.Encode()
+	}
+	return key
+}
+
+// SubscriptionRequiredFields lists the fields each subscription type must set.
+// Types not listed here, such as allMids, need none.
+var SubscriptionRequiredFields = map[string][]string{
+	"l2Book":                      {"coin"},
+	"trades":                      {"coin"},
+	"candle":                      {"coin", "interval"},
+	"bbo":                         {"coin"},
+	"activeAssetCtx":              {"coin"},
+	"activeAssetData":             {"user", "coin"},
+	"midPx":                       {"coin"},
+	"notification":                {"user"},
+	"webData2":                    {"user"},
+	"orderUpdates":                {"user"},
+	"userEvents":                  {"user"},
+	"userFills":                   {"user"},
+	"userFundings":                {"user"},
+	"userNonFundingLedgerUpdates": {"user"},
+	"userTwapSliceFills":          {"user"},
+	"userTwapHistory":             {"user"},
+}
+
+// MissingFields returns the required fields of the subscription's type that are
+// empty, in the order SubscriptionRequiredFields lists them
+func (s *SubscriptionRequest) MissingFields() []string {
+	var missing []string
+	for _, field := range SubscriptionRequiredFields[s.Type] {
+		var value string
+		switch field {
+		case "coin":
+			value = s.Coin
+			if value == "" && len(s.Coins) > 0 {
+				value = s.Coins[0]
+			}
+		case "user":
+			value = s.User
+		case "interval":
+			value = s.Interval
+		}
+		if value == "" {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// TakesCoin reports whether subscriptions of the given type are per coin
+func TakesCoin(subscriptionType string) bool {
+	for _, field := range SubscriptionRequiredFields[subscriptionType] {
+		if field == "coin" {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns one subscription per coin of a request listing several in
+// Coins, its singular Coin first if set, skipping repeats. A request without
+// Coins is returned as is.
+func (s *SubscriptionRequest) Expand() []*SubscriptionRequest {
+	if len(s.Coins) == 0 {
+		return []*SubscriptionRequest{s}
+	}
+
+	coins := s.Coins
+	if s.Coin != "" {
+		coins = append([]string{s.Coin}, coins...)
+	}
+
+	seen := make(map[string]bool, len(coins))
+	subs := make([]*SubscriptionRequest, 0, len(coins))
+	for _, coin := range coins {
+		if coin == "" || seen[coin] {
+			continue
+		}
+		seen[coin] = true
+
+		single := *s
+		single.Coin = coin
+		single.Coins = nil
+		subs = append(subs, &single)
+	}
+	return subs
 }
 
 type PostRequest struct {
@@ -38,6 +182,10 @@ type PostResponse struct {
 type PostResponseInner struct {
 	Type    string          `json:"type"` // "info", "action", or "error"
 	Payload json.RawMessage `json:"payload"`
+	
+	// Set by the proxy on error responses it generates
+	Code      string `json:"code,omitempty"`
+	Retryable *bool  `json:"retryable,omitempty"`
 }
 
 // Subscription types enum
@@ -60,13 +208,89 @@ const (
 	ActiveAssetData             SubscriptionType = "activeAssetData"
 	UserTwapSliceFills          SubscriptionType = "userTwapSliceFills"
 	UserTwapHistory             SubscriptionType = "userTwapHistory"
+	
+	// Proxy-only channel streaming a single coin's mid price
+	MidPxType SubscriptionType = "midPx"
+	
+	// Proxy-only channel streaming every coin's mid with its best bid and ask
+	MidsBboType SubscriptionType = "midsBbo"
 )
 
+// knownSubscriptionTypes are the subscription types clients may request
+var knownSubscriptionTypes = map[SubscriptionType]bool{
+	AllMidsType:                 true,
+	L2BookType:                  true,
+	TradesType:                  true,
+	CandleType:                  true,
+	BBOType:                     true,
+	NotificationType:            true,
+	WebData2Type:                true,
+	OrderUpdates:                true,
+	UserEvents:                  true,
+	UserFills:                   true,
+	UserFundings:                true,
+	UserNonFundingLedgerUpdates: true,
+	ActiveAssetCtx:              true,
+	ActiveAssetData:             true,
+	UserTwapSliceFills:          true,
+	UserTwapHistory:             true,
+	MidPxType:                   true,
+	MidsBboType:                 true,
+}
+
+// ValidateSubscription checks that a subscribe request names a known type and
+// sets the fields SubscriptionRequiredFields lists for it. The error is a
+// *ProxyError with the invalid_request code; a missing field error lists the
+// fields in its missing_fields detail.
+func ValidateSubscription(sub *SubscriptionRequest) error {
+	if sub == nil {
+		return NewProxyError(ErrCodeInvalidRequest, "Missing subscription details", false)
+	}
+	if sub.Type == "" {
+		return NewProxyError(ErrCodeInvalidRequest, "subscription requires a type", false)
+	}
+	if !knownSubscriptionTypes[SubscriptionType(sub.Type)] {
+		proxyErr := NewProxyError(ErrCodeInvalidRequest, fmt.Sprintf("unknown subscription type %q", sub.Type), false)
+		proxyErr.Details = map[string]interface{}{
+			"type": sub.Type,
+		}
+		return proxyErr
+	}
+
+	// e.g. activeAssetData without a user
+	if missing := sub.MissingFields(); len(missing) > 0 {
+		proxyErr := NewProxyError(ErrCodeInvalidRequest, fmt.Sprintf("%s subscription requires %s", sub.Type, strings.Join(missing, " and ")), false)
+		proxyErr.Details = map[string]interface{}{
+			"missing_fields": missing,
+		}
+		return proxyErr
+	}
+	return nil
+}
+
 // Response data structures
 type AllMids struct {
 	Mids map[string]string `json:"mids"`
 }
 
+// WsMidPx carries the mid price of a single coin
+type WsMidPx struct {
+	Coin string `json:"coin"`
+	Mid  string `json:"mid"`
+}
+
+// WsMidBbo carries a coin's mid price and top of book; sides absent from the book are omitted
+type WsMidBbo struct {
+	Mid string `json:"mid,omitempty"`
+	Bid string `json:"bid,omitempty"`
+	Ask string `json:"ask,omitempty"`
+}
+
+// MidsBbo maps coins to their mid and top of book. Updates carry only the coins that changed.
+type MidsBbo struct {
+	Mids map[string]WsMidBbo `json:"mids"`
+}
+
 type WsTrade struct {
 	Coin  string    `json:"coin"`
 	Side  string    `json:"side"`
@@ -0,0 +1,191 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSubscriptionKeyDistinguishesEveryField(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+	strPtr := func(v string) *string { return &v }
+
+	base := SubscriptionRequest{Type: "l2Book", Coin: "BTC"}
+	variants := map[string]func(s *SubscriptionRequest){
+		"type":            func(s *SubscriptionRequest) { s.Type = "bbo" },
+		"user":            func(s *SubscriptionRequest) { s.User = "0xabc" },
+		"coin":            func(s *SubscriptionRequest) { s.Coin = "ETH" },
+		"interval":        func(s *SubscriptionRequest) { s.Interval = "1m" },
+		"dex":             func(s *SubscriptionRequest) { s.Dex = "xyz" },
+		"nSigFigs":        func(s *SubscriptionRequest) { s.NSigFigs = intPtr(5) },
+		"mantissa":        func(s *SubscriptionRequest) { s.Mantissa = intPtr(2) },
+		"aggregateByTime": func(s *SubscriptionRequest) { s.AggregateByTime = boolPtr(true) },
+		"minSz":           func(s *SubscriptionRequest) { s.MinSz = strPtr("10") },
+		"depth":           func(s *SubscriptionRequest) { s.Depth = intPtr(20) },
+		"market":          func(s *SubscriptionRequest) { s.Market = "spot" },
+	}
+
+	seen := map[string]string{base.Key(): "base"}
+	for field, apply := range variants {
+		sub := base
+		apply(&sub)
+		key := sub.Key()
+		if other, exists := seen[key]; exists {
+			t.Errorf("changing %s gives key %q, same as %s", field, key, other)
+		}
+		seen[key] = field
+	}
+}
+
+func TestSubscriptionKeyLabelsFields(t *testing.T) {
+	collisions := []struct {
+		name string
+		a, b SubscriptionRequest
+	}{
+		{
+			"dex against interval",
+			SubscriptionRequest{Type: "trades", Coin: "BTC", Dex: "abc"},
+			SubscriptionRequest{Type: "trades", Coin: "BTC", Interval: "abc"},
+		},
+		{
+			"user and coin against a coin holding both",
+			SubscriptionRequest{Type: "trades", User: "X", Coin: "BTC"},
+			SubscriptionRequest{Type: "trades", Coin: "X-BTC"},
+		},
+		{
+			"value holding a separator",
+			SubscriptionRequest{Type: "candle", Coin: "BTC", Interval: "1m"},
+			SubscriptionRequest{Type: "candle", Coin: "BTC&interval=1m"},
+		},
+	}
+	for _, tc := range collisions {
+		if a, b := tc.a.Key(), tc.b.Key(); a == b {
+			t.Errorf("%s: both requests give key %q", tc.name, a)
+		}
+	}
+}
+
+func TestSubscriptionKeyIgnoresDeliveryOptions(t *testing.T) {
+	base := SubscriptionRequest{Type: "l2Book", Coin: "BTC"}
+
+	same := []SubscriptionRequest{
+		{Type: "l2Book", Coin: "BTC", CompressSnapshot: true},
+		{Type: "l2Book", Coin: "BTC", Coins: []string{"ETH"}},
+		{Type: "l2Book", Coin: "BTC", Market: "all"},
+	}
+	for _, sub := range same {
+		if sub.Key() != base.Key() {
+			t.Errorf("key %q, want %q for an interchangeable request", sub.Key(), base.Key())
+		}
+	}
+
+	if key := (&SubscriptionRequest{Type: "allMids"}).Key(); key != "allMids" {
+		t.Errorf("allMids key = %q, want the bare type", key)
+	}
+}
+
+func TestMissingFieldsForEveryCombination(t *testing.T) {
+	setters := map[string]func(s *SubscriptionRequest){
+		"coin":     func(s *SubscriptionRequest) { s.Coin = "BTC" },
+		"user":     func(s *SubscriptionRequest) { s.User = "0xabc" },
+		"interval": func(s *SubscriptionRequest) { s.Interval = "1m" },
+	}
+
+	for subType, required := range SubscriptionRequiredFields {
+		// Every subset of the required fields is set once, the rest must be reported
+		for mask := 0; mask < 1<<len(required); mask++ {
+			sub := SubscriptionRequest{Type: subType}
+			var want []string
+			for i, field := range required {
+				if mask&(1<<i) != 0 {
+					setters[field](&sub)
+				} else {
+					want = append(want, field)
+				}
+			}
+
+			missing := sub.MissingFields()
+			if strings.Join(missing, ",") != strings.Join(want, ",") {
+				t.Errorf("%s with %+v: missing %v, want %v", subType, sub, missing, want)
+			}
+		}
+	}
+}
+
+func TestMissingFieldsAcceptsCoinsList(t *testing.T) {
+	sub := SubscriptionRequest{Type: "candle", Coins: []string{"BTC", "ETH"}, Interval: "1m"}
+	if missing := sub.MissingFields(); len(missing) != 0 {
+		t.Fatalf("candle with coins missing %v", missing)
+	}
+
+	sub = SubscriptionRequest{Type: "allMids"}
+	if missing := sub.MissingFields(); len(missing) != 0 {
+		t.Fatalf("allMids missing %v, want no required fields", missing)
+	}
+}
+
+func TestValidateSubscriptionPerType(t *testing.T) {
+	complete := func(subscriptionType string) SubscriptionRequest {
+		return SubscriptionRequest{Type: subscriptionType, Coin: "BTC", User: "0xabc", Interval: "1m"}
+	}
+
+	for subscriptionType := range knownSubscriptionTypes {
+		t.Run(string(subscriptionType), func(t *testing.T) {
+			sub := complete(string(subscriptionType))
+			if err := ValidateSubscription(&sub); err != nil {
+				t.Fatalf("complete request rejected: %v", err)
+			}
+
+			for _, field := range SubscriptionRequiredFields[string(subscriptionType)] {
+				sub := complete(string(subscriptionType))
+				switch field {
+				case "coin":
+					sub.Coin = ""
+				case "user":
+					sub.User = ""
+				case "interval":
+					sub.Interval = ""
+				}
+
+				var proxyErr *ProxyError
+				if err := ValidateSubscription(&sub); !errors.As(err, &proxyErr) || proxyErr.Code != ErrCodeInvalidRequest {
+					t.Fatalf("request without %s gave %v, want an invalid_request error", field, err)
+				}
+				if missing, _ := proxyErr.Details["missing_fields"].([]string); len(missing) != 1 || missing[0] != field {
+					t.Fatalf("request without %s reports missing fields %v", field, proxyErr.Details["missing_fields"])
+				}
+				if !strings.Contains(proxyErr.Message, field) {
+					t.Fatalf("error %q doesn't name the missing %s", proxyErr.Message, field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSubscriptionRejectsUnknownOrMissingType(t *testing.T) {
+	cases := []struct {
+		name string
+		sub  *SubscriptionRequest
+		want string
+	}{
+		{"nil", nil, "Missing subscription details"},
+		{"no type", &SubscriptionRequest{Coin: "BTC"}, "subscription requires a type"},
+		{"unknown type", &SubscriptionRequest{Type: "l3Book", Coin: "BTC"}, `unknown subscription type "l3Book"`},
+	}
+
+	for _, tc := range cases {
+		var proxyErr *ProxyError
+		if err := ValidateSubscription(tc.sub); !errors.As(err, &proxyErr) || proxyErr.Code != ErrCodeInvalidRequest || proxyErr.Message != tc.want {
+			t.Errorf("%s: got %v, want invalid_request %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestRequiredFieldsOnlyForKnownTypes(t *testing.T) {
+	for subscriptionType := range SubscriptionRequiredFields {
+		if !knownSubscriptionTypes[SubscriptionType(subscriptionType)] {
+			t.Errorf("required fields listed for %s, which is not a known subscription type", subscriptionType)
+		}
+	}
+}
@@ -10,18 +10,21 @@ import (
 // Configuration de l'application
 type Config struct {
 	Server struct {
-		Host string `yaml:"host"`
-		Port int    `yaml:"port"`
+		Host           string   `yaml:"host"`
+		Port           int      `yaml:"port"`
+		AllowedOrigins []string `yaml:"allowed_origins"` // origines autorisées, vide = toutes
 	} `yaml:"server"`
 
 	Node struct {
 		DataPath string `yaml:"data_path"`
+		InfoURL  string `yaml:"info_url"` // API info pour les métadonnées des assets
 	} `yaml:"node"`
 
 	Proxy struct {
 		MaxClients        int `yaml:"max_clients"`
 		HeartbeatInterval int `yaml:"heartbeat_interval"`
 		MessageBufferSize int `yaml:"message_buffer_size"`
+		MaxTradesPerCoin  int `yaml:"max_trades_per_coin"` // trades conservés par asset, 0 = illimité
 	} `yaml:"proxy"`
 
 	Logging struct {
@@ -38,9 +41,11 @@ func LoadConfig(configPath string) (*Config, error) {
 	config.Server.Host = "0.0.0.0"
 	config.Server.Port = 8080
 	config.Node.DataPath = "/var/lib/docker/volumes/node_hl-data-mainnet/_data"
+	config.Node.InfoURL = "https://api.hyperliquid.xyz/info"
 	config.Proxy.MaxClients = 1000
 	config.Proxy.HeartbeatInterval = 30
 	config.Proxy.MessageBufferSize = 1024
+	config.Proxy.MaxTradesPerCoin = 100
 	config.Logging.Level = "info"
 	config.Logging.Format = "text"
 
@@ -80,9 +85,17 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("chemin des données du nœud non spécifié")
 	}
 
+	if c.Node.InfoURL == "" {
+		return fmt.Errorf("URL de l'API info non spécifiée")
+	}
+
 	if c.Proxy.MaxClients <= 0 {
 		return fmt.Errorf("nombre maximum de clients invalide: %d", c.Proxy.MaxClients)
 	}
 
+	if c.Proxy.MaxTradesPerCoin < 0 {
+		return fmt.Errorf("nombre de trades par asset invalide: %d (0 = illimité)", c.Proxy.MaxTradesPerCoin)
+	}
+
 	return nil
 } 
\ No newline at end of file
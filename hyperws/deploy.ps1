@@ -134,7 +134,7 @@ function Build-DockerImage {
     Write-Host "Construction de l'image Docker..." -ForegroundColor Blue
     
     try {
-        docker build -t hyperws:latest .
+        docker build -t hyperws:latest -f Dockerfile ..
         Write-Host "✓ Image Docker construite: hyperws:latest" -ForegroundColor Green
     } catch {
         Write-Host "✗ Erreur de construction Docker: $_" -ForegroundColor Red
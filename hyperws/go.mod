@@ -6,9 +6,15 @@ require (
 	github.com/gorilla/websocket v1.5.1
 	github.com/sirupsen/logrus v1.9.3
 	gopkg.in/yaml.v3 v3.0.1
+	hyperliquid-ws-proxy v0.0.0
 )
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
-) 
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+// Lecteur du nœud partagé avec le proxy
+replace hyperliquid-ws-proxy => ../hyperliquid-ws-proxy
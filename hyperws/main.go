@@ -5,8 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"runtime"
 	"strings"
 	"sync"
 	"syscall"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+	"hyperliquid-ws-proxy/proxy"
 )
 
 const (
@@ -52,10 +55,13 @@ type Hub struct {
 
 // HyperWS - Serveur principal
 type HyperWS struct {
-	config     *Config
-	hub        *Hub
-	nodeReader *LocalNodeReader
-	server     *http.Server
+	config       *Config
+	hub          *Hub
+	assetFetcher *proxy.AssetFetcher
+	nodeReader   *proxy.LocalNodeReader
+	updated      chan struct{} // signalé par le lecteur quand ses données changent
+	server       *http.Server
+	startTime    time.Time
 }
 
 // NewClient crée un nouveau client
@@ -92,38 +98,62 @@ func (h *Hub) Run() {
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				
-				// Supprimer le client de toutes les souscriptions
-				for key, clients := range h.subscriptions {
-					if clients[client] {
-						delete(clients, client)
-						if len(clients) == 0 {
-							delete(h.subscriptions, key)
-						}
-					}
-				}
-			}
+			h.removeClient(client)
 			h.mu.Unlock()
 			logrus.WithField("client_id", client.ID).Info("Client déconnecté")
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			for client := range h.clients {
 				select {
 				case client.send <- message:
 				default:
-					close(client.send)
-					delete(h.clients, client)
+					h.removeClient(client)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
+// removeClient retire un client du hub et de toutes ses souscriptions.
+// L'appelant doit détenir h.mu.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	close(client.send)
+
+	// Supprimer le client de toutes les souscriptions
+	for key, clients := range h.subscriptions {
+		if clients[client] {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(h.subscriptions, key)
+			}
+		}
+	}
+}
+
+// sendTo envoie un message à un client sans bloquer. Le verrou garantit que
+// removeClient n'a pas fermé client.send entre-temps ; retourne false si le
+// client a quitté le hub ou si son buffer est plein.
+func (h *Hub) sendTo(client *Client, data []byte) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.clients[client] {
+		return false
+	}
+	select {
+	case client.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
 // GetClientCount retourne le nombre de clients connectés
 func (h *Hub) GetClientCount() int {
 	h.mu.RLock()
@@ -296,7 +326,7 @@ func (c *Client) handleUnsubscribe(sub *SubscriptionRequest) {
 func (c *Client) sendInitialData(sub *SubscriptionRequest) {
 	switch sub.Type {
 	case AllMidsType:
-		prices := hyperWS.nodeReader.GetAllPrices()
+		prices := hyperWS.nodeReader.GetAllLatestPrices()
 		if len(prices) > 0 {
 			allMids := AllMids{Mids: prices}
 			data, _ := json.Marshal(allMids)
@@ -309,7 +339,7 @@ func (c *Client) sendInitialData(sub *SubscriptionRequest) {
 
 	case TradesType:
 		if sub.Coin != "" {
-			trades := hyperWS.nodeReader.GetLatestTrades(sub.Coin, 5)
+			trades := hyperWS.nodeReader.GetRealTrades(sub.Coin, 5)
 			for _, trade := range trades {
 				data, _ := json.Marshal(trade)
 				msg := WSMessage{
@@ -375,11 +405,28 @@ var hyperWS *HyperWS
 
 // NewHyperWS crée une nouvelle instance du serveur
 func NewHyperWS(config *Config) *HyperWS {
-	return &HyperWS{
-		config:     config,
-		hub:        NewHub(),
-		nodeReader: NewLocalNodeReader(config.Node.DataPath),
+	// Lecteur partagé avec hyperliquid-ws-proxy, les noms d'assets viennent de l'API info
+	assetFetcher := proxy.NewAssetFetcher(config.Node.InfoURL)
+	nodeReader := proxy.NewLocalNodeReader(config.Node.DataPath, assetFetcher, proxy.LocalNodeOptions{
+		MaxTradesPerCoin: config.Proxy.MaxTradesPerCoin,
+	})
+
+	hw := &HyperWS{
+		config:       config,
+		hub:          NewHub(),
+		assetFetcher: assetFetcher,
+		nodeReader:   nodeReader,
+		updated:      make(chan struct{}, 1),
+		startTime:    time.Now(),
 	}
+	nodeReader.SetOnUpdate(func() {
+		select {
+		case hw.updated <- struct{}{}:
+		default:
+			// Déjà signalé depuis le dernier tick
+		}
+	})
+	return hw
 }
 
 // Start démarre le serveur
@@ -387,10 +434,11 @@ func (hw *HyperWS) Start() error {
 	// Démarrer le hub
 	go hw.hub.Run()
 
-	// Démarrer le lecteur de nœud
-	if err := hw.nodeReader.Start(); err != nil {
-		return fmt.Errorf("erreur démarrage lecteur nœud: %v", err)
+	// Charger les métadonnées des assets puis démarrer le lecteur de nœud
+	if err := hw.assetFetcher.Start(); err != nil {
+		return fmt.Errorf("erreur chargement métadonnées assets: %v", err)
 	}
+	hw.nodeReader.Start()
 
 	// Démarrer la génération de données périodique
 	go hw.generatePeriodicData()
@@ -412,7 +460,15 @@ func (hw *HyperWS) Start() error {
 
 // handleWebSocket traite les connexions WebSocket
 func (hw *HyperWS) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	u := upgrader
+	if origins := hw.config.Server.AllowedOrigins; len(origins) > 0 {
+		u.CheckOrigin = func(r *http.Request) bool {
+			return originAllowed(r.Header.Get("Origin"), origins)
+		}
+	}
+
+	// Une origine refusée reçoit un 403 de l'upgrader
+	conn, err := u.Upgrade(w, r, nil)
 	if err != nil {
 		logrus.WithError(err).Error("Erreur upgrade WebSocket")
 		return
@@ -425,6 +481,35 @@ func (hw *HyperWS) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// originAllowed vérifie l'en-tête Origin contre la liste autorisée : origine ou
+// hôte exact, ou tout sous-domaine avec "*.domaine". Sans en-tête Origin (client
+// hors navigateur), la connexion est acceptée.
+func originAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+			continue
+		}
+		if entry == strings.ToLower(origin) || entry == host {
+			return true
+		}
+	}
+	return false
+}
+
 // handleHealth endpoint de santé
 func (hw *HyperWS) handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
@@ -446,19 +531,45 @@ func (hw *HyperWS) handleStats(w http.ResponseWriter, r *http.Request) {
 		"server": map[string]interface{}{
 			"name":    appName,
 			"version": appVersion,
-			"uptime":  time.Since(time.Now()).Seconds(), // TODO: tracker le vrai uptime
+			"uptime":  time.Since(hw.startTime).Seconds(),
 		},
 		"websocket": map[string]interface{}{
 			"connected_clients":    hw.hub.GetClientCount(),
 			"active_subscriptions": len(hw.hub.subscriptions),
 		},
-		"node": hw.nodeReader.GetStats(),
+		"node":    hw.nodeReader.GetNodeStats(),
+		"runtime": runtimeStats(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
 
+// Dernières statistiques mémoire lues, ReadMemStats arrêtant brièvement le monde
+var (
+	memStatsMu     sync.Mutex
+	memStats       runtime.MemStats
+	memStatsReadAt time.Time
+)
+
+// runtimeStats retourne le nombre de goroutines et l'occupation du tas, les
+// chiffres mémoire étant relus au plus une fois par seconde
+func runtimeStats() map[string]interface{} {
+	memStatsMu.Lock()
+	defer memStatsMu.Unlock()
+
+	if time.Since(memStatsReadAt) >= time.Second {
+		runtime.ReadMemStats(&memStats)
+		memStatsReadAt = time.Now()
+	}
+
+	return map[string]interface{}{
+		"goroutines":       runtime.NumGoroutine(),
+		"heap_alloc_bytes": memStats.HeapAlloc,
+		"heap_inuse_bytes": memStats.HeapInuse,
+	}
+}
+
 // generatePeriodicData génère des données périodiques pour les souscriptions
 func (hw *HyperWS) generatePeriodicData() {
 	ticker := time.NewTicker(1 * time.Second)
@@ -471,6 +582,13 @@ func (hw *HyperWS) generatePeriodicData() {
 				continue
 			}
 
+			// Rien de nouveau depuis le dernier tick
+			select {
+			case <-hw.updated:
+			default:
+				continue
+			}
+
 			// Générer allMids si des clients sont souscrits
 			hw.generateAllMids()
 			
@@ -496,7 +614,7 @@ func (hw *HyperWS) generateAllMids() {
 		return
 	}
 
-	prices := hw.nodeReader.GetAllPrices()
+	prices := hw.nodeReader.GetAllLatestPrices()
 	if len(prices) == 0 {
 		return
 	}
@@ -530,19 +648,22 @@ func (hw *HyperWS) generateAllMids() {
 
 // generateTrades génère les données de trades
 func (hw *HyperWS) generateTrades() {
+	// Copier les clients sous verrou : le hub modifie ses ensembles pendant l'envoi
 	hw.hub.mu.RLock()
-	tradesSubscriptions := make(map[string]map[*Client]bool)
+	tradesSubscriptions := make(map[string][]*Client)
 	for key, clients := range hw.hub.subscriptions {
 		if strings.HasPrefix(key, TradesType+"-") {
 			coin := strings.TrimPrefix(key, TradesType+"-")
-			tradesSubscriptions[coin] = clients
+			for client := range clients {
+				tradesSubscriptions[coin] = append(tradesSubscriptions[coin], client)
+			}
 		}
 	}
 	hw.hub.mu.RUnlock()
 
 	// Générer des trades pour chaque coin avec souscriptions
 	for coin, clients := range tradesSubscriptions {
-		trades := hw.nodeReader.GetLatestTrades(coin, 1)
+		trades := hw.nodeReader.GetRealTrades(coin, 1)
 		if len(trades) == 0 {
 			continue
 		}
@@ -558,13 +679,9 @@ func (hw *HyperWS) generateTrades() {
 			continue
 		}
 
-		// Envoyer aux clients souscrits
-		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-				// Client déconnecté
-			}
+		// Envoyer aux clients souscrits, ceux partis entre-temps sont ignorés
+		for _, client := range clients {
+			hw.hub.sendTo(client, data)
 		}
 	}
 }
@@ -574,6 +691,9 @@ func (hw *HyperWS) Stop() error {
 	if hw.nodeReader != nil {
 		hw.nodeReader.Stop()
 	}
+	if hw.assetFetcher != nil {
+		hw.assetFetcher.Stop()
+	}
 
 	if hw.server != nil {
 		return hw.server.Close()
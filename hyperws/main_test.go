@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// Les logs info par client noient la sortie des tests
+	logrus.SetLevel(logrus.WarnLevel)
+	os.Exit(m.Run())
+}
+
+// filledBlock est un bloc replica_cmds contenant un ordre rempli sur l'asset 0
+const filledBlock = `{"abci_block":{"time":"2025-01-01T00:00:00.000","round":1,"signed_action_bundles":[["0xbundle",{"signed_actions":[{"signature":{"r":"0x0","s":"0x0","v":27},"action":{"type":"order","orders":[{"a":0,"b":true,"p":"100","s":"1","r":false,"t":{"limit":{"tif":"Ioc"}}}],"grouping":"na"},"nonce":1}],"broadcaster":"0x0","broadcaster_nonce":1}]]},"resps":{"Full":[["0xbundle",[{"user":"0xtaker","res":{"status":"ok","response":{"type":"order","data":{"statuses":[{"filled":{"totalSz":"1","avgPx":"100","oid":1}}]}}}}]]]}}`
+
+// startTestHyperWS démarre le hub et un lecteur ayant lu un trade, et retourne
+// le coin de ce trade
+func startTestHyperWS(t *testing.T) string {
+	t.Helper()
+
+	dataPath := t.TempDir()
+	datePath := filepath.Join(dataPath, "replica_cmds", "2025-01-01T00:00:00Z", "20250101")
+	if err := os.MkdirAll(datePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(datePath, "0"), []byte(filledBlock+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{}
+	config.Node.DataPath = dataPath
+	hyperWS = NewHyperWS(config)
+	go hyperWS.hub.Run()
+	hyperWS.nodeReader.Start()
+	t.Cleanup(hyperWS.nodeReader.Stop)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(hyperWS.nodeReader.GetTradeCoins()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("le lecteur n'a lu aucun trade")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return hyperWS.nodeReader.GetTradeCoins()[0]
+}
+
+func TestGenerateTradesWhileSubscriptionsChange(t *testing.T) {
+	coin := startTestHyperWS(t)
+	sub := &SubscriptionRequest{Type: TradesType, Coin: coin}
+
+	// Un client reste souscrit pendant que d'autres vont et viennent
+	stable := NewClient(nil, hyperWS.hub)
+	hyperWS.hub.register <- stable
+	stable.handleSubscribe(sub)
+	for hyperWS.hub.GetClientCount() == 0 {
+		// Le hub enregistre le client dans sa propre goroutine
+		time.Sleep(time.Millisecond)
+	}
+	for len(stable.send) > 0 {
+		<-stable.send
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			client := NewClient(nil, hyperWS.hub)
+			hyperWS.hub.register <- client
+			client.handleSubscribe(sub)
+			hyperWS.hub.unregister <- client
+		}
+	}()
+
+	const rounds = 100
+	for i := 0; i < rounds; i++ {
+		hyperWS.generateTrades()
+	}
+	close(stop)
+	wg.Wait()
+
+	if received := len(stable.send); received != rounds {
+		t.Fatalf("le client stable a reçu %d trades, %d attendus", received, rounds)
+	}
+}